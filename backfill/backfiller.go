@@ -0,0 +1,118 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+)
+
+// Options of a backfill, filter pattern is optional and has the same syntax as subscription filters.
+type Options struct {
+	LogGroup      string
+	FilterPattern string
+	AccountID     string
+	StartTime     time.Time
+	EndTime       time.Time
+	// Checkpoint is optional, backfill starts from the first page without it
+	Checkpoint *Checkpoint
+}
+
+// Backfiller sends events a log group had before its subscription filter was created,
+// in the same envelope a subscription would have delivered them.
+type Backfiller struct {
+	client    cwlogs.Client
+	enricher  *enrich.Enricher
+	forwarder *forward.Forwarder
+	opts      Options
+}
+
+func NewBackfiller(client cwlogs.Client, enricher *enrich.Enricher, forwarder *forward.Forwarder, opts Options) *Backfiller {
+	return &Backfiller{
+		client:    client,
+		enricher:  enricher,
+		forwarder: forwarder,
+		opts:      opts,
+	}
+}
+
+type streamEvents struct {
+	logStream string
+	logEvents []events.CloudwatchLogsLogEvent
+}
+
+// Run pages through the log events in the time range and pushes each page before requesting the next one.
+// Checkpoint is updated after every page, so at most one page is sent again when an interrupted backfill is resumed.
+func (b *Backfiller) Run(ctx context.Context) error {
+	startTime := b.opts.StartTime.UnixMilli()
+	// end time is not part of the key, so a backfill until now can be resumed later
+	key := fmt.Sprintf("%s|%s|%d", b.opts.LogGroup, b.opts.FilterPattern, startTime)
+	p := progress{EndTime: b.opts.EndTime.UnixMilli()}
+	if b.opts.Checkpoint != nil {
+		if saved := b.opts.Checkpoint.progress(key); saved.Done {
+			log.Printf("Skipping backfill of %s, it is already completed with %d events", b.opts.LogGroup, saved.Events)
+			return nil
+		} else if saved.NextToken != "" {
+			log.Printf("Resuming backfill of %s after %d events until %s", b.opts.LogGroup, saved.Events, time.UnixMilli(saved.EndTime).UTC().Format(time.RFC3339))
+			p = saved
+		}
+	}
+	endTime := p.EndTime
+
+	commons := map[string]*enrich.Common{}
+	for {
+		page, err := b.client.FilterLogEvents(ctx, b.opts.LogGroup, b.opts.FilterPattern, startTime, endTime, p.NextToken)
+		if err != nil {
+			return fmt.Errorf("failed to filter log events of %s, err: %v", b.opts.LogGroup, err)
+		}
+
+		for _, s := range groupByLogStream(page.Events) {
+			common, ok := commons[s.logStream]
+			if !ok {
+				common = b.enricher.GetEDCommon(ctx, nil, cwlogs.DataMessage, b.opts.LogGroup, s.logStream, b.opts.AccountID)
+				commons[s.logStream] = common
+			}
+			// several web ACLs can log to the same WAF log stream, so their events are forwarded with their own common fields
+			for _, g := range b.enricher.GroupByWebACL(ctx, common, s.logEvents) {
+				if err := b.forwarder.Forward(ctx, g.Common, g.LogEvents); err != nil {
+					return fmt.Errorf("failed to forward events of log stream %s, err: %v", s.logStream, err)
+				}
+			}
+		}
+
+		p.Events += len(page.Events)
+		p.NextToken = page.NextToken
+		p.Done = page.NextToken == ""
+		if b.opts.Checkpoint != nil {
+			if err := b.opts.Checkpoint.save(key, p); err != nil {
+				return err
+			}
+		}
+		if p.Done {
+			log.Printf("Backfilled %d events of %s", p.Events, b.opts.LogGroup)
+			return nil
+		}
+	}
+}
+
+// groupByLogStream splits events of a page by their log streams, since a subscription payload has events of a single stream.
+// Streams are in the order of their first event and events keep their order within a stream.
+func groupByLogStream(logEvents []cwlogs.FilteredLogEvent) []streamEvents {
+	var streams []streamEvents
+	indexes := map[string]int{}
+	for _, e := range logEvents {
+		i, ok := indexes[e.LogStream]
+		if !ok {
+			i = len(streams)
+			indexes[e.LogStream] = i
+			streams = append(streams, streamEvents{logStream: e.LogStream})
+		}
+		streams[i].logEvents = append(streams[i].logEvents, e.CloudwatchLogsLogEvent)
+	}
+	return streams
+}
@@ -0,0 +1,147 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/forward/forwardtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	logGroup = "/aws/lambda/my-function"
+)
+
+// fakeLogsClient returns pages in order, next token of a page is the index of the next page.
+type fakeLogsClient struct {
+	pages      [][]cwlogs.FilteredLogEvent
+	nextTokens []string
+	endTimes   []int64
+}
+
+func (c *fakeLogsClient) FilterLogEvents(ctx context.Context, logGroup, filterPattern string, startTime, endTime int64, nextToken string) (*cwlogs.FilterLogEventsPage, error) {
+	c.nextTokens = append(c.nextTokens, nextToken)
+	c.endTimes = append(c.endTimes, endTime)
+	i := 0
+	if nextToken != "" {
+		i, _ = strconv.Atoi(nextToken)
+	}
+	page := &cwlogs.FilterLogEventsPage{Events: c.pages[i]}
+	if i+1 < len(c.pages) {
+		page.NextToken = strconv.Itoa(i + 1)
+	}
+	return page, nil
+}
+
+type pushedStream struct {
+	logStream string
+	messages  []string
+}
+
+func pushedStreams(logs []core.Log) []pushedStream {
+	var streams []pushedStream
+	for _, l := range logs {
+		s := pushedStream{logStream: l.AwsCommon.LogStream}
+		for _, e := range l.LogEvents {
+			s.messages = append(s.messages, e.Message)
+		}
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+func newEvent(logStream string, id int) cwlogs.FilteredLogEvent {
+	return cwlogs.FilteredLogEvent{
+		CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{ID: strconv.Itoa(id), Timestamp: 1704067200000 + int64(id), Message: "message " + strconv.Itoa(id)},
+		LogStream:              logStream,
+	}
+}
+
+func newBackfiller(client cwlogs.Client, pusher forward.Pusher, checkpoint *Checkpoint, endTime time.Time) *Backfiller {
+	conf := &cfg.Config{Region: "us-west-2", BatchSize: cfg.MaxChunkSize}
+	enricher, forwarder := forwardtest.NewForwarder(conf, pusher)
+	return NewBackfiller(client, enricher, forwarder, Options{
+		LogGroup:   logGroup,
+		AccountID:  "123456789012",
+		StartTime:  time.UnixMilli(1704067200000),
+		EndTime:    endTime,
+		Checkpoint: checkpoint,
+	})
+}
+
+func TestBackfillerRun(t *testing.T) {
+	client := &fakeLogsClient{pages: [][]cwlogs.FilteredLogEvent{
+		{newEvent("stream-a", 1), newEvent("stream-b", 2), newEvent("stream-a", 3)},
+		{},
+		{newEvent("stream-b", 4)},
+	}}
+	checkpoint, err := LoadCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+	require.NoError(t, err)
+	pusher := &forwardtest.Endpoint{}
+
+	require.NoError(t, newBackfiller(client, pusher, checkpoint, time.UnixMilli(1704070800000)).Run(context.Background()))
+
+	assert.Equal(t, []string{"", "1", "2"}, client.nextTokens)
+	assert.Equal(t, []pushedStream{
+		{logStream: "stream-a", messages: []string{"message 1", "message 3"}},
+		{logStream: "stream-b", messages: []string{"message 2"}},
+		{logStream: "stream-b", messages: []string{"message 4"}},
+	}, pushedStreams(pusher.Logs()))
+	for _, l := range pusher.Logs() {
+		assert.Equal(t, logGroup, l.AwsCommon.LogGroup)
+		assert.Equal(t, "arn:aws:logs:us-west-2:123456789012:log-group:/aws/lambda/my-function", l.AwsCommon.LogGroupARN)
+		assert.Equal(t, cwlogs.DataMessage, l.AwsCommon.LogMessageType)
+	}
+
+	// completed backfill is not run again
+	client.nextTokens = nil
+	require.NoError(t, newBackfiller(client, pusher, checkpoint, time.Now()).Run(context.Background()))
+	assert.Empty(t, client.nextTokens)
+}
+
+func TestBackfillerResume(t *testing.T) {
+	client := &fakeLogsClient{pages: [][]cwlogs.FilteredLogEvent{
+		{newEvent("stream-a", 1)},
+		{newEvent("stream-a", 2)},
+		{newEvent("stream-a", 3)},
+	}}
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpoint, err := LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+
+	endTime := time.UnixMilli(1704070800000)
+	failing := &forwardtest.Endpoint{}
+	// pushes after the first page fail
+	failing.Fail = func(core.Log) error {
+		if len(failing.Logs()) == 1 {
+			return errors.New("endpoint is not reachable")
+		}
+		return nil
+	}
+	err = newBackfiller(client, failing, checkpoint, endTime).Run(context.Background())
+	require.Error(t, err)
+
+	// resumed backfill starts from the failed page with the end time of the first run
+	checkpoint, err = LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	client.nextTokens, client.endTimes = nil, nil
+	pusher := &forwardtest.Endpoint{}
+	require.NoError(t, newBackfiller(client, pusher, checkpoint, time.Now()).Run(context.Background()))
+
+	assert.Equal(t, []string{"1", "2"}, client.nextTokens)
+	assert.Equal(t, []int64{endTime.UnixMilli(), endTime.UnixMilli()}, client.endTimes)
+	assert.Equal(t, []pushedStream{
+		{logStream: "stream-a", messages: []string{"message 2"}},
+		{logStream: "stream-a", messages: []string{"message 3"}},
+	}, pushedStreams(pusher.Logs()))
+}
@@ -0,0 +1,35 @@
+package backfill
+
+import (
+	"github.com/edgedelta/edgedelta-forwarder/utils"
+)
+
+// Checkpoint keeps the next token of each backfill, so an interrupted backfill resumes from the page it stopped at.
+type Checkpoint struct {
+	backfills *utils.Checkpoint[progress]
+}
+
+type progress struct {
+	// EndTime is kept since next token is only valid for the same time range
+	EndTime   int64  `json:"end_time"`
+	NextToken string `json:"next_token,omitempty"`
+	Events    int    `json:"events"`
+	Done      bool   `json:"done,omitempty"`
+}
+
+// LoadCheckpoint reads the checkpoint file at path, a missing file is an empty checkpoint.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	backfills, err := utils.LoadCheckpoint[progress](path, "backfills")
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{backfills: backfills}, nil
+}
+
+func (c *Checkpoint) progress(key string) progress {
+	return c.backfills.Get(key)
+}
+
+func (c *Checkpoint) save(key string, p progress) error {
+	return c.backfills.Save(key, p)
+}
@@ -12,10 +12,30 @@ import (
 const (
 	defaultECSContainerCacheTTL = 300 * time.Second // 5 minutes
 	defaultPushTimeout          = 10 * time.Second
+	defaultWorkerCount          = 4
+	defaultHTTPSignatureHeader  = "X-Hub-Signature-256"
 	MaxChunkSize                = 1000 * 1000 // 1MB
 	MinChunkSize                = 50 * 1000   // 50KB
 )
 
+const (
+	HandlerModeAuto           = "auto"
+	HandlerModeCloudwatchLogs = "cloudwatch_logs"
+	HandlerModeS3             = "s3"
+	HandlerModeSQS            = "sqs"
+	HandlerModeKinesis        = "kinesis"
+	HandlerModeFirehose       = "firehose"
+	HandlerModeEventBridge    = "eventbridge"
+	HandlerModeSNS            = "sns"
+	HandlerModeFunctionURL    = "function_url"
+	HandlerModeKafka          = "kafka"
+)
+
+const (
+	MetricStreamFormatJSON          = "json"
+	MetricStreamFormatOpenTelemetry = "opentelemetry1.0"
+)
+
 // Config for storing all parameters
 type Config struct {
 	Region                    string
@@ -32,9 +52,58 @@ type Config struct {
 	// /ecs/{cluster_name}
 	// /ecs/{cluster_name}/{service_name}
 	ECSClusterOverride string
+	// HandlerMode selects the lambda event type the forwarder is triggered with, event type is detected per invocation in auto mode
+	HandlerMode string
+	// WorkerCount is the number of records processed concurrently for batched event sources
+	WorkerCount int
+	// FirehosePushToEndpoint enables pushing transformed records to ED_ENDPOINT in firehose handler mode
+	FirehosePushToEndpoint bool
+	// FirehoseListenAddress enables Firehose HTTP endpoint receiver in standalone mode
+	FirehoseListenAddress string
+	// FirehoseAccessKey is compared with the access key configured on Firehose HTTP endpoint destination, it is required
+	// when FirehoseListenAddress is set
+	FirehoseAccessKey string
+	// FluentListenAddress enables Fluent Forward protocol receiver in standalone mode
+	FluentListenAddress string
+	// OTLPListenAddress enables OTLP/HTTP logs receiver in standalone mode, requests are authenticated
+	// with HTTPSharedSecret or HTTPHMACSecret, so one of them is required
+	OTLPListenAddress string
+	// SyslogUDPListenAddress, SyslogTCPListenAddress and SyslogTLSListenAddress enable syslog receivers in standalone mode
+	SyslogUDPListenAddress string
+	SyslogTCPListenAddress string
+	SyslogTLSListenAddress string
+	// SyslogTLSCertFile and SyslogTLSKeyFile are PEM encoded certificate and key files of syslog TLS receiver
+	SyslogTLSCertFile string
+	SyslogTLSKeyFile  string
+	// AccountID is the account of logs received from hosts in standalone mode, instance metadata is used if it is empty
+	AccountID string
+	// MetricStreamFormat is the output format of CloudWatch metric streams, Firehose records are decoded as metrics when it is set
+	MetricStreamFormat string
+	// HTTPSharedSecret is compared with bearer token in Authorization header of HTTP requests
+	HTTPSharedSecret string
+	// HTTPHMACSecret is used to verify HMAC-SHA256 signature of HTTP request bodies
+	HTTPHMACSecret string
+	// HTTPSignatureHeader is the header containing hex encoded HMAC-SHA256 signature of HTTP request bodies
+	HTTPSignatureHeader string
+	// VPCFlowLogFormats has comma separated "<log_group>=<format>" pairs for flow logs with custom formats
+	VPCFlowLogFormats string
+	// CloudFrontRealtimeLogFields has the fields of the real-time log configuration of CloudFront real-time logs
+	CloudFrontRealtimeLogFields string
+	// CloudFrontRealtimeLogDistributions has comma separated "<kinesis_stream_name>=<distribution_id>" pairs
+	CloudFrontRealtimeLogDistributions string
 }
 
 func GetConfig() (*Config, error) {
+	return getConfig(false)
+}
+
+// GetConfigWithoutEndpoint is the same as GetConfig except ED_ENDPOINT is optional,
+// it is used when chunks may not be pushed to Edge Delta endpoint, i.e. dry run of replay.
+func GetConfigWithoutEndpoint() (*Config, error) {
+	return getConfig(true)
+}
+
+func getConfig(endpointOptional bool) (*Config, error) {
 	config := &Config{}
 
 	var errs []error
@@ -46,8 +115,58 @@ func GetConfig() (*Config, error) {
 		config.Region = region
 	}
 
+	handlerMode := os.Getenv("ED_HANDLER_MODE")
+	switch handlerMode {
+	case "":
+		// detection is opt-in, so existing forwarders keep handling CloudWatch Logs subscription events
+		config.HandlerMode = HandlerModeCloudwatchLogs
+	case HandlerModeAuto, HandlerModeCloudwatchLogs, HandlerModeS3, HandlerModeSQS, HandlerModeKinesis, HandlerModeFirehose,
+		HandlerModeEventBridge, HandlerModeSNS, HandlerModeFunctionURL, HandlerModeKafka:
+		config.HandlerMode = handlerMode
+	default:
+		errs = append(errs, fmt.Errorf("unknown handler mode: %s", handlerMode))
+	}
+	config.FirehosePushToEndpoint = os.Getenv("ED_FIREHOSE_PUSH_TO_ENDPOINT") == "true"
+	config.FirehoseListenAddress = os.Getenv("ED_FIREHOSE_LISTEN_ADDRESS")
+	config.FirehoseAccessKey = os.Getenv("ED_FIREHOSE_ACCESS_KEY")
+	if config.FirehoseListenAddress != "" && config.FirehoseAccessKey == "" {
+		errs = append(errs, errors.New("ED_FIREHOSE_ACCESS_KEY is required for Firehose HTTP endpoint receiver"))
+	}
+	config.FluentListenAddress = os.Getenv("ED_FLUENT_LISTEN_ADDRESS")
+	config.AccountID = os.Getenv("ED_ACCOUNT_ID")
+	config.OTLPListenAddress = os.Getenv("ED_OTLP_LISTEN_ADDRESS")
+
+	config.SyslogUDPListenAddress = os.Getenv("ED_SYSLOG_UDP_LISTEN_ADDRESS")
+	config.SyslogTCPListenAddress = os.Getenv("ED_SYSLOG_TCP_LISTEN_ADDRESS")
+	config.SyslogTLSListenAddress = os.Getenv("ED_SYSLOG_TLS_LISTEN_ADDRESS")
+	config.SyslogTLSCertFile = os.Getenv("ED_SYSLOG_TLS_CERT_FILE")
+	config.SyslogTLSKeyFile = os.Getenv("ED_SYSLOG_TLS_KEY_FILE")
+	if config.SyslogTLSListenAddress != "" && (config.SyslogTLSCertFile == "" || config.SyslogTLSKeyFile == "") {
+		errs = append(errs, errors.New("ED_SYSLOG_TLS_CERT_FILE and ED_SYSLOG_TLS_KEY_FILE are required for syslog TLS receiver"))
+	}
+
+	config.HTTPSharedSecret = os.Getenv("ED_HTTP_SHARED_SECRET")
+	config.HTTPHMACSecret = os.Getenv("ED_HTTP_HMAC_SECRET")
+	config.HTTPSignatureHeader = os.Getenv("ED_HTTP_SIGNATURE_HEADER")
+	if config.HTTPSignatureHeader == "" {
+		config.HTTPSignatureHeader = defaultHTTPSignatureHeader
+	}
+	if config.OTLPListenAddress != "" && config.HTTPSharedSecret == "" && config.HTTPHMACSecret == "" {
+		errs = append(errs, errors.New("ED_HTTP_SHARED_SECRET or ED_HTTP_HMAC_SECRET is required for OTLP/HTTP logs receiver"))
+	}
+
+	metricStreamFormat := os.Getenv("ED_METRIC_STREAM_FORMAT")
+	switch metricStreamFormat {
+	case "", MetricStreamFormatJSON, MetricStreamFormatOpenTelemetry:
+		config.MetricStreamFormat = metricStreamFormat
+	default:
+		errs = append(errs, fmt.Errorf("unknown metric stream format: %s", metricStreamFormat))
+	}
+
 	endpoint := os.Getenv("ED_ENDPOINT")
-	if endpoint == "" {
+	// endpoint is optional when forwarder only transforms firehose records
+	endpointOptional = endpointOptional || (config.HandlerMode == HandlerModeFirehose && !config.FirehosePushToEndpoint)
+	if endpoint == "" && !endpointOptional {
 		err := fmt.Errorf("ED_ENDPOINT environment variable is required")
 		errs = append(errs, err)
 	} else {
@@ -99,6 +218,20 @@ func GetConfig() (*Config, error) {
 		config.RetryInterval = 100 * time.Millisecond
 	}
 
+	wc := os.Getenv("ED_WORKER_COUNT")
+	if wc != "" {
+		workerCount, err := strconv.Atoi(wc)
+		if err != nil {
+			errs = append(errs, err)
+		} else if workerCount <= 0 {
+			errs = append(errs, errors.New("worker count must be greater than 0"))
+		} else {
+			config.WorkerCount = workerCount
+		}
+	} else {
+		config.WorkerCount = defaultWorkerCount
+	}
+
 	ecsContainerCacheTTLFromEnv := os.Getenv("ECS_CONTAINER_CACHE_TTL_SEC")
 	if ecsContainerCacheTTLFromEnv != "" {
 		ecsContainerCacheTTL, err := strconv.Atoi(ecsContainerCacheTTLFromEnv)
@@ -113,6 +246,9 @@ func GetConfig() (*Config, error) {
 	config.ECSClusterOverride = os.Getenv("ECS_CLUSTER_OVERRIDE")
 
 	config.SourceEnvironmentPrefixes = os.Getenv("ED_SOURCE_TAG_PREFIXES")
+	config.VPCFlowLogFormats = os.Getenv("ED_VPC_FLOW_LOG_FORMATS")
+	config.CloudFrontRealtimeLogFields = os.Getenv("ED_CLOUDFRONT_REALTIME_LOG_FIELDS")
+	config.CloudFrontRealtimeLogDistributions = os.Getenv("ED_CLOUDFRONT_REALTIME_LOG_DISTRIBUTIONS")
 
 	config.ForwardForwarderTags = os.Getenv("ED_FORWARD_FORWARDER_TAGS") == "true"
 	config.ForwardSourceTags = os.Getenv("ED_FORWARD_SOURCE_TAGS") == "true"
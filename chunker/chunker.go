@@ -13,15 +13,38 @@ const (
 
 type Chunker struct {
 	chunkSize int
-	log       *core.Log
+	// count is the number of log events or metrics
+	count int
+	// marshal returns the envelope containing common fields and items in range [start, end)
+	marshal func(start, end int) ([]byte, error)
 }
 
 func NewChunker(chunkSize int, logEntry *core.Log) (*Chunker, error) {
 	if logEntry == nil {
 		return nil, fmt.Errorf("log object is nil")
 	}
+	return newChunker(chunkSize, logEntry.Common, len(logEntry.LogEvents), func(start, end int) ([]byte, error) {
+		return json.Marshal(core.Log{
+			Common: logEntry.Common,
+			Data:   core.Data{LogEvents: logEntry.LogEvents[start:end]},
+		})
+	})
+}
+
+func NewMetricChunker(chunkSize int, metrics *core.Metrics) (*Chunker, error) {
+	if metrics == nil {
+		return nil, fmt.Errorf("metrics object is nil")
+	}
+	return newChunker(chunkSize, metrics.Common, len(metrics.Metrics), func(start, end int) ([]byte, error) {
+		return json.Marshal(core.Metrics{
+			Common:     metrics.Common,
+			MetricData: core.MetricData{Metrics: metrics.Metrics[start:end]},
+		})
+	})
+}
 
-	commonJson, err := json.Marshal(logEntry.Common)
+func newChunker(chunkSize int, common core.Common, count int, marshal func(start, end int) ([]byte, error)) (*Chunker, error) {
+	commonJson, err := json.Marshal(common)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal common object: %w", err)
 	}
@@ -31,44 +54,38 @@ func NewChunker(chunkSize int, logEntry *core.Log) (*Chunker, error) {
 	if len(commonJson) > chunkSize {
 		return nil, fmt.Errorf("common object is too large for specified chunk size. Try increasing the chunk size, given chunk size: %d, detected object size: %d", chunkSize, len(commonJson))
 	}
-
-	return &Chunker{chunkSize: chunkSize, log: logEntry}, nil
+	return &Chunker{chunkSize: chunkSize, count: count, marshal: marshal}, nil
 }
 
-// ChunkLogs splits a large log into smaller chunks that fit within the specified max chunk size.
+// Chunk splits large logs or metrics into smaller chunks that fit within the specified max chunk size.
 // It uses a recursive divide-and-conquer approach.
-func (c *Chunker) ChunkLogs() ([][]byte, error) {
-	return c.chunkLogs(c.log, 0, len(c.log.LogEvents), 0)
+func (c *Chunker) Chunk() ([][]byte, error) {
+	return c.chunk(0, c.count, 0)
 }
 
-func (c *Chunker) chunkLogs(log *core.Log, start, end, depth int) ([][]byte, error) {
-	currentLogObject := core.Log{
-		Common: log.Common,
-		Data:   core.Data{LogEvents: log.LogEvents[start:end]},
-	}
-
-	currentBytes, err := json.Marshal(currentLogObject)
+func (c *Chunker) chunk(start, end, depth int) ([][]byte, error) {
+	currentBytes, err := c.marshal(start, end)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal log object: %w", err)
+		return nil, fmt.Errorf("failed to marshal chunk (start: %d, end: %d): %w", start, end, err)
 	}
 
-	shouldChunk := len(currentBytes) > c.chunkSize && // should chunk further if current log object is too large
-		start != end-1 && // except when there is no more log events to chunk
+	shouldChunk := len(currentBytes) > c.chunkSize && // should chunk further if current chunk is too large
+		start != end-1 && // except when there is no more items to chunk
 		depth < maxDepth // except when we have reached the maximum recursion depth
 
 	if !shouldChunk {
 		return [][]byte{currentBytes}, nil
 	}
 
-	// Find the middle of the log events
+	// Find the middle of the items
 	mid := start + (end-start)/2
 
 	// Recursively chunk the left and right halves
-	leftChunks, err := c.chunkLogs(log, start, mid, depth+1)
+	leftChunks, err := c.chunk(start, mid, depth+1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to chunk left half (start: %d, mid: %d, depth: %d): %w", start, mid, depth+1, err)
 	}
-	rightChunks, err := c.chunkLogs(log, mid, end, depth+1)
+	rightChunks, err := c.chunk(mid, end, depth+1)
 	if err != nil {
 		return nil, fmt.Errorf("failed to chunk right half (mid: %d, end: %d, depth: %d): %w", mid, end, depth+1, err)
 	}
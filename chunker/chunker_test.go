@@ -103,10 +103,7 @@ func TestChunkLogs(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			log := &core.Log{
-				Common: tt.common,
-				Data:   core.Data{LogEvents: tt.logEvents},
-			}
+			log := core.NewLog(tt.common, tt.logEvents)
 			chunker, err := NewChunker(tt.chunkSize, log)
 			if err != nil {
 				if !tt.exceptsError {
@@ -115,7 +112,7 @@ func TestChunkLogs(t *testing.T) {
 				return
 			}
 
-			chunks, err := chunker.ChunkLogs()
+			chunks, err := chunker.Chunk()
 			if err != nil {
 				t.Errorf("Failed to chunk logs: %v", err)
 			}
@@ -155,6 +152,31 @@ func TestChunkLogs(t *testing.T) {
 	}
 }
 
+func TestChunkLogsWithoutAttributes(t *testing.T) {
+	common := core.Common{HostArchitecture: "test arch"}
+	logEvents := []events.CloudwatchLogsLogEvent{{ID: "1", Timestamp: 1, Message: "hello"}}
+	chunker, err := NewChunker(cfg.MinChunkSize, core.NewLog(common, logEvents))
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err := chunker.Chunk()
+	if err != nil {
+		t.Fatalf("Failed to chunk logs: %v", err)
+	}
+
+	// log events without attributes are pushed as CloudWatch log events
+	expected, err := json.Marshal(struct {
+		core.Common
+		LogEvents []events.CloudwatchLogsLogEvent `json:"logEvents"`
+	}{common, logEvents})
+	if err != nil {
+		t.Fatalf("Failed to marshal expected payload: %v", err)
+	}
+	if len(chunks) != 1 || string(chunks[0]) != string(expected) {
+		t.Errorf("Expected payload %s, got %s", expected, chunks)
+	}
+}
+
 // Helper function to generate multiple log events
 func generateLogEvents(count, size int) []events.CloudwatchLogsLogEvent {
 	logEvents := make([]events.CloudwatchLogsLogEvent, count)
@@ -163,3 +185,48 @@ func generateLogEvents(count, size int) []events.CloudwatchLogsLogEvent {
 	}
 	return logEvents
 }
+
+func TestChunkMetrics(t *testing.T) {
+	metrics := make([]core.Metric, 1000)
+	for i := range metrics {
+		metrics[i] = core.Metric{
+			Namespace:  "AWS/EC2",
+			Name:       "CPUUtilization",
+			Dimensions: map[string]string{"InstanceId": "i-0123456789abcdef0"},
+			Timestamp:  int64(i),
+			Value:      map[string]float64{"max": 1, "min": 0, "sum": 10, "count": 20},
+			Unit:       "Percent",
+		}
+	}
+	common := core.Common{HostArchitecture: "test arch"}
+
+	chunker, err := NewMetricChunker(cfg.MinChunkSize, &core.Metrics{Common: common, MetricData: core.MetricData{Metrics: metrics}})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err := chunker.Chunk()
+	if err != nil {
+		t.Fatalf("Failed to chunk metrics: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Errorf("Expected metrics to be split into multiple chunks, got %d", len(chunks))
+	}
+
+	total := 0
+	for i, chunk := range chunks {
+		if len(chunk) > cfg.MinChunkSize {
+			t.Errorf("Chunk %d size should not exceed chunk size, max chunk size: %d, got %d bytes", i, cfg.MinChunkSize, len(chunk))
+		}
+		var decoded core.Metrics
+		if err := json.Unmarshal(chunk, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal chunk %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(common, decoded.Common) {
+			t.Errorf("Common data mismatch in chunk %d", i)
+		}
+		total += len(decoded.Metrics)
+	}
+	if total != len(metrics) {
+		t.Errorf("Total number of metrics mismatch: expected %d, got %d", len(metrics), total)
+	}
+}
@@ -0,0 +1,117 @@
+// Backfill sends historical events of a log group through the forwarder pipeline,
+// i.e. events a newly onboarded log group had before its subscription filter was created.
+//
+//	backfill -log-group <log_group> -start <RFC3339 time> [flags]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/edgedelta/edgedelta-forwarder/backfill"
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/ecs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+	"github.com/edgedelta/edgedelta-forwarder/resource"
+
+	lambdaCl "github.com/edgedelta/edgedelta-forwarder/lambda"
+)
+
+func main() {
+	logGroup := flag.String("log-group", "", "Log group to backfill (required)")
+	filterPattern := flag.String("filter-pattern", "", "Filter pattern of events, i.e. the pattern of the subscription filter")
+	start := flag.String("start", "", "Start of the time range in RFC3339 format (required)")
+	end := flag.String("end", "", "End of the time range in RFC3339 format, defaults to now")
+	accountID := flag.String("account-id", "", "Account ID of the log group, defaults to the account of the credentials")
+	checkpointPath := flag.String("checkpoint", "", "Checkpoint file to resume backfill from, it is updated after every page")
+	flag.Parse()
+
+	if *logGroup == "" || *start == "" {
+		flag.Usage()
+		log.Fatalf("-log-group and -start are required")
+	}
+	startTime, err := time.Parse(time.RFC3339, *start)
+	if err != nil {
+		log.Fatalf("Failed to parse start time, err: %v", err)
+	}
+	endTime := time.Now()
+	if *end != "" {
+		if endTime, err = time.Parse(time.RFC3339, *end); err != nil {
+			log.Fatalf("Failed to parse end time, err: %v", err)
+		}
+	}
+
+	config, err := cfg.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get config from environment variables, err: %v", err)
+	}
+	if *accountID == "" {
+		*accountID, err = getCallerAccountID(config.Region)
+		if err != nil {
+			log.Fatalf("Failed to get account ID, err: %v", err)
+		}
+	}
+	resCl, err := resource.NewAWSClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS resourcegroupstaggingapi client, err: %v", err)
+	}
+	lambdaClient, err := lambdaCl.NewClient(config.Region)
+	if err != nil {
+		log.Fatalf("Failed to create AWS lambda client, err: %v", err)
+	}
+	ecsClient, err := ecs.NewClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS ECS client, err: %v", err)
+	}
+	logsClient, err := cwlogs.NewClient(config.Region)
+	if err != nil {
+		log.Fatalf("Failed to create AWS CloudWatch Logs client, err: %v", err)
+	}
+
+	var checkpoint *backfill.Checkpoint
+	if *checkpointPath != "" {
+		checkpoint, err = backfill.LoadCheckpoint(*checkpointPath)
+		if err != nil {
+			log.Fatalf("Failed to load checkpoint, err: %v", err)
+		}
+	}
+
+	enricher := enrich.NewEnricher(config, resCl, lambdaClient, ecsClient)
+	forwarder := forward.NewForwarder(config, push.NewPusher(config))
+	backfiller := backfill.NewBackfiller(logsClient, enricher, forwarder, backfill.Options{
+		LogGroup:      *logGroup,
+		FilterPattern: *filterPattern,
+		AccountID:     *accountID,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Checkpoint:    checkpoint,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := backfiller.Run(ctx); err != nil {
+		log.Fatalf("Failed to backfill, err: %v", err)
+	}
+}
+
+func getCallerAccountID(region string) (string, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return "", err
+	}
+	identity, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(identity.Account), nil
+}
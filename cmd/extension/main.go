@@ -0,0 +1,78 @@
+// Extension runs the forwarder as a Lambda external extension, which sends function, platform and extension
+// telemetry directly to Edge Delta instead of reading it from CloudWatch Logs.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/ecs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/extension"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/parser"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+	"github.com/edgedelta/edgedelta-forwarder/resource"
+
+	lambdaCl "github.com/edgedelta/edgedelta-forwarder/lambda"
+)
+
+const (
+	telemetryListenerPort = 4243
+)
+
+func main() {
+	config, err := cfg.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get config from environment variables, err: %v", err)
+	}
+	resCl, err := resource.NewAWSClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS resourcegroupstaggingapi client, err: %v", err)
+	}
+	lambdaClient, err := lambdaCl.NewClient(config.Region)
+	if err != nil {
+		log.Fatalf("Failed to create AWS lambda client, err: %v", err)
+	}
+
+	enricher := enrich.NewEnricher(config, resCl, lambdaClient, ecs.NewNoOpClient())
+	forwarder := forward.NewForwarder(config, push.NewPusher(config))
+
+	// common fields do not change during the lifetime of the execution environment
+	commons := make(map[string]*enrich.Common)
+	flush := func(ctx context.Context, function *extension.RegisterResponse, telemetryEvents []extension.TelemetryEvent) error {
+		functionARN := parser.BuildResourceARN("lambda", function.AccountID, config.Region, fmt.Sprintf("function:%s", function.FunctionName))
+		for telemetryType, logEvents := range extension.GetLogEventsByType(telemetryEvents) {
+			common, ok := commons[telemetryType]
+			if !ok {
+				common = enricher.GetLambdaFunctionCommon(ctx, functionARN, function.FunctionName, function.FunctionVersion, telemetryType)
+				commons[telemetryType] = common
+			}
+			if err := forwarder.Forward(ctx, common, logEvents); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// name must match the file name under /opt/extensions
+	name := filepath.Base(os.Args[0])
+	listenAddress := fmt.Sprintf("sandbox.localdomain:%d", telemetryListenerPort)
+	if os.Getenv("AWS_SAM_LOCAL") == "true" {
+		listenAddress = fmt.Sprintf(":%d", telemetryListenerPort)
+	}
+	destinationURI := fmt.Sprintf("http://sandbox.localdomain:%d", telemetryListenerPort)
+	ext := extension.NewExtension(name, extension.NewClient(os.Getenv("AWS_LAMBDA_RUNTIME_API")), listenAddress, destinationURI, flush)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := ext.Run(ctx); err != nil {
+		log.Fatalf("Extension stopped, err: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Replay re-sends captured CloudWatch Logs subscription payloads, exported log files or NDJSON files
+// through the same enrichment, chunking and pushing as the forwarder, i.e. after failed pushes or enrichment changes.
+//
+//	replay [flags] <file or directory>...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/ecs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+	"github.com/edgedelta/edgedelta-forwarder/replay"
+	"github.com/edgedelta/edgedelta-forwarder/resource"
+
+	lambdaCl "github.com/edgedelta/edgedelta-forwarder/lambda"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", "", "Endpoint to push logs to, overrides ED_ENDPOINT")
+	format := flag.String("format", replay.FormatAuto, `Input format: "auto", "cloudwatch", "export" or "ndjson"`)
+	logGroup := flag.String("log-group", "", "Log group of exported and NDJSON logs")
+	logStream := flag.String("log-stream", "", "Log stream of exported and NDJSON logs, defaults to directory name of exported files")
+	accountID := flag.String("account-id", "", "Account ID of exported and NDJSON logs")
+	rate := flag.Float64("rate", 0, "Max chunks pushed per second, 0 is unlimited")
+	dryRun := flag.Bool("dry-run", false, "Print envelopes to stdout instead of pushing them")
+	checkpointPath := flag.String("checkpoint", "", "Checkpoint file to resume replay from, it is updated after every push")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <file or directory>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch *format {
+	case replay.FormatAuto, replay.FormatCloudwatch, replay.FormatExport, replay.FormatNDJSON:
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+	// endpoint may be given by the flag and it is not used in dry run
+	config, err := cfg.GetConfigWithoutEndpoint()
+	if err != nil {
+		log.Fatalf("Failed to get config from environment variables, err: %v", err)
+	}
+	if *endpoint != "" {
+		config.EDEndpoint = *endpoint
+	}
+	if config.EDEndpoint == "" && !*dryRun {
+		log.Fatalf("ED_ENDPOINT environment variable or -endpoint flag is required unless -dry-run is set")
+	}
+	resCl, err := resource.NewAWSClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS resourcegroupstaggingapi client, err: %v", err)
+	}
+	lambdaClient, err := lambdaCl.NewClient(config.Region)
+	if err != nil {
+		log.Fatalf("Failed to create AWS lambda client, err: %v", err)
+	}
+	ecsClient, err := ecs.NewClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS ECS client, err: %v", err)
+	}
+
+	var checkpoint *replay.Checkpoint
+	if *checkpointPath != "" {
+		checkpoint, err = replay.LoadCheckpoint(*checkpointPath)
+		if err != nil {
+			log.Fatalf("Failed to load checkpoint, err: %v", err)
+		}
+	}
+
+	// chunks are printed instead of pushed in dry run
+	var pusher forward.Pusher
+	if *dryRun {
+		pusher = replay.NewWriterPusher(os.Stdout)
+	} else {
+		pusher = push.NewPusher(config)
+	}
+	if *rate > 0 {
+		rateLimitedPusher := replay.NewRateLimitedPusher(pusher, *rate)
+		defer rateLimitedPusher.Stop()
+		pusher = rateLimitedPusher
+	}
+
+	enricher := enrich.NewEnricher(config, resCl, lambdaClient, ecsClient)
+	replayer := replay.NewReplayer(enricher, forward.NewForwarder(config, pusher), replay.Options{
+		Format:     *format,
+		LogGroup:   *logGroup,
+		LogStream:  *logStream,
+		AccountID:  *accountID,
+		Checkpoint: checkpoint,
+		DryRun:     *dryRun,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := replayer.Replay(ctx, flag.Args()); err != nil {
+		log.Fatalf("Failed to replay, err: %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Standalone runs the forwarder as a long-running server instead of a lambda function,
+// receivers are enabled by setting their listen addresses.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/ecs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/firehose"
+	"github.com/edgedelta/edgedelta-forwarder/fluent"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/imds"
+	"github.com/edgedelta/edgedelta-forwarder/otlp"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+	"github.com/edgedelta/edgedelta-forwarder/resource"
+	"github.com/edgedelta/edgedelta-forwarder/syslog"
+	"github.com/edgedelta/edgedelta-forwarder/webhook"
+
+	lambdaCl "github.com/edgedelta/edgedelta-forwarder/lambda"
+)
+
+const (
+	shutdownTimeout = 30 * time.Second
+	metadataTimeout = 5 * time.Second
+)
+
+type server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+type receiver struct {
+	addr   string
+	server server
+}
+
+func main() {
+	config, err := cfg.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get config from environment variables, err: %v", err)
+	}
+	resCl, err := resource.NewAWSClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS resourcegroupstaggingapi client, err: %v", err)
+	}
+	lambdaClient, err := lambdaCl.NewClient(config.Region)
+	if err != nil {
+		log.Fatalf("Failed to create AWS lambda client, err: %v", err)
+	}
+	ecsClient, err := ecs.NewClient()
+	if err != nil {
+		log.Fatalf("Failed to create AWS ECS client, err: %v", err)
+	}
+
+	enricher := enrich.NewEnricher(config, resCl, lambdaClient, ecsClient)
+	enricher.StartECSContainerCacheCleanup()
+	forwarder := forward.NewForwarder(config, push.NewPusher(config))
+
+	var receivers []receiver
+	if config.FirehoseListenAddress != "" {
+		receivers = append(receivers, receiver{
+			addr: config.FirehoseListenAddress,
+			server: &http.Server{
+				Addr:    config.FirehoseListenAddress,
+				Handler: firehose.NewServer(config, enricher, forwarder),
+			},
+		})
+	}
+	if config.OTLPListenAddress != "" {
+		receivers = append(receivers, receiver{
+			addr: config.OTLPListenAddress,
+			server: &http.Server{
+				Addr:    config.OTLPListenAddress,
+				Handler: otlp.NewServer(enricher, forwarder, webhook.NewAuthenticator(config)),
+			},
+		})
+	}
+	syslogEnabled := config.SyslogUDPListenAddress != "" || config.SyslogTCPListenAddress != "" || config.SyslogTLSListenAddress != ""
+	var identity *imds.InstanceIdentity
+	if config.FluentListenAddress != "" || syslogEnabled {
+		identity = getInstanceIdentity(config)
+	}
+	if config.FluentListenAddress != "" {
+		hostName, err := os.Hostname()
+		if err != nil {
+			log.Printf("Failed to get host name, err: %v", err)
+		}
+		receivers = append(receivers, receiver{
+			addr:   config.FluentListenAddress,
+			server: fluent.NewServer(config, enricher, forwarder, identity, hostName),
+		})
+	}
+	if syslogEnabled {
+		receivers = append(receivers, receiver{
+			addr:   syslogAddr(config),
+			server: syslog.NewServer(config, enricher, forwarder, identity),
+		})
+	}
+	if len(receivers) == 0 {
+		log.Fatalf("No receiver is enabled, set ED_FIREHOSE_LISTEN_ADDRESS, ED_FLUENT_LISTEN_ADDRESS, ED_OTLP_LISTEN_ADDRESS or ED_SYSLOG_*_LISTEN_ADDRESS to enable a receiver")
+	}
+
+	for _, r := range receivers {
+		r := r
+		go func() {
+			log.Printf("Listening on %s", r.addr)
+			err := r.server.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, fluent.ErrServerClosed) && !errors.Is(err, syslog.ErrServerClosed) {
+				log.Fatalf("Failed to listen on %s, err: %v", r.addr, err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, r := range receivers {
+		if err := r.server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shutdown server on %s, err: %v", r.addr, err)
+		}
+	}
+}
+
+// getInstanceIdentity returns the EC2 instance the forwarder runs on,
+// account and region are taken from configuration if instance metadata is not available, i.e. on-prem.
+func getInstanceIdentity(config *cfg.Config) *imds.InstanceIdentity {
+	fallback := &imds.InstanceIdentity{AccountID: config.AccountID, Region: config.Region}
+	client, err := imds.NewClient()
+	if err != nil {
+		log.Printf("Failed to create EC2 metadata client, err: %v", err)
+		return fallback
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+	identity, err := client.GetInstanceIdentity(ctx)
+	if err != nil {
+		log.Printf("Instance metadata is not available, using ED_ACCOUNT_ID and AWS_REGION, err: %v", err)
+		return fallback
+	}
+	return identity
+}
+
+// syslogAddr describes the addresses of syslog listeners for logging.
+func syslogAddr(config *cfg.Config) string {
+	var addrs []string
+	for _, a := range []struct{ network, addr string }{
+		{"udp", config.SyslogUDPListenAddress},
+		{"tcp", config.SyslogTCPListenAddress},
+		{"tls", config.SyslogTLSListenAddress},
+	} {
+		if a.addr != "" {
+			addrs = append(addrs, fmt.Sprintf("%s://%s", a.network, a.addr))
+		}
+	}
+	return strings.Join(addrs, ", ")
+}
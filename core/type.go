@@ -7,11 +7,73 @@ import (
 
 type Common enrich.Common
 
+// LogEvent is a log event with optional structured attributes, i.e. metadata of the record the event is read from.
+type LogEvent struct {
+	events.CloudwatchLogsLogEvent
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// NewLogEvents returns log events without attributes.
+func NewLogEvents(logEvents []events.CloudwatchLogsLogEvent) []LogEvent {
+	res := make([]LogEvent, len(logEvents))
+	for i, e := range logEvents {
+		res[i] = LogEvent{CloudwatchLogsLogEvent: e}
+	}
+	return res
+}
+
 type Data struct {
-	LogEvents []events.CloudwatchLogsLogEvent `json:"logEvents"`
+	LogEvents []LogEvent `json:"logEvents"`
 }
 
 type Log struct {
 	Common
 	Data
 }
+
+// NewLog returns the log of CloudWatch log events without attributes, its payload is the same as before attributes were added.
+func NewLog(common Common, logEvents []events.CloudwatchLogsLogEvent) *Log {
+	return &Log{Common: common, Data: Data{LogEvents: NewLogEvents(logEvents)}}
+}
+
+// Metric is a CloudWatch metric data point, value contains statistics (max, min, sum, count) and percentiles if any.
+type Metric struct {
+	Namespace  string             `json:"namespace"`
+	Name       string             `json:"name"`
+	Dimensions map[string]string  `json:"dimensions,omitempty"`
+	Timestamp  int64              `json:"timestamp"`
+	Value      map[string]float64 `json:"value"`
+	Unit       string             `json:"unit,omitempty"`
+}
+
+type MetricData struct {
+	Metrics []Metric `json:"metrics"`
+}
+
+type Metrics struct {
+	Common
+	MetricData
+}
+
+const (
+	HealthEventSubscriptionEstablished = "subscription_established"
+)
+
+// HealthEvent is a signal about the forwarder itself rather than a log of a source, i.e. a subscription is established.
+type HealthEvent struct {
+	Type                string   `json:"type"`
+	Timestamp           int64    `json:"timestamp"`
+	Message             string   `json:"message"`
+	LogGroup            string   `json:"log_group,omitempty"`
+	SubscriptionFilters []string `json:"subscription_filters,omitempty"`
+	ControlMessage      string   `json:"control_message,omitempty"`
+}
+
+type HealthData struct {
+	HealthEvents []HealthEvent `json:"healthEvents"`
+}
+
+type Health struct {
+	Common
+	HealthData
+}
@@ -0,0 +1,75 @@
+package cwlogs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+type Client interface {
+	// FilterLogEvents returns a page of log events of the log group in [startTime, endTime] epoch milliseconds,
+	// next token of the page is empty after the last page.
+	FilterLogEvents(ctx context.Context, logGroup, filterPattern string, startTime, endTime int64, nextToken string) (*FilterLogEventsPage, error)
+}
+
+type FilterLogEventsPage struct {
+	Events    []FilteredLogEvent
+	NextToken string
+}
+
+// FilteredLogEvent is a log event with the log stream it belongs to, since a page has events of multiple streams.
+type FilteredLogEvent struct {
+	events.CloudwatchLogsLogEvent
+	LogStream string
+}
+
+type DefaultClient struct {
+	svc *cloudwatchlogs.CloudWatchLogs
+}
+
+func NewClient(region string) (*DefaultClient, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS CloudWatch Logs client, err: %v", err)
+	}
+	return &DefaultClient{svc: cloudwatchlogs.New(sess, &aws.Config{Region: aws.String(region)})}, nil
+}
+
+func (c *DefaultClient) FilterLogEvents(ctx context.Context, logGroup, filterPattern string, startTime, endTime int64, nextToken string) (*FilterLogEventsPage, error) {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(logGroup),
+		StartTime:    aws.Int64(startTime),
+		EndTime:      aws.Int64(endTime),
+	}
+	if filterPattern != "" {
+		input.FilterPattern = aws.String(filterPattern)
+	}
+	if nextToken != "" {
+		input.NextToken = aws.String(nextToken)
+	}
+
+	result, err := c.svc.FilterLogEventsWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &FilterLogEventsPage{
+		Events:    make([]FilteredLogEvent, 0, len(result.Events)),
+		NextToken: aws.StringValue(result.NextToken),
+	}
+	for _, e := range result.Events {
+		page.Events = append(page.Events, FilteredLogEvent{
+			CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{
+				ID:        aws.StringValue(e.EventId),
+				Timestamp: aws.Int64Value(e.Timestamp),
+				Message:   aws.StringValue(e.Message),
+			},
+			LogStream: aws.StringValue(e.LogStreamName),
+		})
+	}
+	return page, nil
+}
@@ -0,0 +1,36 @@
+package cwlogs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	DataMessage    = "DATA_MESSAGE"
+	ControlMessage = "CONTROL_MESSAGE"
+)
+
+// Decode decodes a CloudWatch Logs subscription payload delivered through Kinesis or Firehose.
+// Unlike the payload delivered to lambda directly, data is not base64 encoded but still gzip compressed.
+func Decode(data []byte) (*events.CloudwatchLogsData, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader, err: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var d events.CloudwatchLogsData
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("failed to decode CloudWatch logs data, err: %v", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,78 @@
+package cwlogs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	payload = `{"messageType":"DATA_MESSAGE","owner":"123456789012","logGroup":"/aws/lambda/my-function","logStream":"2024/01/01/[$LATEST]abc","subscriptionFilters":["my-filter"],"logEvents":[{"id":"1","timestamp":1704067200000,"message":"hello"}]}`
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatalf("Failed to write gzip content: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	want := &events.CloudwatchLogsData{
+		Owner:               "123456789012",
+		LogGroup:            "/aws/lambda/my-function",
+		LogStream:           "2024/01/01/[$LATEST]abc",
+		SubscriptionFilters: []string{"my-filter"},
+		MessageType:         "DATA_MESSAGE",
+		LogEvents:           []events.CloudwatchLogsLogEvent{{ID: "1", Timestamp: 1704067200000, Message: "hello"}},
+	}
+
+	tests := []struct {
+		desc    string
+		data    []byte
+		want    *events.CloudwatchLogsData
+		wantErr bool
+	}{
+		{
+			desc: "Gzip compressed payload",
+			data: gzipBytes(t, []byte(payload)),
+			want: want,
+		},
+		{
+			desc: "Uncompressed payload",
+			data: []byte(payload),
+			want: want,
+		},
+		{
+			desc:    "Invalid payload",
+			data:    gzipBytes(t, []byte("not json")),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := Decode(tc.data)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Data mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
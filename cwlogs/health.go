@@ -0,0 +1,42 @@
+package cwlogs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+)
+
+// GetHealthEvents converts a control message payload, which CloudWatch Logs sends to check if the destination is reachable
+// when a subscription is created, into health events so it is not forwarded as a log of the log group.
+func GetHealthEvents(data *events.CloudwatchLogsData) []core.HealthEvent {
+	message := "Subscription destination is reachable"
+	if data.LogGroup != "" {
+		message = fmt.Sprintf("Subscription established for log group %s", data.LogGroup)
+		if len(data.SubscriptionFilters) > 0 {
+			message += fmt.Sprintf(" with filter %s", strings.Join(data.SubscriptionFilters, ", "))
+		}
+	}
+
+	newHealthEvent := func(timestamp int64, controlMessage string) core.HealthEvent {
+		return core.HealthEvent{
+			Type:                core.HealthEventSubscriptionEstablished,
+			Timestamp:           timestamp,
+			Message:             message,
+			LogGroup:            data.LogGroup,
+			SubscriptionFilters: data.SubscriptionFilters,
+			ControlMessage:      controlMessage,
+		}
+	}
+
+	if len(data.LogEvents) == 0 {
+		return []core.HealthEvent{newHealthEvent(time.Now().UnixMilli(), "")}
+	}
+	healthEvents := make([]core.HealthEvent, 0, len(data.LogEvents))
+	for _, e := range data.LogEvents {
+		healthEvents = append(healthEvents, newHealthEvent(e.Timestamp, e.Message))
+	}
+	return healthEvents
+}
@@ -0,0 +1,63 @@
+package cwlogs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetHealthEvents(t *testing.T) {
+	tests := []struct {
+		desc string
+		data *events.CloudwatchLogsData
+		want []core.HealthEvent
+	}{
+		{
+			desc: "Subscription of a log group",
+			data: &events.CloudwatchLogsData{
+				MessageType:         ControlMessage,
+				LogGroup:            "/aws/lambda/my-function",
+				SubscriptionFilters: []string{"my-filter"},
+				LogEvents:           []events.CloudwatchLogsLogEvent{{Timestamp: 1704067200000, Message: "CWL CONTROL MESSAGE: Checking health of destination Lambda function."}},
+			},
+			want: []core.HealthEvent{
+				{
+					Type:                core.HealthEventSubscriptionEstablished,
+					Timestamp:           1704067200000,
+					Message:             "Subscription established for log group /aws/lambda/my-function with filter my-filter",
+					LogGroup:            "/aws/lambda/my-function",
+					SubscriptionFilters: []string{"my-filter"},
+					ControlMessage:      "CWL CONTROL MESSAGE: Checking health of destination Lambda function.",
+				},
+			},
+		},
+		{
+			desc: "Destination health check without log group",
+			data: &events.CloudwatchLogsData{
+				MessageType:         ControlMessage,
+				Owner:               "CloudwatchLogs",
+				SubscriptionFilters: []string{},
+				LogEvents:           []events.CloudwatchLogsLogEvent{{Timestamp: 1704067200000, Message: "CWL CONTROL MESSAGE: Checking health of destination Firehose."}},
+			},
+			want: []core.HealthEvent{
+				{
+					Type:                core.HealthEventSubscriptionEstablished,
+					Timestamp:           1704067200000,
+					Message:             "Subscription destination is reachable",
+					SubscriptionFilters: []string{},
+					ControlMessage:      "CWL CONTROL MESSAGE: Checking health of destination Firehose.",
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, GetHealthEvents(tc.data)); diff != "" {
+				t.Errorf("GetHealthEvents() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/kafka"
+)
+
+var (
+	ErrUnknownEvent = errors.New("unknown event type")
+)
+
+// Detect returns the handler mode of a lambda event by its shape, so a single forwarder can be the target of all triggers.
+func Detect(payload []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return "", fmt.Errorf("failed to decode event, err: %v", err)
+	}
+	has := func(key string) bool {
+		_, ok := fields[key]
+		return ok
+	}
+
+	switch {
+	case has("awslogs"):
+		return cfg.HandlerModeCloudwatchLogs, nil
+	case has("Records"):
+		return detectRecordsSource(fields["Records"])
+	case has("deliveryStreamArn") && has("records"):
+		return cfg.HandlerModeFirehose, nil
+	case has("eventSource") && has("records"):
+		var eventSource string
+		if err := json.Unmarshal(fields["eventSource"], &eventSource); err == nil && (eventSource == kafka.EventSourceMSK || eventSource == kafka.EventSourceSelfManaged) {
+			return cfg.HandlerModeKafka, nil
+		}
+	case has("detail-type") && has("source"):
+		return cfg.HandlerModeEventBridge, nil
+	// Function URL and API Gateway HTTP API payload format 2.0
+	case has("requestContext") && has("rawPath"):
+		return cfg.HandlerModeFunctionURL, nil
+	}
+	return "", ErrUnknownEvent
+}
+
+// detectRecordsSource detects S3, SQS, SNS and Kinesis events, which all have Records with the event source.
+func detectRecordsSource(raw json.RawMessage) (string, error) {
+	var records []struct {
+		EventSource string `json:"eventSource"`
+		// SNS records use a capitalized key
+		SNSEventSource string `json:"EventSource"`
+	}
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return "", fmt.Errorf("failed to decode event records, err: %v", err)
+	}
+	if len(records) == 0 {
+		return "", fmt.Errorf("%w: event has no records", ErrUnknownEvent)
+	}
+
+	source := records[0].EventSource
+	if source == "" {
+		source = records[0].SNSEventSource
+	}
+	switch source {
+	case "aws:s3":
+		return cfg.HandlerModeS3, nil
+	case "aws:sqs":
+		return cfg.HandlerModeSQS, nil
+	case "aws:sns":
+		return cfg.HandlerModeSNS, nil
+	case "aws:kinesis":
+		return cfg.HandlerModeKinesis, nil
+	}
+	return "", fmt.Errorf("%w: unknown event source %q", ErrUnknownEvent, source)
+}
@@ -0,0 +1,113 @@
+package dispatch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		desc        string
+		payload     string
+		want        string
+		wantErr     bool
+		wantUnknown bool
+	}{
+		{
+			desc:    "CloudWatch Logs subscription",
+			payload: `{"awslogs": {"data": "H4sIAAAAAAAAA6tWKkktLlGyUlAqS8wpTtVRSs7PS0lNLkktVrKqVsrMS8tXslIqSs1NLMrJzEtXqgUAMR1f6ygAAAA="}}`,
+			want:    cfg.HandlerModeCloudwatchLogs,
+		},
+		{
+			desc:    "S3 notification",
+			payload: `{"Records": [{"eventVersion": "2.1", "eventSource": "aws:s3", "eventName": "ObjectCreated:Put", "s3": {"bucket": {"name": "logs"}, "object": {"key": "a.log"}}}]}`,
+			want:    cfg.HandlerModeS3,
+		},
+		{
+			desc:    "SQS messages",
+			payload: `{"Records": [{"messageId": "1", "body": "hello", "eventSource": "aws:sqs", "eventSourceARN": "arn:aws:sqs:us-east-1:123456789012:queue"}]}`,
+			want:    cfg.HandlerModeSQS,
+		},
+		{
+			desc:    "SNS notification",
+			payload: `{"Records": [{"EventSource": "aws:sns", "EventVersion": "1.0", "Sns": {"TopicArn": "arn:aws:sns:us-east-1:123456789012:topic", "Message": "hello"}}]}`,
+			want:    cfg.HandlerModeSNS,
+		},
+		{
+			desc:    "Kinesis records",
+			payload: `{"Records": [{"eventSource": "aws:kinesis", "eventID": "shardId-000000000000:1", "kinesis": {"data": "aGVsbG8="}}]}`,
+			want:    cfg.HandlerModeKinesis,
+		},
+		{
+			desc:    "Firehose transformation",
+			payload: `{"invocationId": "1", "deliveryStreamArn": "arn:aws:firehose:us-east-1:123456789012:deliverystream/logs", "region": "us-east-1", "records": [{"recordId": "1", "data": "aGVsbG8="}]}`,
+			want:    cfg.HandlerModeFirehose,
+		},
+		{
+			desc:    "MSK records",
+			payload: `{"eventSource": "aws:kafka", "eventSourceArn": "arn:aws:kafka:us-east-1:123456789012:cluster/logs/1", "records": {"logs-0": [{"topic": "logs", "partition": 0, "offset": 1, "value": "aGVsbG8="}]}}`,
+			want:    cfg.HandlerModeKafka,
+		},
+		{
+			desc:    "Self managed Kafka records",
+			payload: `{"eventSource": "SelfManagedKafka", "bootstrapServers": "b-1:9092", "records": {"logs-0": []}}`,
+			want:    cfg.HandlerModeKafka,
+		},
+		{
+			desc:    "EventBridge event",
+			payload: `{"version": "0", "id": "1", "detail-type": "EC2 Instance State-change Notification", "source": "aws.ec2", "detail": {}}`,
+			want:    cfg.HandlerModeEventBridge,
+		},
+		{
+			desc:    "Function URL request",
+			payload: `{"version": "2.0", "rawPath": "/", "headers": {}, "requestContext": {"http": {"method": "POST"}}, "body": "hello"}`,
+			want:    cfg.HandlerModeFunctionURL,
+		},
+		{
+			desc:        "Records with unknown event source",
+			payload:     `{"Records": [{"eventSource": "aws:dynamodb"}]}`,
+			wantErr:     true,
+			wantUnknown: true,
+		},
+		{
+			desc:        "Empty records",
+			payload:     `{"Records": []}`,
+			wantErr:     true,
+			wantUnknown: true,
+		},
+		{
+			desc:        "Event source that is not Kafka",
+			payload:     `{"eventSource": "aws:dynamodb", "records": {}}`,
+			wantErr:     true,
+			wantUnknown: true,
+		},
+		{
+			desc:        "Unknown event",
+			payload:     `{"hello": "world"}`,
+			wantErr:     true,
+			wantUnknown: true,
+		},
+		{
+			desc:    "Non object payload",
+			payload: `"hello"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := Detect([]byte(tt.payload))
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantUnknown, errors.Is(err, ErrUnknownEvent))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
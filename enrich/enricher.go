@@ -5,47 +5,54 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/edgedelta/edgedelta-forwarder/cfg"
 	"github.com/edgedelta/edgedelta-forwarder/ecs"
 	"github.com/edgedelta/edgedelta-forwarder/lambda"
+	"github.com/edgedelta/edgedelta-forwarder/logparser"
 	"github.com/edgedelta/edgedelta-forwarder/parser"
 	"github.com/edgedelta/edgedelta-forwarder/resource"
 	"github.com/edgedelta/edgedelta-forwarder/tag"
 	"github.com/edgedelta/edgedelta-forwarder/utils"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambdacontext"
 
 	sLambda "github.com/aws/aws-sdk-go/service/lambda"
 )
 
-var resourceARNToTagsCache = make(map[string]map[string]string)
+const (
+	// maxResourceTagsARNs is the maximum number of ARNs the resource groups tagging API accepts in a request
+	maxResourceTagsARNs = 100
+)
+
+var (
+	resourceARNToTagsCache     = make(map[string]map[string]string)
+	resourceARNToTagsCacheLock sync.RWMutex
+)
 
 func NewEnricher(conf *cfg.Config, resourceCl resource.Client, lambdaCl lambda.Client, ecsCl ecs.Client) *Enricher {
 	return &Enricher{
-		forwardForwarderTags: conf.ForwardForwarderTags,
-		forwardSourceTags:    conf.ForwardSourceTags,
-		forwardLogGroupTags:  conf.ForwardLogGroupTags,
-		sourcePrefixMap:      prepareSourcePrefixMap(conf.SourceEnvironmentPrefixes),
-		region:               conf.Region,
-		resourceCl:           resourceCl,
-		lambdaCl:             lambdaCl,
-		ecsCl:                ecsCl,
-		ecsContainerCacheMap: make(map[ecsContainerCacheKey]ecsContainerCachedResult),
-		ecsContainerCacheTTL: conf.ECSContainerCacheTTL,
-		ecsClusterOverride:   conf.ECSClusterOverride,
+		forwardForwarderTags:    conf.ForwardForwarderTags,
+		forwardSourceTags:       conf.ForwardSourceTags,
+		forwardLogGroupTags:     conf.ForwardLogGroupTags,
+		sourcePrefixMap:         prepareSourcePrefixMap(conf.SourceEnvironmentPrefixes),
+		region:                  conf.Region,
+		resourceCl:              resourceCl,
+		lambdaCl:                lambdaCl,
+		ecsCl:                   ecsCl,
+		ecsContainerCacheMap:    make(map[ecsContainerCacheKey]ecsContainerCachedResult),
+		ecsContainerCacheTTL:    conf.ECSContainerCacheTTL,
+		ecsClusterOverride:      conf.ECSClusterOverride,
+		cloudFrontDistributions: prepareCloudFrontDistributions(conf.CloudFrontRealtimeLogDistributions),
+		forwarderDetails:        make(map[string]functionDetails),
 	}
 }
 
 func (e *Enricher) GetEDCommon(ctx context.Context, subscriptionFilters []string, messageType, logGroup, logStream, accountID string) *Common {
-	var forwarderARN string
-	lc, ok := lambdacontext.FromContext(ctx)
-	if !ok {
-		log.Printf("Failed to create lambda context")
-	} else {
-		forwarderARN = lc.InvokedFunctionArn
-	}
+	forwarderARN, requestID := getInvocation(ctx)
 
 	var arnsToGetTags []string
 	if forwarderARN != "" && e.forwardForwarderTags {
@@ -81,44 +88,16 @@ func (e *Enricher) GetEDCommon(ctx context.Context, subscriptionFilters []string
 		isSourceLambda = true
 	}
 
-	var functionOutput *sLambda.GetFunctionOutput
-	if functionARN != "" {
-		function, err := e.lambdaCl.GetFunction(functionARN)
-		if err != nil {
-			log.Printf("Failed to get function for ARN: %s, err: %v", functionARN, err)
-		} else {
-			functionOutput = function
-		}
-	}
-
-	// Overwrite function version if it exists in the function output
-	if functionOutput != nil && functionOutput.Configuration != nil && functionOutput.Configuration.Version != nil {
-		functionVersion = *functionOutput.Configuration.Version
-	}
-
-	var memorySize string
-	if functionOutput != nil && functionOutput.Configuration != nil && functionOutput.Configuration.MemorySize != nil {
-		memorySize = fmt.Sprintf("%d", *functionOutput.Configuration.MemorySize)
-	}
-
-	var processRuntimeName string
-	if functionOutput != nil && functionOutput.Configuration != nil && functionOutput.Configuration.Runtime != nil {
-		processRuntimeName = *functionOutput.Configuration.Runtime
-	}
-
-	var hostArchitecture string
-	if functionOutput != nil && functionOutput.Configuration != nil && functionOutput.Configuration.Architectures != nil {
-		hostArchitecture = getRuntimeArchitecture(functionARN, forwarderARN, functionOutput.Configuration.Architectures)
-	}
+	details := e.getFunctionDetails(functionARN, forwarderARN, functionVersion)
 
 	sourceTags, faasTags, logGroupTags := e.getAllTags(ctx, forwarderARN, logGroupARN, arnsToGetTags, arnToTagSourceMap, isSourceLambda)
 	cm := &Common{
 		Cloud: &cloud{ResourceID: getResourceID(arnsToGetTags, forwarderARN, logGroupARN), AccountID: accountID, Region: e.region},
 		Faas: &faas{
 			Name:       functionName,
-			Version:    functionVersion,
-			RequestID:  lc.AwsRequestID,
-			MemorySize: memorySize,
+			Version:    details.version,
+			RequestID:  requestID,
+			MemorySize: details.memorySize,
 			Tags:       faasTags,
 		},
 		AwsCommon: &awsCommon{
@@ -132,8 +111,8 @@ func (e *Enricher) GetEDCommon(ctx context.Context, subscriptionFilters []string
 			},
 			ServiceTags: sourceTags,
 		},
-		HostArchitecture:   hostArchitecture,
-		ProcessRuntimeName: processRuntimeName,
+		HostArchitecture:   details.hostArchitecture,
+		ProcessRuntimeName: details.processRuntimeName,
 	}
 
 	ecsCluster, ecsContainerFromStream, ecsTaskID := parser.GetClusterContainerAndTaskIfSourceIsECS(logGroup, logStream, e.ecsClusterOverride)
@@ -150,13 +129,514 @@ func (e *Enricher) GetEDCommon(ctx context.Context, subscriptionFilters []string
 	return cm
 }
 
+// GetHealthCommon returns common fields for health events of the forwarder, log group fields are set if health event is about a log group.
+// Log group ARN has the given account (owner of the payload) as in GetEDCommon, since log group may be in another account.
+func (e *Enricher) GetHealthCommon(ctx context.Context, messageType, logGroup, accountID string, subscriptionFilters []string) *Common {
+	cm := e.getResourceCommon(ctx, "", nil)
+	cm.AwsCommon.LogMessageType = messageType
+	cm.AwsCommon.LogSubscriptionFilters = subscriptionFilters
+	if logGroup != "" {
+		cm.AwsCommon.LogGroup = logGroup
+		cm.AwsCommon.LogGroupARN = parser.BuildResourceARN("logs", accountID, e.region, fmt.Sprintf("log-group:%s", logGroup))
+	}
+	return cm
+}
+
+// GetS3Common returns common fields for logs read from an S3 object, bucket is used as the source to get tags.
+// Load balancer of an access log object is also used as the source, it is the resource ID if it has tags.
+// Distribution of a CloudFront standard log object is added to the common fields without its ARN.
+func (e *Enricher) GetS3Common(ctx context.Context, bucket, key string, size int64) *Common {
+	bucketARN := parser.BuildS3BucketARN(bucket)
+	sources := []tag.ServiceInfo{{Name: tag.SourceS3, ARN: bucketARN}}
+	var accountID string
+	var distribution *cloudFrontDistribution
+	if lbARN, ok := parser.GetLoadBalancerARNFromS3Key(key); ok {
+		sources = append([]tag.ServiceInfo{{Name: tag.SourceELB, ARN: lbARN}}, sources...)
+		accountID = parser.GetAccountIDFromARN(lbARN)
+	} else if distributionID, ok := parser.GetCloudFrontDistributionIDFromS3Key(key); ok {
+		// standard logs do not have the account of the distribution, which may not be the account of the bucket
+		// or the forwarder, so distribution ARN is not set and its tags are not added
+		distribution = e.newCloudFrontDistribution("", distributionID)
+	}
+	cm := e.getResourceCommon(ctx, accountID, sources)
+	cm.AwsCommon.S3 = &s3Object{
+		BucketName: bucket,
+		BucketARN:  bucketARN,
+		Key:        key,
+		Size:       size,
+	}
+	cm.AwsCommon.CloudFront = distribution
+	return cm
+}
+
+// GetS3AccessLogCommon returns common fields for a server access log object of the source bucket,
+// source bucket is used as the source to get tags instead of the bucket of the object.
+func (e *Enricher) GetS3AccessLogCommon(ctx context.Context, bucket, key string, size int64, sourceBucket string) *Common {
+	sourceBucketARN := parser.BuildS3BucketARN(sourceBucket)
+	cm := e.getResourceCommon(ctx, "", []tag.ServiceInfo{{Name: tag.SourceS3, ARN: sourceBucketARN}})
+	cm.AwsCommon.S3 = &s3Object{
+		BucketName:       bucket,
+		BucketARN:        parser.BuildS3BucketARN(bucket),
+		Key:              key,
+		Size:             size,
+		SourceBucketName: sourceBucket,
+		SourceBucketARN:  sourceBucketARN,
+	}
+	return cm
+}
+
+// GroupByWebACL groups log events of WAF logs by their web ACL and adds the web ACL to the sources of the common fields
+// of each group, since several web ACLs can log to the same log group, delivery stream or bucket, which are not named
+// after the web ACL ID. Web ACL is the resource ID of its group if it has tags. Other logs are returned as a single group
+// with the given common fields, which are not changed.
+func (e *Enricher) GroupByWebACL(ctx context.Context, cm *Common, logEvents []events.CloudwatchLogsLogEvent) []LogBatch {
+	if !e.forwardSourceTags || cm.AwsCommon == nil || len(logEvents) == 0 || !isWAFLog(cm.AwsCommon) {
+		return []LogBatch{{Common: cm, LogEvents: logEvents}}
+	}
+
+	var webACLARNs []string
+	eventsByWebACL := make(map[string][]events.CloudwatchLogsLogEvent)
+	for _, le := range logEvents {
+		// events without web ACL are grouped with empty ARN and keep the given common fields
+		webACLARN, _ := logparser.GetWAFWebACLARN(le.Message)
+		if _, ok := eventsByWebACL[webACLARN]; !ok {
+			webACLARNs = append(webACLARNs, webACLARN)
+		}
+		eventsByWebACL[webACLARN] = append(eventsByWebACL[webACLARN], le)
+	}
+
+	groups := make([]LogBatch, 0, len(webACLARNs))
+	for _, webACLARN := range webACLARNs {
+		groups = append(groups, LogBatch{Common: e.withWebACLSource(ctx, cm, webACLARN), LogEvents: eventsByWebACL[webACLARN]})
+	}
+	return groups
+}
+
+// withWebACLSource returns a copy of the common fields with tags of the web ACL, common fields are returned as is
+// if the web ACL is unknown or it has no tags.
+func (e *Enricher) withWebACLSource(ctx context.Context, cm *Common, webACLARN string) *Common {
+	if webACLARN == "" {
+		return cm
+	}
+	sourceTags, _, _ := e.getAllTags(ctx, "", "", []string{webACLARN}, map[string]tag.Source{webACLARN: tag.SourceWAF}, false)
+	if len(sourceTags) == 0 {
+		return cm
+	}
+
+	c := *cm
+	cl := *cm.Cloud
+	cl.ResourceID = webACLARN
+	c.Cloud = &cl
+	ac := *cm.AwsCommon
+	ac.ServiceTags = make(map[string]string, len(cm.AwsCommon.ServiceTags)+len(sourceTags))
+	for k, v := range cm.AwsCommon.ServiceTags {
+		ac.ServiceTags[k] = v
+	}
+	for k, v := range sourceTags {
+		ac.ServiceTags[k] = v
+	}
+	c.AwsCommon = &ac
+	return &c
+}
+
+func isWAFLog(c *awsCommon) bool {
+	switch {
+	case c.LogGroup != "":
+		return logparser.IsWAFLogGroup(c.LogGroup)
+	case c.S3 != nil:
+		return logparser.IsWAFS3Key(c.S3.Key)
+	case c.Firehose != nil:
+		return logparser.IsWAFDeliveryStream(c.Firehose.DeliveryStreamARN)
+	}
+	return false
+}
+
+// GetCloudFrontRealtimeCommon returns common fields for CloudFront real-time logs delivered through a Kinesis stream.
+// Real-time logs do not have the distribution, so distribution of the stream in ED_CLOUDFRONT_REALTIME_LOG_DISTRIBUTIONS
+// is used as the source to get tags.
+func (e *Enricher) GetCloudFrontRealtimeCommon(ctx context.Context, streamARN string) *Common {
+	accountID := parser.GetAccountIDFromARN(streamARN)
+	streamName := streamARN[strings.LastIndexByte(streamARN, '/')+1:]
+	distribution := e.newCloudFrontDistribution(accountID, e.cloudFrontDistributions[streamName])
+	distribution.KinesisStreamARN = streamARN
+
+	var sources []tag.ServiceInfo
+	if distribution.DistributionARN != "" {
+		sources = append(sources, tag.ServiceInfo{Name: tag.SourceCloudFront, ARN: distribution.DistributionARN})
+	}
+	cm := e.getResourceCommon(ctx, accountID, sources)
+	cm.AwsCommon.CloudFront = distribution
+	return cm
+}
+
+// newCloudFrontDistribution returns the distribution with its ARN, ARN is empty if account or distribution is unknown.
+func (e *Enricher) newCloudFrontDistribution(accountID, distributionID string) *cloudFrontDistribution {
+	d := &cloudFrontDistribution{DistributionID: distributionID}
+	if accountID != "" && distributionID != "" {
+		d.DistributionARN = parser.BuildCloudFrontDistributionARN(accountID, distributionID)
+	}
+	return d
+}
+
+// GetFirehoseCommon returns common fields for records delivered by a Firehose delivery stream,
+// delivery stream is used as the source to get tags.
+func (e *Enricher) GetFirehoseCommon(ctx context.Context, deliveryStreamARN, requestID string) *Common {
+	var sources []tag.ServiceInfo
+	if deliveryStreamARN != "" {
+		sources = append(sources, tag.ServiceInfo{Name: tag.SourceFirehose, ARN: deliveryStreamARN})
+	}
+	cm := e.getResourceCommon(ctx, parser.GetAccountIDFromARN(deliveryStreamARN), sources)
+	cm.AwsCommon.Firehose = &firehoseDelivery{
+		DeliveryStreamARN: deliveryStreamARN,
+		RequestID:         requestID,
+	}
+	return cm
+}
+
+// GetEventBridgeCommon returns common fields for an EventBridge event, resources of the event are used as sources to get tags.
+func (e *Enricher) GetEventBridgeCommon(ctx context.Context, id, source, detailType, accountID, region string, resources []string) *Common {
+	var sources []tag.ServiceInfo
+	for _, arn := range resources {
+		if s, ok := parser.GetServiceInfoFromARN(arn); ok {
+			sources = append(sources, s)
+		} else {
+			log.Printf("Failed to get source from EventBridge event resource: %s", arn)
+		}
+	}
+
+	cm := e.getResourceCommon(ctx, accountID, sources)
+	if region != "" {
+		cm.Cloud.Region = region
+	}
+	cm.AwsCommon.EventBridge = &eventBridgeEvent{
+		ID:         id,
+		Source:     source,
+		DetailType: detailType,
+		Resources:  resources,
+	}
+	return cm
+}
+
+// GetMetricStreamCommon returns common fields of CloudWatch metric stream metrics, sources are the resources of the metrics.
+func (e *Enricher) GetMetricStreamCommon(ctx context.Context, metricStreamName, accountID, region, namespace string, sources []tag.ServiceInfo) *Common {
+	cm := e.getResourceCommon(ctx, accountID, sources)
+	if region != "" {
+		cm.Cloud.Region = region
+	}
+	// unlike log groups, dimensions identify the resource of a metric exactly
+	if len(sources) > 0 {
+		cm.Cloud.ResourceID = sources[0].ARN
+	}
+	cm.AwsCommon.MetricStream = &metricStream{
+		Name:      metricStreamName,
+		Namespace: namespace,
+	}
+	return cm
+}
+
+// GetSNSCommon returns common fields for an SNS notification, topic is used as the source to get tags.
+// If message is a CloudWatch alarm notification, alarm details are parsed into structured fields and alarm tags are also fetched.
+func (e *Enricher) GetSNSCommon(ctx context.Context, topicARN, messageID, subject, message string, messageAttributes map[string]interface{}) *Common {
+	sources := []tag.ServiceInfo{{Name: tag.SourceSNS, ARN: topicARN}}
+	notification := &snsNotification{
+		TopicARN:          topicARN,
+		MessageID:         messageID,
+		Subject:           subject,
+		MessageAttributes: getSNSMessageAttributes(messageAttributes),
+	}
+
+	if alarm, ok := parser.ParseCloudWatchAlarm(message); ok {
+		notification.Alarm = newCloudWatchAlarm(alarm)
+		if alarm.AlarmARN != "" {
+			sources = append(sources, tag.ServiceInfo{Name: tag.SourceCloudWatchAlarm, ARN: alarm.AlarmARN})
+		}
+	}
+
+	cm := e.getResourceCommon(ctx, parser.GetAccountIDFromARN(topicARN), sources)
+	cm.AwsCommon.SNS = notification
+	return cm
+}
+
+// GetHTTPCommon returns common fields for logs received over HTTP, caller identity is added instead of source tags.
+// Caller ARN is set only for IAM authenticated requests.
+func (e *Enricher) GetHTTPCommon(ctx context.Context, domainName, path, sourceIP, userAgent, authMethod, callerARN string) *Common {
+	cm := e.getResourceCommon(ctx, "", nil)
+	cm.AwsCommon.HTTP = &httpRequest{
+		DomainName:      domainName,
+		Path:            path,
+		SourceIP:        sourceIP,
+		UserAgent:       userAgent,
+		AuthMethod:      authMethod,
+		CallerAccountID: parser.GetAccountIDFromARN(callerARN),
+		CallerARN:       callerARN,
+	}
+	return cm
+}
+
+// GetKafkaCommon returns common fields for records of a Kafka topic partition, MSK cluster is used as the source to get tags.
+// Cluster ARN is empty for self-managed Kafka clusters.
+func (e *Enricher) GetKafkaCommon(ctx context.Context, eventSource, clusterARN, bootstrapServers, topic string, partition int64) *Common {
+	var sources []tag.ServiceInfo
+	if clusterARN != "" {
+		sources = append(sources, tag.ServiceInfo{Name: tag.SourceMSK, ARN: clusterARN})
+	}
+	cm := e.getResourceCommon(ctx, parser.GetAccountIDFromARN(clusterARN), sources)
+	cm.AwsCommon.Kafka = &kafkaPartition{
+		EventSource:      eventSource,
+		ClusterARN:       clusterARN,
+		BootstrapServers: bootstrapServers,
+		Topic:            topic,
+		Partition:        partition,
+	}
+	return cm
+}
+
+// GetHostCommon returns common fields for logs received from a host, i.e. an EC2 instance or an on-prem server.
+// Instance is used as the source to get tags if instance ID is known.
+func (e *Enricher) GetHostCommon(ctx context.Context, accountID, region, instanceID, hostName string) *Common {
+	if region == "" {
+		region = e.region
+	}
+	var sources []tag.ServiceInfo
+	var instanceARN string
+	if instanceID != "" {
+		instanceARN = parser.BuildResourceARN("ec2", accountID, region, fmt.Sprintf("instance/%s", instanceID))
+		sources = append(sources, tag.ServiceInfo{Name: tag.SourceEC2, ARN: instanceARN})
+	}
+	cm := e.getResourceCommon(ctx, accountID, sources)
+	cm.Cloud.Region = region
+	if instanceARN != "" {
+		cm.Cloud.ResourceID = instanceARN
+	}
+	cm.HostName = hostName
+	cm.HostID = instanceID
+	return cm
+}
+
+// PrepareOTLPTags gets tags of the resources of an export request and the forwarder with a single request,
+// so getting common fields of each resource does not request their tags one by one.
+func (e *Enricher) PrepareOTLPTags(ctx context.Context, resources []map[string]any) {
+	var sources []tag.ServiceInfo
+	for _, r := range resources {
+		resourceID, _ := r["cloud.resource_id"].(string)
+		if source, ok := parser.GetServiceInfoFromARN(resourceID); ok {
+			sources = append(sources, source)
+		}
+	}
+	e.PrepareSourceTags(ctx, sources)
+}
+
+// GetOTLPCommon returns common fields for OpenTelemetry logs. Cloud and host resource attributes are mapped to their fields
+// and resource ID is used as the source to get tags if it is an ARN, other attributes are kept as resource attributes.
+func (e *Enricher) GetOTLPCommon(ctx context.Context, resource map[string]any) *Common {
+	attributes := make(map[string]any, len(resource))
+	for k, v := range resource {
+		attributes[k] = v
+	}
+	popString := func(key string) string {
+		s, ok := attributes[key].(string)
+		if ok {
+			delete(attributes, key)
+		}
+		return s
+	}
+
+	accountID, region, resourceID := popString("cloud.account.id"), popString("cloud.region"), popString("cloud.resource_id")
+	var sources []tag.ServiceInfo
+	if source, ok := parser.GetServiceInfoFromARN(resourceID); ok {
+		sources = append(sources, source)
+		if accountID == "" {
+			accountID = parser.GetAccountIDFromARN(resourceID)
+		}
+	}
+
+	cm := e.getResourceCommon(ctx, accountID, sources)
+	if region != "" {
+		cm.Cloud.Region = region
+	}
+	if resourceID != "" {
+		cm.Cloud.ResourceID = resourceID
+	}
+	cm.HostName = popString("host.name")
+	cm.HostID = popString("host.id")
+	if arch := popString("host.arch"); arch != "" {
+		cm.HostArchitecture = arch
+	}
+	if len(attributes) > 0 {
+		cm.Resource = attributes
+	}
+	return cm
+}
+
+// GetLambdaFunctionCommon returns common fields for telemetry of a function collected by the forwarder running as its extension.
+// Function is used as the source, so its tags are set as faas tags as they are for logs from a lambda log group.
+func (e *Enricher) GetLambdaFunctionCommon(ctx context.Context, functionARN, functionName, functionVersion, telemetryType string) *Common {
+	var arnsToGetTags []string
+	if e.forwardSourceTags {
+		arnsToGetTags = append(arnsToGetTags, functionARN)
+	}
+	arnToTagSourceMap := map[string]tag.Source{functionARN: tag.SourceLambda}
+
+	details := e.getFunctionDetails(functionARN, "", functionVersion)
+	_, faasTags, _ := e.getAllTags(ctx, "", "", arnsToGetTags, arnToTagSourceMap, true)
+	return &Common{
+		Cloud: &cloud{ResourceID: functionARN, AccountID: parser.GetAccountIDFromARN(functionARN), Region: e.region},
+		Faas: &faas{
+			Name:       functionName,
+			Version:    details.version,
+			MemorySize: details.memorySize,
+			Tags:       faasTags,
+		},
+		AwsCommon: &awsCommon{
+			TelemetryType: telemetryType,
+		},
+		HostArchitecture:   details.hostArchitecture,
+		ProcessRuntimeName: details.processRuntimeName,
+	}
+}
+
+// PrepareSourceTags gets tags of the sources of multiple batches and the forwarder with a single request, so getting common
+// fields of each batch does not request their tags one by one.
+func (e *Enricher) PrepareSourceTags(ctx context.Context, sources []tag.ServiceInfo) {
+	var arns []string
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.InvokedFunctionArn != "" && e.forwardForwarderTags {
+		arns = append(arns, lc.InvokedFunctionArn)
+	}
+	if e.forwardSourceTags {
+		seen := make(map[string]bool, len(sources))
+		for _, s := range sources {
+			if !seen[s.ARN] {
+				seen[s.ARN] = true
+				arns = append(arns, s.ARN)
+			}
+		}
+	}
+	for len(arns) > maxResourceTagsARNs {
+		e.prepareResourceTags(ctx, arns[:maxResourceTagsARNs])
+		arns = arns[maxResourceTagsARNs:]
+	}
+	e.prepareResourceTags(ctx, arns)
+}
+
+// getResourceCommon returns common fields for sources which are not delivered through a log group.
+// Tags of the given sources are used as source tags. If account ID is empty, forwarder's account is used.
+func (e *Enricher) getResourceCommon(ctx context.Context, accountID string, sources []tag.ServiceInfo) *Common {
+	forwarderARN, requestID := getInvocation(ctx)
+
+	if accountID == "" {
+		accountID = parser.GetAccountIDFromARN(forwarderARN)
+	}
+
+	var arnsToGetTags []string
+	if forwarderARN != "" && e.forwardForwarderTags {
+		arnsToGetTags = append(arnsToGetTags, forwarderARN)
+	}
+
+	arnToTagSourceMap := map[string]tag.Source{}
+	if e.forwardSourceTags {
+		for _, s := range sources {
+			arnsToGetTags = append(arnsToGetTags, s.ARN)
+			arnToTagSourceMap[s.ARN] = s.Name
+		}
+	}
+
+	details := e.getFunctionDetails(forwarderARN, forwarderARN, lambdacontext.FunctionVersion)
+	sourceTags, faasTags, _ := e.getAllTags(ctx, forwarderARN, "", arnsToGetTags, arnToTagSourceMap, false)
+	return &Common{
+		Cloud: &cloud{ResourceID: getResourceID(arnsToGetTags, forwarderARN, ""), AccountID: accountID, Region: e.region},
+		Faas: &faas{
+			Name:       lambdacontext.FunctionName,
+			Version:    details.version,
+			RequestID:  requestID,
+			MemorySize: details.memorySize,
+			Tags:       faasTags,
+		},
+		AwsCommon: &awsCommon{
+			ServiceTags: sourceTags,
+		},
+		HostArchitecture:   details.hostArchitecture,
+		ProcessRuntimeName: details.processRuntimeName,
+	}
+}
+
+// getInvocation returns the invoked function ARN and request ID of the lambda invocation of the given context.
+// Servers, replayer and backfiller do not run in a lambda invocation, so missing lambda context is only logged in lambda.
+func getInvocation(ctx context.Context) (forwarderARN, requestID string) {
+	lc, ok := lambdacontext.FromContext(ctx)
+	if !ok {
+		if lambdacontext.FunctionName != "" {
+			log.Printf("Failed to create lambda context")
+		}
+		return "", ""
+	}
+	return lc.InvokedFunctionArn, lc.AwsRequestID
+}
+
+// getFunctionDetails gets function configuration of the given function, version is returned as is if it is not found.
+// Details of the forwarder are got once, since they are needed for every batch of sources which are not lambda functions.
+func (e *Enricher) getFunctionDetails(functionARN, forwarderARN, version string) functionDetails {
+	if functionARN == "" || functionARN != forwarderARN {
+		return e.fetchFunctionDetails(functionARN, forwarderARN, version)
+	}
+
+	e.forwarderDetailsLock.RLock()
+	details, ok := e.forwarderDetails[forwarderARN]
+	e.forwarderDetailsLock.RUnlock()
+	if ok {
+		return details
+	}
+	details = e.fetchFunctionDetails(functionARN, forwarderARN, version)
+	e.forwarderDetailsLock.Lock()
+	e.forwarderDetails[forwarderARN] = details
+	e.forwarderDetailsLock.Unlock()
+	return details
+}
+
+func (e *Enricher) fetchFunctionDetails(functionARN, forwarderARN, version string) functionDetails {
+	details := functionDetails{version: version}
+
+	var functionOutput *sLambda.GetFunctionOutput
+	if functionARN != "" {
+		function, err := e.lambdaCl.GetFunction(functionARN)
+		if err != nil {
+			log.Printf("Failed to get function for ARN: %s, err: %v", functionARN, err)
+		} else {
+			functionOutput = function
+		}
+	}
+
+	if functionOutput == nil || functionOutput.Configuration == nil {
+		return details
+	}
+	configuration := functionOutput.Configuration
+
+	// Overwrite function version if it exists in the function output
+	if configuration.Version != nil {
+		details.version = *configuration.Version
+	}
+
+	if configuration.MemorySize != nil {
+		details.memorySize = fmt.Sprintf("%d", *configuration.MemorySize)
+	}
+
+	if configuration.Runtime != nil {
+		details.processRuntimeName = *configuration.Runtime
+	}
+
+	if configuration.Architectures != nil {
+		details.hostArchitecture = getRuntimeArchitecture(functionARN, forwarderARN, configuration.Architectures)
+	}
+
+	return details
+}
+
 // getAllTags retrieves all the tags for the specified ARNs and populates the tag maps.
 func (e *Enricher) getAllTags(ctx context.Context, forwarderARN, logGroupARN string, allARNs []string, arnToService map[string]tag.Source, isSourceLambda bool) (sourceTags, faasTags, logGroupTags map[string]string) {
 	e.prepareResourceTags(ctx, allARNs)
 
 	if prefix, ok := e.sourcePrefixMap[tag.SourceLogGroup]; !ok {
-		logGroupTags = resourceARNToTagsCache[logGroupARN]
-	} else if m, ok := resourceARNToTagsCache[logGroupARN]; ok {
+		logGroupTags, _ = getCachedTags(logGroupARN)
+	} else if m, ok := getCachedTags(logGroupARN); ok {
 		logGroupTags = make(map[string]string, len(m))
 		for k, v := range m {
 			utils.SetKeyWithPrefix(logGroupTags, prefix, k, v)
@@ -164,8 +644,8 @@ func (e *Enricher) getAllTags(ctx context.Context, forwarderARN, logGroupARN str
 	}
 
 	if prefix, ok := e.sourcePrefixMap[tag.SourceForwarder]; !ok {
-		faasTags = resourceARNToTagsCache[forwarderARN]
-	} else if m, ok := resourceARNToTagsCache[forwarderARN]; ok {
+		faasTags, _ = getCachedTags(forwarderARN)
+	} else if m, ok := getCachedTags(forwarderARN); ok {
 		faasTags = make(map[string]string, len(m))
 		for k, v := range m {
 			utils.SetKeyWithPrefix(faasTags, prefix, k, v)
@@ -185,7 +665,7 @@ func (e *Enricher) getAllTags(ctx context.Context, forwarderARN, logGroupARN str
 		if arn == forwarderARN || arn == logGroupARN {
 			continue
 		}
-		if m, ok := resourceARNToTagsCache[arn]; ok {
+		if m, ok := getCachedTags(arn); ok {
 			prefix := e.sourcePrefixMap[arnToService[arn]]
 			for k, v := range m {
 				utils.SetKeyWithPrefix(tags, prefix, k, v)
@@ -197,8 +677,12 @@ func (e *Enricher) getAllTags(ctx context.Context, forwarderARN, logGroupARN str
 }
 
 func (e *Enricher) prepareResourceTags(ctx context.Context, arns []string) {
+	// GetResources returns all resources of the account when no ARN is given
+	if len(arns) == 0 {
+		return
+	}
 	tagsCacheKey := getTagsCacheKey(arns...)
-	if _, ok := resourceARNToTagsCache[tagsCacheKey]; ok {
+	if _, ok := getCachedTags(tagsCacheKey); ok || allTagsCached(arns) {
 		return
 	}
 	log.Printf("Getting resource tags for ARNs: %v", arns)
@@ -208,18 +692,27 @@ func (e *Enricher) prepareResourceTags(ctx context.Context, arns []string) {
 		return
 	}
 	if len(tagsMap) == 0 {
+		// nothing is cached, so tags added to the resources later are found by the next call
 		log.Printf("Failed to find tags for ARNs: %v", arns)
 		return
 	}
 
+	resourceARNToTagsCacheLock.Lock()
+	defer resourceARNToTagsCacheLock.Unlock()
 	for _, arn := range arns {
 		if m, ok := tagsMap[arn]; ok {
 			resourceARNToTagsCache[arn] = m
+		} else if _, ok := resourceARNToTagsCache[arn]; !ok {
+			// resources without tags are cached too, so tags prepared for multiple sources are not requested again for each source
+			resourceARNToTagsCache[arn] = map[string]string{}
 		}
 	}
 
-	// Empty map with key as all ARNs is added to cache to avoid repeated calls to resource service
-	resourceARNToTagsCache[tagsCacheKey] = map[string]string{}
+	// Empty map with key as all ARNs is added to cache to avoid repeated calls to resource service,
+	// key of a single ARN is the ARN itself, so its tags are not overwritten
+	if _, ok := resourceARNToTagsCache[tagsCacheKey]; !ok {
+		resourceARNToTagsCache[tagsCacheKey] = map[string]string{}
+	}
 }
 
 func (e *Enricher) GetECSContainerDetails(ctx context.Context, clusterName, taskID, containerName string) (*ecsContainer, []*ecsContainer, error) {
@@ -314,6 +807,33 @@ func (e *Enricher) CleanupExpiredEntries() {
 	}
 }
 
+// getCachedTags returns a copy of the cached tags, so callers can modify it while other requests are processed.
+func getCachedTags(key string) (map[string]string, bool) {
+	resourceARNToTagsCacheLock.RLock()
+	defer resourceARNToTagsCacheLock.RUnlock()
+	m, ok := resourceARNToTagsCache[key]
+	if !ok || m == nil {
+		return nil, ok
+	}
+	tags := make(map[string]string, len(m))
+	for k, v := range m {
+		tags[k] = v
+	}
+	return tags, true
+}
+
+// allTagsCached returns true if tags of every ARN are cached.
+func allTagsCached(arns []string) bool {
+	resourceARNToTagsCacheLock.RLock()
+	defer resourceARNToTagsCacheLock.RUnlock()
+	for _, arn := range arns {
+		if _, ok := resourceARNToTagsCache[arn]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func getTagsCacheKey(arns ...string) string {
 	return strings.Join(arns, ",")
 }
@@ -332,7 +852,7 @@ func getRuntimeArchitecture(functionARN, forwarderARN string, archs []*string) s
 func getResourceID(arns []string, forwarderARN, logGroupARN string) string {
 	for _, arn := range arns {
 		if arn != forwarderARN && arn != logGroupARN {
-			if m, ok := resourceARNToTagsCache[arn]; ok && len(m) > 0 {
+			if m, ok := getCachedTags(arn); ok && len(m) > 0 {
 				return arn
 			}
 		}
@@ -341,6 +861,48 @@ func getResourceID(arns []string, forwarderARN, logGroupARN string) string {
 	return forwarderARN
 }
 
+// getSNSMessageAttributes returns values of message attributes, which are delivered as {"Type": "String", "Value": "..."}.
+func getSNSMessageAttributes(attributes map[string]interface{}) map[string]string {
+	if len(attributes) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		attribute, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if value, ok := attribute["Value"].(string); ok {
+			m[k] = value
+		}
+	}
+	return m
+}
+
+func newCloudWatchAlarm(alarm *events.CloudWatchAlarmSNSPayload) *cloudWatchAlarm {
+	a := &cloudWatchAlarm{
+		Name:               alarm.AlarmName,
+		ARN:                alarm.AlarmARN,
+		Description:        alarm.AlarmDescription,
+		NewState:           alarm.NewStateValue,
+		OldState:           alarm.OldStateValue,
+		Reason:             alarm.NewStateReason,
+		StateChangeTime:    alarm.StateChangeTime,
+		MetricName:         alarm.Trigger.MetricName,
+		Namespace:          alarm.Trigger.Namespace,
+		Statistic:          alarm.Trigger.Statistic,
+		ComparisonOperator: alarm.Trigger.ComparisonOperator,
+		Threshold:          alarm.Trigger.Threshold,
+	}
+	if len(alarm.Trigger.Dimensions) > 0 {
+		a.Dimensions = make(map[string]string, len(alarm.Trigger.Dimensions))
+		for _, d := range alarm.Trigger.Dimensions {
+			a.Dimensions[d.Name] = d.Value
+		}
+	}
+	return a
+}
+
 func initializeMapIfEmpty(m map[string]string) map[string]string {
 	if m == nil {
 		return make(map[string]string)
@@ -366,6 +928,21 @@ func prepareSourcePrefixMap(prefixes string) map[tag.Source]string {
 	return prefixMap
 }
 
+// prepareCloudFrontDistributions parses comma separated "<kinesis_stream_name>=<distribution_id>" pairs.
+func prepareCloudFrontDistributions(distributions string) map[string]string {
+	if distributions == "" {
+		return nil
+	}
+	distributionMap := make(map[string]string)
+	for _, p := range strings.Split(distributions, ",") {
+		parts := strings.Split(strings.TrimSpace(p), "=")
+		if len(parts) == 2 {
+			distributionMap[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	return distributionMap
+}
+
 func safeDeref(ptr *string) string {
 	if ptr == nil {
 		return ""
@@ -1,9 +1,17 @@
 package enrich
 
 import (
+	"bytes"
 	"context"
+	"log"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go/aws"
+	sLambda "github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/edgedelta/edgedelta-forwarder/cfg"
 	"github.com/edgedelta/edgedelta-forwarder/ecs"
 	"github.com/edgedelta/edgedelta-forwarder/lambda"
@@ -291,3 +299,294 @@ func TestGetLambdaTags(t *testing.T) {
 	}
 
 }
+
+func TestGroupByWebACL(t *testing.T) {
+	webACLARN := "arn:aws:wafv2:us-west-2:123456789012:regional/webacl/my-acl/1234"
+	otherWebACLARN := "arn:aws:wafv2:us-west-2:123456789012:regional/webacl/other-acl/5678"
+	resourceCl := &mockResourceClient{tags: map[string]map[string]string{
+		webACLARN:      {"team": "security"},
+		otherWebACLARN: {"team": "platform"},
+	}}
+	e := NewEnricher(&cfg.Config{ForwardSourceTags: true, SourceEnvironmentPrefixes: "wafv2=waf_"}, resourceCl, lambda.NewNoOpClient(), ecs.NewNoOpClient())
+	defer func() {
+		resourceARNToTagsCache = make(map[string]map[string]string)
+	}()
+
+	logEvents := []events.CloudwatchLogsLogEvent{
+		{ID: "1", Message: `{"timestamp":1576280412771,"webaclId":"` + webACLARN + `","action":"BLOCK"}`},
+		{ID: "2", Message: `{"timestamp":1576280412772,"webaclId":"` + otherWebACLARN + `","action":"ALLOW"}`},
+		{ID: "3", Message: "not a WAF log"},
+		{ID: "4", Message: `{"timestamp":1576280412773,"webaclId":"` + webACLARN + `","action":"ALLOW"}`},
+	}
+
+	type group struct {
+		IDs        []string
+		Tags       map[string]string
+		ResourceID string
+	}
+	tests := []struct {
+		desc     string
+		logGroup string
+		expected []group
+	}{
+		{
+			desc:     "WAF log group",
+			logGroup: "aws-waf-logs-my-acl",
+			expected: []group{
+				{IDs: []string{"1", "4"}, Tags: map[string]string{"source": "log", "waf_team": "security"}, ResourceID: webACLARN},
+				{IDs: []string{"2"}, Tags: map[string]string{"source": "log", "waf_team": "platform"}, ResourceID: otherWebACLARN},
+				{IDs: []string{"3"}, Tags: map[string]string{"source": "log"}, ResourceID: "log-group-arn"},
+			},
+		},
+		{
+			desc:     "Other log group",
+			logGroup: "/aws/lambda/my-function",
+			expected: []group{
+				{IDs: []string{"1", "2", "3", "4"}, Tags: map[string]string{"source": "log"}, ResourceID: "log-group-arn"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cm := &Common{
+				Cloud:     &cloud{ResourceID: "log-group-arn"},
+				AwsCommon: &awsCommon{awsLogs: awsLogs{LogGroup: tt.logGroup}, ServiceTags: map[string]string{"source": "log"}},
+			}
+			var got []group
+			for _, b := range e.GroupByWebACL(context.Background(), cm, logEvents) {
+				g := group{Tags: b.Common.AwsCommon.ServiceTags, ResourceID: b.Common.Cloud.ResourceID}
+				for _, le := range b.LogEvents {
+					g.IDs = append(g.IDs, le.ID)
+				}
+				got = append(got, g)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("unexpected groups (-want +got):\n%s", diff)
+			}
+			// common fields of the log group are shared by its batches, so they should not be changed
+			if cm.Cloud.ResourceID != "log-group-arn" || len(cm.AwsCommon.ServiceTags) != 1 {
+				t.Errorf("expected common fields not to be changed, got: %+v %+v", cm.Cloud, cm.AwsCommon.ServiceTags)
+			}
+		})
+	}
+}
+
+type countingResourceClient struct {
+	mockResourceClient
+	calls int
+}
+
+func (c *countingResourceClient) GetResourceTags(ctx context.Context, resourceARNs ...string) (map[string]map[string]string, error) {
+	c.calls++
+	return c.mockResourceClient.GetResourceTags(ctx, resourceARNs...)
+}
+
+func TestPrepareResourceTags(t *testing.T) {
+	bucketARN := "arn:aws:s3:::my-bucket"
+	tests := []struct {
+		desc         string
+		arns         []string
+		expectedTags map[string]map[string]string
+	}{
+		{
+			desc:         "Single ARN is its own cache key",
+			arns:         []string{bucketARN},
+			expectedTags: map[string]map[string]string{bucketARN: {"team": "data"}},
+		},
+		{
+			desc: "Multiple ARNs",
+			arns: []string{forwarderARN, bucketARN},
+			expectedTags: map[string]map[string]string{
+				bucketARN:                                {"team": "data"},
+				forwarderARN:                             copyMap(forwarderTags),
+				getTagsCacheKey(forwarderARN, bucketARN): {},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			resourceARNToTagsCache = make(map[string]map[string]string)
+			defer func() {
+				resourceARNToTagsCache = make(map[string]map[string]string)
+			}()
+
+			resourceCl := &countingResourceClient{mockResourceClient: mockResourceClient{tags: map[string]map[string]string{
+				bucketARN:    {"team": "data"},
+				forwarderARN: copyMap(forwarderTags),
+			}}}
+			e := NewEnricher(&cfg.Config{}, resourceCl, lambda.NewNoOpClient(), ecs.NewNoOpClient())
+			e.prepareResourceTags(context.Background(), tt.arns)
+			e.prepareResourceTags(context.Background(), tt.arns)
+
+			if diff := cmp.Diff(tt.expectedTags, resourceARNToTagsCache); diff != "" {
+				t.Errorf("unexpected cached tags (-want +got):\n%s", diff)
+			}
+			if resourceCl.calls != 1 {
+				t.Errorf("expected tags to be requested once, got: %d", resourceCl.calls)
+			}
+		})
+	}
+}
+
+func TestPrepareResourceTagsAddedLater(t *testing.T) {
+	resourceARNToTagsCache = make(map[string]map[string]string)
+	defer func() {
+		resourceARNToTagsCache = make(map[string]map[string]string)
+	}()
+
+	bucketARN := "arn:aws:s3:::my-bucket"
+	arns := []string{forwarderARN, bucketARN}
+	resourceCl := &countingResourceClient{mockResourceClient: mockResourceClient{tags: map[string]map[string]string{}}}
+	e := NewEnricher(&cfg.Config{}, resourceCl, lambda.NewNoOpClient(), ecs.NewNoOpClient())
+	e.prepareResourceTags(context.Background(), arns)
+	if diff := cmp.Diff(map[string]map[string]string{}, resourceARNToTagsCache); diff != "" {
+		t.Errorf("unexpected cached tags of resources without tags (-want +got):\n%s", diff)
+	}
+
+	resourceCl.tags = map[string]map[string]string{bucketARN: {"team": "data"}}
+	e.prepareResourceTags(context.Background(), arns)
+	if resourceCl.calls != 2 {
+		t.Errorf("expected tags to be requested again, got: %d requests", resourceCl.calls)
+	}
+	if diff := cmp.Diff(map[string]string{"team": "data"}, resourceARNToTagsCache[bucketARN]); diff != "" {
+		t.Errorf("unexpected tags added later (-want +got):\n%s", diff)
+	}
+}
+
+type countingLambdaClient struct {
+	calls int
+}
+
+func (c *countingLambdaClient) GetFunction(functionARN string) (*sLambda.GetFunctionOutput, error) {
+	c.calls++
+	return &sLambda.GetFunctionOutput{Configuration: &sLambda.FunctionConfiguration{Version: aws.String("3"), MemorySize: aws.Int64(256)}}, nil
+}
+
+func TestMetricStreamCommonOfMultipleSources(t *testing.T) {
+	resourceARNToTagsCache = make(map[string]map[string]string)
+	defer func() {
+		resourceARNToTagsCache = make(map[string]map[string]string)
+	}()
+
+	instanceARN := "arn:aws:ec2:us-west-2:123456789012:instance/i-0123456789abcdef0"
+	queueARN := "arn:aws:sqs:us-west-2:123456789012:my-queue"
+	resourceCl := &countingResourceClient{mockResourceClient: mockResourceClient{tags: map[string]map[string]string{
+		instanceARN:  {"team": "compute"},
+		forwarderARN: copyMap(forwarderTags),
+	}}}
+	lambdaCl := &countingLambdaClient{}
+	e := NewEnricher(&cfg.Config{ForwardSourceTags: true, ForwardForwarderTags: true}, resourceCl, lambdaCl, ecs.NewNoOpClient())
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: forwarderARN})
+
+	sources := []tag.ServiceInfo{{Name: tag.SourceEC2, ARN: instanceARN}, {Name: "sqs", ARN: queueARN}}
+	e.PrepareSourceTags(ctx, sources)
+	instanceCommon := e.GetMetricStreamCommon(ctx, "my-stream", "123456789012", "us-west-2", "AWS/EC2", sources[:1])
+	queueCommon := e.GetMetricStreamCommon(ctx, "my-stream", "123456789012", "us-west-2", "AWS/SQS", sources[1:])
+
+	if resourceCl.calls != 1 {
+		t.Errorf("expected tags of all sources to be requested once, got: %d requests", resourceCl.calls)
+	}
+	if lambdaCl.calls != 1 {
+		t.Errorf("expected forwarder function to be requested once, got: %d requests", lambdaCl.calls)
+	}
+	if diff := cmp.Diff(map[string]string{"team": "compute"}, instanceCommon.AwsCommon.ServiceTags); diff != "" {
+		t.Errorf("unexpected instance tags (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{}, queueCommon.AwsCommon.ServiceTags); diff != "" {
+		t.Errorf("unexpected queue tags (-want +got):\n%s", diff)
+	}
+	if queueCommon.Faas.Version != "3" || queueCommon.Faas.MemorySize != "256" {
+		t.Errorf("unexpected forwarder details: %+v", queueCommon.Faas)
+	}
+}
+
+func TestOTLPCommonOfMultipleResources(t *testing.T) {
+	resourceARNToTagsCache = make(map[string]map[string]string)
+	defer func() {
+		resourceARNToTagsCache = make(map[string]map[string]string)
+	}()
+
+	instanceARN := "arn:aws:ec2:us-west-2:123456789012:instance/i-0123456789abcdef0"
+	functionARN := "arn:aws:lambda:us-west-2:123456789012:function:my-function"
+	resourceCl := &countingResourceClient{mockResourceClient: mockResourceClient{tags: map[string]map[string]string{
+		instanceARN:  {"team": "compute"},
+		forwarderARN: copyMap(forwarderTags),
+	}}}
+	lambdaCl := &countingLambdaClient{}
+	e := NewEnricher(&cfg.Config{ForwardSourceTags: true, ForwardForwarderTags: true}, resourceCl, lambdaCl, ecs.NewNoOpClient())
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: forwarderARN})
+
+	resources := []map[string]any{
+		{"cloud.resource_id": instanceARN, "service.name": "api"},
+		{"cloud.resource_id": functionARN},
+		{"service.name": "worker"},
+	}
+	e.PrepareOTLPTags(ctx, resources)
+	var commons []*Common
+	for _, r := range resources {
+		commons = append(commons, e.GetOTLPCommon(ctx, r))
+	}
+
+	if resourceCl.calls != 1 {
+		t.Errorf("expected tags of all resources to be requested once, got: %d requests", resourceCl.calls)
+	}
+	if lambdaCl.calls != 1 {
+		t.Errorf("expected forwarder function to be requested once, got: %d requests", lambdaCl.calls)
+	}
+	if diff := cmp.Diff(map[string]string{"team": "compute"}, commons[0].AwsCommon.ServiceTags); diff != "" {
+		t.Errorf("unexpected instance tags (-want +got):\n%s", diff)
+	}
+	for _, cm := range commons {
+		if cm.Faas.Version != "3" || cm.Faas.MemorySize != "256" {
+			t.Errorf("unexpected forwarder details: %+v", cm.Faas)
+		}
+	}
+}
+
+func TestGetHealthCommon(t *testing.T) {
+	e := NewEnricher(&cfg.Config{Region: "us-west-2"}, &mockResourceClient{}, lambda.NewNoOpClient(), ecs.NewNoOpClient())
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: forwarderARN})
+
+	cm := e.GetHealthCommon(ctx, "CONTROL_MESSAGE", "/aws/lambda/my-function", "111122223333", []string{"filter"})
+	if cm.AwsCommon.LogGroupARN != "arn:aws:logs:us-west-2:111122223333:log-group:/aws/lambda/my-function" {
+		t.Errorf("expected log group ARN in the account of the owner, got: %s", cm.AwsCommon.LogGroupARN)
+	}
+	if cm.Cloud.AccountID != "123456789012" {
+		t.Errorf("expected account of the forwarder, got: %s", cm.Cloud.AccountID)
+	}
+
+	cm = e.GetHealthCommon(ctx, "CONTROL_MESSAGE", "", "CloudwatchLogs", nil)
+	if cm.AwsCommon.LogGroup != "" || cm.AwsCommon.LogGroupARN != "" {
+		t.Errorf("expected no log group fields, got: %s %s", cm.AwsCommon.LogGroup, cm.AwsCommon.LogGroupARN)
+	}
+}
+
+func TestGetInvocationOutsideLambda(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	functionName := lambdacontext.FunctionName
+	defer func() { lambdacontext.FunctionName = functionName }()
+
+	lambdacontext.FunctionName = ""
+	if arn, requestID := getInvocation(context.Background()); arn != "" || requestID != "" {
+		t.Errorf("expected empty invocation outside lambda, got arn: %s, request ID: %s", arn, requestID)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no log outside lambda, got: %s", buf.String())
+	}
+
+	lambdacontext.FunctionName = "forwarder"
+	getInvocation(context.Background())
+	if !strings.Contains(buf.String(), "Failed to create lambda context") {
+		t.Errorf("expected missing lambda context to be logged in lambda, got: %s", buf.String())
+	}
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: forwarderARN, AwsRequestID: "request-id"})
+	if arn, requestID := getInvocation(ctx); arn != forwarderARN || requestID != "request-id" {
+		t.Errorf("unexpected invocation, arn: %s, request ID: %s", arn, requestID)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/edgedelta/edgedelta-forwarder/ecs"
 	"github.com/edgedelta/edgedelta-forwarder/lambda"
 	"github.com/edgedelta/edgedelta-forwarder/resource"
@@ -34,6 +35,24 @@ type Enricher struct {
 	ecsContainerCacheLock sync.RWMutex
 	ecsContainerCacheTTL  time.Duration
 	ecsClusterOverride    string
+	// cloudFrontDistributions maps Kinesis streams of CloudFront real-time logs to their distributions
+	cloudFrontDistributions map[string]string
+	// forwarderDetails caches function details of the forwarder, its configuration does not change while it runs
+	forwarderDetails     map[string]functionDetails
+	forwarderDetailsLock sync.RWMutex
+}
+
+// LogBatch is log events with their common fields.
+type LogBatch struct {
+	Common    *Common
+	LogEvents []events.CloudwatchLogsLogEvent
+}
+
+type functionDetails struct {
+	version            string
+	memorySize         string
+	processRuntimeName string
+	hostArchitecture   string
 }
 
 type Common struct {
@@ -41,7 +60,11 @@ type Common struct {
 	Faas               *faas      `json:"faas"`
 	AwsCommon          *awsCommon `json:"aws"`
 	HostArchitecture   string     `json:"host.arch,omitempty"`
+	HostName           string     `json:"host.name,omitempty"`
+	HostID             string     `json:"host.id,omitempty"`
 	ProcessRuntimeName string     `json:"process.runtime.name,omitempty"`
+	// Resource has OpenTelemetry resource attributes which are not mapped to other fields
+	Resource map[string]any `json:"resource,omitempty"`
 }
 
 type faas struct {
@@ -79,8 +102,92 @@ type ecsContainerWrapper struct {
 	ContainerList []*ecsContainer `json:"container_list,omitempty"`
 }
 
+type s3Object struct {
+	BucketName string `json:"bucket.name"`
+	BucketARN  string `json:"bucket.arn"`
+	Key        string `json:"object.key"`
+	Size       int64  `json:"object.size"`
+	// SourceBucketName is the bucket whose server access log is the object
+	SourceBucketName string `json:"source_bucket.name,omitempty"`
+	SourceBucketARN  string `json:"source_bucket.arn,omitempty"`
+}
+
+type firehoseDelivery struct {
+	DeliveryStreamARN string `json:"delivery_stream.arn,omitempty"`
+	RequestID         string `json:"request_id,omitempty"`
+}
+
+type eventBridgeEvent struct {
+	ID         string   `json:"id"`
+	Source     string   `json:"source"`
+	DetailType string   `json:"detail_type"`
+	Resources  []string `json:"resources,omitempty"`
+}
+
+type cloudWatchAlarm struct {
+	Name               string            `json:"name"`
+	ARN                string            `json:"arn,omitempty"`
+	Description        string            `json:"description,omitempty"`
+	NewState           string            `json:"state.new"`
+	OldState           string            `json:"state.old,omitempty"`
+	Reason             string            `json:"state.reason,omitempty"`
+	StateChangeTime    string            `json:"state.change_time,omitempty"`
+	MetricName         string            `json:"metric.name,omitempty"`
+	Namespace          string            `json:"metric.namespace,omitempty"`
+	Dimensions         map[string]string `json:"metric.dimensions,omitempty"`
+	Statistic          string            `json:"metric.statistic,omitempty"`
+	ComparisonOperator string            `json:"comparison_operator,omitempty"`
+	Threshold          float64           `json:"threshold,omitempty"`
+}
+
+type snsNotification struct {
+	TopicARN          string            `json:"topic.arn"`
+	MessageID         string            `json:"message_id"`
+	Subject           string            `json:"subject,omitempty"`
+	MessageAttributes map[string]string `json:"message_attributes,omitempty"`
+	Alarm             *cloudWatchAlarm  `json:"alarm,omitempty"`
+}
+
+type metricStream struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace"`
+}
+
+type httpRequest struct {
+	DomainName      string `json:"domain_name,omitempty"`
+	Path            string `json:"path,omitempty"`
+	SourceIP        string `json:"source_ip,omitempty"`
+	UserAgent       string `json:"user_agent,omitempty"`
+	AuthMethod      string `json:"caller.auth_method,omitempty"`
+	CallerAccountID string `json:"caller.account_id,omitempty"`
+	CallerARN       string `json:"caller.arn,omitempty"`
+}
+
+type kafkaPartition struct {
+	EventSource      string `json:"event_source"`
+	ClusterARN       string `json:"cluster.arn,omitempty"`
+	BootstrapServers string `json:"bootstrap_servers,omitempty"`
+	Topic            string `json:"topic"`
+	Partition        int64  `json:"partition"`
+}
+
+type cloudFrontDistribution struct {
+	DistributionID   string `json:"distribution.id,omitempty"`
+	DistributionARN  string `json:"distribution.arn,omitempty"`
+	KinesisStreamARN string `json:"kinesis_stream.arn,omitempty"`
+}
+
 type awsCommon struct {
 	awsLogs
-	ServiceTags map[string]string    `json:"service.tags,omitempty"`
-	ECS         *ecsContainerWrapper `json:"ecs,omitempty"`
+	ServiceTags   map[string]string       `json:"service.tags,omitempty"`
+	ECS           *ecsContainerWrapper    `json:"ecs,omitempty"`
+	S3            *s3Object               `json:"s3,omitempty"`
+	Firehose      *firehoseDelivery       `json:"firehose,omitempty"`
+	EventBridge   *eventBridgeEvent       `json:"eventbridge,omitempty"`
+	SNS           *snsNotification        `json:"sns,omitempty"`
+	TelemetryType string                  `json:"lambda.telemetry.type,omitempty"`
+	MetricStream  *metricStream           `json:"metric_stream,omitempty"`
+	HTTP          *httpRequest            `json:"http,omitempty"`
+	Kafka         *kafkaPartition         `json:"kafka,omitempty"`
+	CloudFront    *cloudFrontDistribution `json:"cloudfront,omitempty"`
 }
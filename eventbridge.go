@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// handleEventBridgeRequest forwards an EventBridge event (i.e. ECS task state change, AWS Health, GuardDuty finding)
+// as a single log event whose message is the event itself.
+func handleEventBridgeRequest(ctx context.Context, event events.CloudWatchEvent) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in handleEventBridgeRequest, err: %v", r)
+		}
+	}()
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal EventBridge event: %s, err: %v", event.ID, err)
+		return fmt.Errorf("failed to marshal event, err: %v", err)
+	}
+
+	common := enricher.GetEventBridgeCommon(ctx, event.ID, event.Source, event.DetailType, event.AccountID, event.Region, event.Resources)
+	return forwarder.Forward(ctx, common, []events.CloudwatchLogsLogEvent{
+		{
+			ID:        event.ID,
+			Timestamp: event.Time.UnixMilli(),
+			Message:   string(message),
+		},
+	})
+}
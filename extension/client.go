@@ -0,0 +1,170 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	extensionAPIVersion          = "2020-01-01"
+	telemetryAPIVersion          = "2022-07-01"
+	telemetrySchemaVersion       = "2022-12-13"
+	extensionNameHeader          = "Lambda-Extension-Name"
+	extensionIdentifierHeader    = "Lambda-Extension-Identifier"
+	extensionAcceptFeatureHeader = "Lambda-Extension-Accept-Feature"
+)
+
+type EventType string
+
+const (
+	Invoke   EventType = "INVOKE"
+	Shutdown EventType = "SHUTDOWN"
+)
+
+// Telemetry types to subscribe to
+const (
+	TelemetryTypePlatform  = "platform"
+	TelemetryTypeFunction  = "function"
+	TelemetryTypeExtension = "extension"
+)
+
+type RegisterResponse struct {
+	FunctionName    string `json:"functionName"`
+	FunctionVersion string `json:"functionVersion"`
+	Handler         string `json:"handler"`
+	AccountID       string `json:"accountId"`
+}
+
+type NextEventResponse struct {
+	EventType          EventType `json:"eventType"`
+	DeadlineMs         int64     `json:"deadlineMs"`
+	RequestID          string    `json:"requestId"`
+	InvokedFunctionARN string    `json:"invokedFunctionArn"`
+	ShutdownReason     string    `json:"shutdownReason"`
+}
+
+type telemetrySubscription struct {
+	SchemaVersion string               `json:"schemaVersion"`
+	Destination   telemetryDestination `json:"destination"`
+	Types         []string             `json:"types"`
+	Buffering     telemetryBuffering   `json:"buffering"`
+}
+
+type telemetryDestination struct {
+	Protocol string `json:"protocol"`
+	URI      string `json:"URI"`
+}
+
+type telemetryBuffering struct {
+	MaxItems  int `json:"maxItems"`
+	MaxBytes  int `json:"maxBytes"`
+	TimeoutMs int `json:"timeoutMs"`
+}
+
+// Client talks to Lambda Extensions API and Telemetry API, which are served from AWS_LAMBDA_RUNTIME_API.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	extensionID string
+}
+
+func NewClient(runtimeAPI string) *Client {
+	return &Client{
+		baseURL: fmt.Sprintf("http://%s", runtimeAPI),
+		// no timeout since next event request blocks until the next invocation
+		httpClient: &http.Client{},
+	}
+}
+
+// Register registers the extension for invoke and shutdown events, name must match the file name of the extension.
+func (c *Client) Register(ctx context.Context, name string) (*RegisterResponse, error) {
+	body, err := json.Marshal(map[string][]EventType{"events": {Invoke, Shutdown}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal register request, err: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s/extension/register", c.baseURL, extensionAPIVersion), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create register request, err: %v", err)
+	}
+	req.Header.Set(extensionNameHeader, name)
+	req.Header.Set(extensionAcceptFeatureHeader, "accountId")
+
+	var resp RegisterResponse
+	header, err := c.do(req, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register extension, err: %v", err)
+	}
+	c.extensionID = header.Get(extensionIdentifierHeader)
+	if c.extensionID == "" {
+		return nil, fmt.Errorf("extension identifier is missing in register response")
+	}
+	return &resp, nil
+}
+
+// NextEvent blocks until the next invoke or shutdown event.
+func (c *Client) NextEvent(ctx context.Context) (*NextEventResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s/extension/event/next", c.baseURL, extensionAPIVersion), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create next event request, err: %v", err)
+	}
+	req.Header.Set(extensionIdentifierHeader, c.extensionID)
+
+	var resp NextEventResponse
+	if _, err := c.do(req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get next event, err: %v", err)
+	}
+	return &resp, nil
+}
+
+// SubscribeTelemetry subscribes given telemetry types, Lambda posts them to destination in batches.
+func (c *Client) SubscribeTelemetry(ctx context.Context, destinationURI string, types []string) error {
+	body, err := json.Marshal(telemetrySubscription{
+		SchemaVersion: telemetrySchemaVersion,
+		Destination:   telemetryDestination{Protocol: "HTTP", URI: destinationURI},
+		Types:         types,
+		Buffering:     telemetryBuffering{MaxItems: 1000, MaxBytes: 256 * 1024, TimeoutMs: 100},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry subscription, err: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/%s/telemetry", c.baseURL, telemetryAPIVersion), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry subscription request, err: %v", err)
+	}
+	req.Header.Set(extensionIdentifierHeader, c.extensionID)
+
+	if _, err := c.do(req, nil); err != nil {
+		return fmt.Errorf("failed to subscribe telemetry, err: %v", err)
+	}
+	return nil
+}
+
+func (c *Client) do(req *http.Request, out interface{}) (http.Header, error) {
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body, err: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code: %d response: %s", resp.StatusCode, body)
+	}
+	if out == nil || len(body) == 0 {
+		return resp.Header, nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %s, err: %v", body, err)
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,136 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultShutdownDrainPeriod is the time waited on shutdown for telemetry of the last invocation,
+	// it is longer than telemetry buffering timeout
+	defaultShutdownDrainPeriod = 500 * time.Millisecond
+	// defaultInvokeFlushTimeout bounds flushes started on invoke events, they are not bounded by the invocation deadline
+	// since a slow endpoint should not hold the function
+	defaultInvokeFlushTimeout = 5 * time.Second
+)
+
+// FlushFunc sends telemetry events of the function which the extension runs with.
+type FlushFunc func(ctx context.Context, function *RegisterResponse, telemetryEvents []TelemetryEvent) error
+
+// Extension runs the forwarder as a Lambda external extension. Telemetry is buffered while the function runs,
+// and flushed in background on the next invoke event and before returning on shutdown.
+// Telemetry of a failed flush is put back to the buffer, so it is flushed again with the next one.
+type Extension struct {
+	name                string
+	client              *Client
+	listener            *TelemetryListener
+	listenAddress       string
+	destinationURI      string
+	shutdownDrainPeriod time.Duration
+	invokeFlushTimeout  time.Duration
+	flush               FlushFunc
+
+	// flushing is set while a background flush runs, so flushes of consecutive invocations do not overlap
+	flushing atomic.Bool
+	flushes  sync.WaitGroup
+}
+
+// NewExtension returns an extension which listens telemetry on the given address,
+// destination URI is the address Telemetry API posts to (i.e. http://sandbox.localdomain:4243).
+func NewExtension(name string, client *Client, listenAddress, destinationURI string, flush FlushFunc) *Extension {
+	return &Extension{
+		name:                name,
+		client:              client,
+		listener:            NewTelemetryListener(),
+		listenAddress:       listenAddress,
+		destinationURI:      destinationURI,
+		shutdownDrainPeriod: defaultShutdownDrainPeriod,
+		invokeFlushTimeout:  defaultInvokeFlushTimeout,
+		flush:               flush,
+	}
+}
+
+// Run blocks until shutdown event is received or an Extensions API call fails.
+func (e *Extension) Run(ctx context.Context) error {
+	function, err := e.client.Register(ctx, e.name)
+	if err != nil {
+		return err
+	}
+	log.Printf("Registered extension: %s for function: %s", e.name, function.FunctionName)
+
+	ln, err := net.Listen("tcp", e.listenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen telemetry on %s, err: %v", e.listenAddress, err)
+	}
+	server := &http.Server{Handler: e.listener}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Telemetry listener stopped, err: %v", err)
+		}
+	}()
+	defer server.Close()
+
+	types := []string{TelemetryTypePlatform, TelemetryTypeFunction, TelemetryTypeExtension}
+	if err := e.client.SubscribeTelemetry(ctx, e.destinationURI, types); err != nil {
+		return err
+	}
+
+	for {
+		event, err := e.client.NextEvent(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch event.EventType {
+		case Invoke:
+			// telemetry of previous invocations is flushed, current invocation's telemetry arrives while it runs.
+			// Invocation is not complete until next event is requested, so flush does not block it
+			e.flushInBackground(ctx, function)
+		case Shutdown:
+			log.Printf("Received shutdown event, reason: %s", event.ShutdownReason)
+			time.Sleep(e.shutdownDrainPeriod)
+			// telemetry of a failed background flush is back in the buffer once it returns
+			e.flushes.Wait()
+			shutdownCtx, cancel := context.WithDeadline(ctx, time.UnixMilli(event.DeadlineMs))
+			e.flushBuffered(shutdownCtx, function)
+			cancel()
+			return nil
+		default:
+			log.Printf("Received unknown event type: %s", event.EventType)
+		}
+	}
+}
+
+// flushInBackground flushes buffered telemetry with invoke flush timeout unless a flush is already running,
+// telemetry stays in the buffer for the next flush in that case.
+func (e *Extension) flushInBackground(ctx context.Context, function *RegisterResponse) {
+	if !e.flushing.CompareAndSwap(false, true) {
+		return
+	}
+	e.flushes.Add(1)
+	go func() {
+		defer e.flushes.Done()
+		defer e.flushing.Store(false)
+		flushCtx, cancel := context.WithTimeout(ctx, e.invokeFlushTimeout)
+		defer cancel()
+		e.flushBuffered(flushCtx, function)
+	}()
+}
+
+func (e *Extension) flushBuffered(ctx context.Context, function *RegisterResponse) {
+	telemetryEvents := e.listener.Flush()
+	if len(telemetryEvents) == 0 {
+		return
+	}
+	if err := e.flush(ctx, function, telemetryEvents); err != nil {
+		log.Printf("Failed to flush %d telemetry events, they are kept for the next flush, err: %v", len(telemetryEvents), err)
+		e.listener.Requeue(telemetryEvents)
+	}
+}
@@ -0,0 +1,202 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/extension/extensiontest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func getFreeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestExtension(t *testing.T) {
+	api := extensiontest.NewServer("my-function", "123456789012", "us-west-2")
+	defer api.Close()
+
+	var lock sync.Mutex
+	var flushes [][]string
+	flushed := make(chan struct{}, 2)
+	flush := func(ctx context.Context, function *RegisterResponse, telemetryEvents []TelemetryEvent) error {
+		if function.FunctionName != "my-function" || function.AccountID != "123456789012" {
+			return fmt.Errorf("unexpected function: %+v", function)
+		}
+		var messages []string
+		if _, ok := ctx.Deadline(); !ok {
+			messages = append(messages, "flush is not bounded")
+		}
+		for _, logEvents := range GetLogEventsByType(telemetryEvents) {
+			for _, e := range logEvents {
+				messages = append(messages, e.Message)
+			}
+		}
+		lock.Lock()
+		flushes = append(flushes, messages)
+		lock.Unlock()
+		flushed <- struct{}{}
+		return nil
+	}
+
+	address := getFreeAddress(t)
+	ext := NewExtension("edgedelta-forwarder", NewClient(api.RuntimeAPI()), address, "http://"+address, flush)
+	ext.shutdownDrainPeriod = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// extension runs without deadline like it does in lambda, so flushes are only bounded by their own timeouts
+	runCtx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
+	done := make(chan error)
+	go func() {
+		done <- ext.Run(runCtx)
+	}()
+
+	if err := api.WaitSubscribed(ctx); err != nil {
+		t.Fatalf("Extension did not subscribe to telemetry: %v", err)
+	}
+
+	now := time.Now()
+	err := api.SendTelemetry(ctx, extensiontest.TelemetryEvent{Time: now, Type: "function", Record: "first invocation log\n"})
+	if err != nil {
+		t.Fatalf("Failed to send telemetry: %v", err)
+	}
+	api.Invoke("request-2")
+	<-flushed
+
+	err = api.SendTelemetry(ctx, extensiontest.TelemetryEvent{Time: now, Type: "function", Record: map[string]string{"level": "INFO", "message": "json log"}})
+	if err != nil {
+		t.Fatalf("Failed to send telemetry: %v", err)
+	}
+	api.Shutdown("spindown")
+
+	if err := <-done; err != nil {
+		t.Fatalf("Extension failed: %v", err)
+	}
+
+	want := [][]string{
+		{"first invocation log"},
+		{`{"level":"INFO","message":"json log"}`},
+	}
+	if diff := cmp.Diff(want, flushes); diff != "" {
+		t.Errorf("Flushed messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExtensionFlushFailure(t *testing.T) {
+	api := extensiontest.NewServer("my-function", "123456789012", "us-west-2")
+	defer api.Close()
+
+	var lock sync.Mutex
+	var flushes [][]string
+	flushStarted := make(chan struct{}, 2)
+	flush := func(ctx context.Context, function *RegisterResponse, telemetryEvents []TelemetryEvent) error {
+		var messages []string
+		for _, logEvents := range GetLogEventsByType(telemetryEvents) {
+			for _, e := range logEvents {
+				messages = append(messages, e.Message)
+			}
+		}
+		lock.Lock()
+		flushes = append(flushes, messages)
+		first := len(flushes) == 1
+		lock.Unlock()
+		if !first {
+			return nil
+		}
+		// pusher retries an unreachable endpoint until context is done
+		flushStarted <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	address := getFreeAddress(t)
+	ext := NewExtension("edgedelta-forwarder", NewClient(api.RuntimeAPI()), address, "http://"+address, flush)
+	ext.shutdownDrainPeriod = 10 * time.Millisecond
+	ext.invokeFlushTimeout = 200 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	runCtx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
+	done := make(chan error)
+	go func() {
+		done <- ext.Run(runCtx)
+	}()
+
+	if err := api.WaitSubscribed(ctx); err != nil {
+		t.Fatalf("Extension did not subscribe to telemetry: %v", err)
+	}
+	if err := api.WaitNext(ctx); err != nil {
+		t.Fatalf("Extension did not request next event: %v", err)
+	}
+
+	err := api.SendTelemetry(ctx, extensiontest.TelemetryEvent{Time: time.Now(), Type: "function", Record: "first invocation log"})
+	if err != nil {
+		t.Fatalf("Failed to send telemetry: %v", err)
+	}
+	api.Invoke("request-2")
+	<-flushStarted
+	// invocation completes while flush is still failing
+	nextCtx, nextCancel := context.WithTimeout(ctx, ext.invokeFlushTimeout/2)
+	defer nextCancel()
+	if err := api.WaitNext(nextCtx); err != nil {
+		t.Fatalf("Extension did not request next event while flushing: %v", err)
+	}
+
+	err = api.SendTelemetry(ctx, extensiontest.TelemetryEvent{Time: time.Now(), Type: "function", Record: "second invocation log"})
+	if err != nil {
+		t.Fatalf("Failed to send telemetry: %v", err)
+	}
+	api.Shutdown("spindown")
+	if err := <-done; err != nil {
+		t.Fatalf("Extension failed: %v", err)
+	}
+
+	// events of the failed flush are flushed again on shutdown
+	want := [][]string{
+		{"first invocation log"},
+		{"first invocation log", "second invocation log"},
+	}
+	if diff := cmp.Diff(want, flushes); diff != "" {
+		t.Errorf("Flushed messages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetLogEventsByType(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	telemetryEvents := []TelemetryEvent{
+		{Time: ts, Type: "function", Record: []byte(`"function log\n"`)},
+		{Time: ts, Type: "extension", Record: []byte(`"extension log"`)},
+		{Time: ts, Type: "platform.start", Record: []byte(`{"requestId":"abc"}`)},
+	}
+
+	got := GetLogEventsByType(telemetryEvents)
+	wantMessages := map[string]string{
+		TelemetryTypeFunction:  "function log",
+		TelemetryTypeExtension: "extension log",
+		TelemetryTypePlatform:  `{"time":"2024-01-01T00:00:00Z","type":"platform.start","record":{"requestId":"abc"}}`,
+	}
+	for telemetryType, message := range wantMessages {
+		logEvents := got[telemetryType]
+		if len(logEvents) != 1 {
+			t.Fatalf("Expected 1 log event for %s, got %d", telemetryType, len(logEvents))
+		}
+		if logEvents[0].Message != message {
+			t.Errorf("Expected message %q for %s, got %q", message, telemetryType, logEvents[0].Message)
+		}
+		if logEvents[0].Timestamp != ts.UnixMilli() {
+			t.Errorf("Expected timestamp %d for %s, got %d", ts.UnixMilli(), telemetryType, logEvents[0].Timestamp)
+		}
+	}
+}
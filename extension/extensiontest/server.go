@@ -0,0 +1,203 @@
+// Package extensiontest provides a local stand-in for Lambda Extensions API and Telemetry API,
+// so the extension can be run and tested without AWS.
+package extensiontest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	extensionID = "extensiontest-extension-id"
+)
+
+type nextEvent struct {
+	EventType          string `json:"eventType"`
+	DeadlineMs         int64  `json:"deadlineMs"`
+	RequestID          string `json:"requestId,omitempty"`
+	InvokedFunctionARN string `json:"invokedFunctionArn,omitempty"`
+	ShutdownReason     string `json:"shutdownReason,omitempty"`
+}
+
+type TelemetryEvent struct {
+	Time   time.Time   `json:"time"`
+	Type   string      `json:"type"`
+	Record interface{} `json:"record"`
+}
+
+type Server struct {
+	*httptest.Server
+	FunctionName string
+	AccountID    string
+	Region       string
+
+	events     chan nextEvent
+	subscribed chan struct{}
+	// nextRequests receives a value each time extension requests the next event
+	nextRequests chan struct{}
+	lock         sync.Mutex
+	// destination is the telemetry listener URI of the subscribed extension
+	destination string
+}
+
+func NewServer(functionName, accountID, region string) *Server {
+	s := &Server{
+		FunctionName: functionName,
+		AccountID:    accountID,
+		Region:       region,
+		events:       make(chan nextEvent, 16),
+		subscribed:   make(chan struct{}),
+		nextRequests: make(chan struct{}, 16),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/2020-01-01/extension/register", s.handleRegister)
+	mux.HandleFunc("/2020-01-01/extension/event/next", s.handleNextEvent)
+	mux.HandleFunc("/2022-07-01/telemetry", s.handleSubscribe)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// RuntimeAPI returns the value Lambda sets to AWS_LAMBDA_RUNTIME_API.
+func (s *Server) RuntimeAPI() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// FunctionARN returns the ARN of the function the fake extension runs with.
+func (s *Server) FunctionARN() string {
+	return fmt.Sprintf("arn:aws:lambda:%s:%s:function:%s", s.Region, s.AccountID, s.FunctionName)
+}
+
+// WaitSubscribed blocks until extension subscribes to telemetry.
+func (s *Server) WaitSubscribed(ctx context.Context) error {
+	select {
+	case <-s.subscribed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitNext blocks until extension requests the next event, which completes the previous invocation.
+func (s *Server) WaitNext(ctx context.Context) error {
+	select {
+	case <-s.nextRequests:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Invoke sends an invoke event to the extension.
+func (s *Server) Invoke(requestID string) {
+	s.events <- nextEvent{
+		EventType:          "INVOKE",
+		DeadlineMs:         time.Now().Add(time.Minute).UnixMilli(),
+		RequestID:          requestID,
+		InvokedFunctionARN: s.FunctionARN(),
+	}
+}
+
+// Shutdown sends a shutdown event to the extension.
+func (s *Server) Shutdown(reason string) {
+	s.events <- nextEvent{
+		EventType:      "SHUTDOWN",
+		DeadlineMs:     time.Now().Add(2 * time.Second).UnixMilli(),
+		ShutdownReason: reason,
+	}
+}
+
+// SendTelemetry posts the events to the telemetry listener of the subscribed extension.
+func (s *Server) SendTelemetry(ctx context.Context, events ...TelemetryEvent) error {
+	s.lock.Lock()
+	destination := s.destination
+	s.lock.Unlock()
+	if destination == "" {
+		return fmt.Errorf("extension is not subscribed to telemetry")
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, destination, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telemetry listener returned status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Lambda-Extension-Name") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Lambda-Extension-Identifier", extensionID)
+	writeJSON(w, map[string]string{
+		"functionName":    s.FunctionName,
+		"functionVersion": "$LATEST",
+		"handler":         "bootstrap",
+		"accountId":       s.AccountID,
+	})
+}
+
+func (s *Server) handleNextEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Lambda-Extension-Identifier") != extensionID {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	select {
+	case s.nextRequests <- struct{}{}:
+	default:
+	}
+	select {
+	case e := <-s.events:
+		writeJSON(w, e)
+	case <-r.Context().Done():
+	}
+}
+
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut || r.Header.Get("Lambda-Extension-Identifier") != extensionID {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var subscription struct {
+		Destination struct {
+			URI string `json:"URI"`
+		} `json:"destination"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil || subscription.Destination.URI == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	first := s.destination == ""
+	s.destination = subscription.Destination.URI
+	s.lock.Unlock()
+	if first {
+		close(s.subscribed)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,103 @@
+package extension
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// maxBufferedEvents limits memory used by telemetry received between flushes, oldest events are dropped beyond it
+	maxBufferedEvents = 100000
+)
+
+// TelemetryEvent is a single event posted by Telemetry API, record is a string for function and extension logs
+// (or an object if function uses JSON log format) and an object for platform events.
+type TelemetryEvent struct {
+	Time   time.Time       `json:"time"`
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+}
+
+// TelemetryListener buffers events posted by Telemetry API until they are flushed.
+type TelemetryListener struct {
+	lock   sync.Mutex
+	events []TelemetryEvent
+}
+
+func NewTelemetryListener() *TelemetryListener {
+	return &TelemetryListener{}
+}
+
+func (l *TelemetryListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var batch []TelemetryEvent
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		log.Printf("Failed to decode telemetry events, err: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	l.lock.Lock()
+	l.events = append(l.events, batch...)
+	l.dropOldest()
+	l.lock.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// Requeue puts events of a failed flush back before the events received since then, so they are flushed again in order.
+func (l *TelemetryListener) Requeue(telemetryEvents []TelemetryEvent) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.events = append(telemetryEvents, l.events...)
+	l.dropOldest()
+}
+
+func (l *TelemetryListener) dropOldest() {
+	if dropped := len(l.events) - maxBufferedEvents; dropped > 0 {
+		log.Printf("Telemetry buffer is full, dropping %d oldest events", dropped)
+		l.events = l.events[dropped:]
+	}
+}
+
+// Flush returns buffered events and resets the buffer.
+func (l *TelemetryListener) Flush() []TelemetryEvent {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	flushed := l.events
+	l.events = nil
+	return flushed
+}
+
+// GetLogEventsByType converts telemetry events to log events grouped by telemetry type
+// (function, extension or platform), so each group can be sent with its own common fields.
+func GetLogEventsByType(telemetryEvents []TelemetryEvent) map[string][]events.CloudwatchLogsLogEvent {
+	logEvents := make(map[string][]events.CloudwatchLogsLogEvent)
+	for _, e := range telemetryEvents {
+		telemetryType := e.Type
+		var message string
+		if strings.HasPrefix(e.Type, TelemetryTypePlatform+".") {
+			// platform events are sent as is since record alone does not contain the event type
+			telemetryType = TelemetryTypePlatform
+			b, err := json.Marshal(e)
+			if err != nil {
+				log.Printf("Failed to marshal telemetry event of type: %s, err: %v", e.Type, err)
+				continue
+			}
+			message = string(b)
+		} else if err := json.Unmarshal(e.Record, &message); err != nil {
+			// record is an object when JSON log format is used
+			message = string(e.Record)
+		}
+
+		logEvents[telemetryType] = append(logEvents[telemetryType], events.CloudwatchLogsLogEvent{
+			Timestamp: e.Time.UnixMilli(),
+			Message:   strings.TrimSuffix(message, "\n"),
+		})
+	}
+	return logEvents
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/firehose"
+	"github.com/edgedelta/edgedelta-forwarder/metricstream"
+	"github.com/edgedelta/edgedelta-forwarder/utils"
+)
+
+// firehosePush has the logs, metrics or control message of a transformed record to push to ED_ENDPOINT.
+type firehosePush struct {
+	logs           []enrich.LogBatch
+	metrics        []metricstream.Batch
+	controlMessage *events.CloudwatchLogsData
+}
+
+// handleFirehoseRequest transforms CloudWatch Logs subscription records of a Firehose delivery stream into enriched logs,
+// so Firehose keeps buffering and backup while destination receives the same payload ED_ENDPOINT would receive.
+// If metric stream format is set, records are CloudWatch metric stream metrics and transformed into enriched metrics instead.
+func handleFirehoseRequest(ctx context.Context, firehoseEvent events.KinesisFirehoseEvent) (events.KinesisFirehoseResponse, error) {
+	transform := transformFirehoseRecord
+	if config.MetricStreamFormat != "" {
+		transform = transformFirehoseMetricRecord
+	}
+
+	records := firehoseEvent.Records
+	responseRecords := make([]events.KinesisFirehoseResponseRecord, len(records))
+	pushes := make([]firehosePush, len(records))
+	utils.ProcessInParallel(len(records), config.WorkerCount, func(i int) {
+		responseRecords[i] = transform(ctx, records[i], &pushes[i])
+	})
+
+	firehose.LimitResponse(records, responseRecords, firehose.MaxResponseSize)
+	if config.FirehosePushToEndpoint {
+		pushFirehoseRecords(ctx, responseRecords, pushes)
+	}
+	return events.KinesisFirehoseResponse{Records: responseRecords}, nil
+}
+
+// pushFirehoseRecords pushes transformed records to ED_ENDPOINT after the response is built. Push failures do not fail
+// the records, destination of the delivery stream still receives them, so pushes have their own deadline which leaves
+// time to return the response. Records which failed to be transformed are not pushed since Firehose retries them.
+func pushFirehoseRecords(ctx context.Context, responseRecords []events.KinesisFirehoseResponseRecord, pushes []firehosePush) {
+	pushCtx, cancel := firehose.WithPushDeadline(ctx, firehose.MaxPushDuration, firehose.ResponseReserve)
+	defer cancel()
+
+	failed := 0
+	for i, p := range pushes {
+		if responseRecords[i].Result == events.KinesisFirehoseTransformedStateProcessingFailed {
+			continue
+		}
+		if pushCtx.Err() != nil {
+			failed++
+			continue
+		}
+		if err := pushFirehoseRecord(pushCtx, p); err != nil {
+			log.Printf("Failed to push Firehose record: %s, err: %v", responseRecords[i].RecordID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Printf("Failed to push %d of %d Firehose records", failed, len(pushes))
+	}
+}
+
+func pushFirehoseRecord(ctx context.Context, p firehosePush) error {
+	if p.controlMessage != nil {
+		return forwardControlMessage(ctx, p.controlMessage)
+	}
+	for _, b := range p.logs {
+		if err := forwarder.Forward(ctx, b.Common, b.LogEvents); err != nil {
+			return err
+		}
+	}
+	for _, b := range p.metrics {
+		if err := forwarder.ForwardMetrics(ctx, b.Common, b.Metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func transformFirehoseRecord(ctx context.Context, record events.KinesisFirehoseEventRecord, push *firehosePush) (response events.KinesisFirehoseResponseRecord) {
+	response = events.KinesisFirehoseResponseRecord{
+		RecordID: record.RecordID,
+		Result:   events.KinesisFirehoseTransformedStateProcessingFailed,
+		Data:     record.Data,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in transformFirehoseRecord, err: %v", r)
+		}
+	}()
+
+	data, err := cwlogs.Decode(record.Data)
+	if err != nil {
+		log.Printf("Failed to decode Firehose record: %s, err: %v", record.RecordID, err)
+		return response
+	}
+
+	if data.MessageType == cwlogs.ControlMessage {
+		push.controlMessage = data
+		response.Result = events.KinesisFirehoseTransformedStateDropped
+		response.Data = nil
+		return response
+	}
+
+	common := enricher.GetEDCommon(ctx, data.SubscriptionFilters, data.MessageType, data.LogGroup, data.LogStream, data.Owner)
+	// log events of each web ACL of WAF logs are transformed into their own line
+	groups := enricher.GroupByWebACL(ctx, common, data.LogEvents)
+	var transformed []byte
+	for _, g := range groups {
+		b, err := marshalFirehoseRecord(g.Common, g.LogEvents)
+		if err != nil {
+			log.Printf("Failed to transform Firehose record: %s, err: %v", record.RecordID, err)
+			return response
+		}
+		transformed = append(transformed, b...)
+	}
+
+	push.logs = groups
+	response.Result = events.KinesisFirehoseTransformedStateOk
+	response.Data = transformed
+	return response
+}
+
+func transformFirehoseMetricRecord(ctx context.Context, record events.KinesisFirehoseEventRecord, push *firehosePush) (response events.KinesisFirehoseResponseRecord) {
+	response = events.KinesisFirehoseResponseRecord{
+		RecordID: record.RecordID,
+		Result:   events.KinesisFirehoseTransformedStateProcessingFailed,
+		Data:     record.Data,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in transformFirehoseMetricRecord, err: %v", r)
+		}
+	}()
+
+	metrics, err := metricstream.Decode(record.Data, config.MetricStreamFormat)
+	if err != nil {
+		log.Printf("Failed to decode Firehose metric record: %s, err: %v", record.RecordID, err)
+		return response
+	}
+
+	var transformed []byte
+	batches := metricstream.Enrich(ctx, enricher, metrics)
+	for _, batch := range batches {
+		b, err := json.Marshal(&core.Metrics{
+			Common:     core.Common(*batch.Common),
+			MetricData: core.MetricData{Metrics: batch.Metrics},
+		})
+		if err != nil {
+			log.Printf("Failed to transform Firehose metric record: %s, err: %v", record.RecordID, err)
+			return response
+		}
+		transformed = append(append(transformed, b...), '\n')
+	}
+	push.metrics = batches
+
+	if len(transformed) == 0 {
+		response.Result = events.KinesisFirehoseTransformedStateDropped
+		response.Data = nil
+		return response
+	}
+
+	response.Result = events.KinesisFirehoseTransformedStateOk
+	response.Data = transformed
+	return response
+}
+
+// marshalFirehoseRecord returns newline delimited log so records can be split at the destination.
+func marshalFirehoseRecord(common *enrich.Common, logEvents []events.CloudwatchLogsLogEvent) ([]byte, error) {
+	b, err := json.Marshal(core.NewLog(core.Common(*common), logEvents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log, err: %v", err)
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,113 @@
+// Package firehosetest provides a fake Firehose client which delivers records to an HTTP endpoint
+// the way Firehose HTTP endpoint destinations do, so receivers can be tested locally.
+package firehosetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type request struct {
+	RequestID string   `json:"requestId"`
+	Timestamp int64    `json:"timestamp"`
+	Records   []record `json:"records"`
+}
+
+type record struct {
+	Data []byte `json:"data"`
+}
+
+type Response struct {
+	StatusCode   int
+	RequestID    string `json:"requestId"`
+	Timestamp    int64  `json:"timestamp"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+type Client struct {
+	Endpoint  string
+	AccessKey string
+	SourceARN string
+	// Gzip compresses request body as Firehose does when content encoding is enabled
+	Gzip       bool
+	HTTPClient *http.Client
+}
+
+// Send delivers the records in a single request and returns the decoded acknowledgement.
+func (c *Client) Send(ctx context.Context, records ...[]byte) (*Response, error) {
+	req := request{
+		RequestID: newRequestID(),
+		Timestamp: time.Now().UnixMilli(),
+	}
+	for _, r := range records {
+		req.Records = append(req.Records, record{Data: r})
+	}
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request, err: %v", err)
+	}
+
+	if c.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(b); err != nil {
+			return nil, fmt.Errorf("failed to compress request, err: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress request, err: %v", err)
+		}
+		b = buf.Bytes()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request, err: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Amz-Firehose-Protocol-Version", "1.0")
+	httpReq.Header.Set("X-Amz-Firehose-Request-Id", req.RequestID)
+	httpReq.Header.Set("X-Amz-Firehose-Access-Key", c.AccessKey)
+	httpReq.Header.Set("X-Amz-Firehose-Source-Arn", c.SourceARN)
+	if c.Gzip {
+		httpReq.Header.Set("Content-Encoding", "GZIP")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response, err: %v", err)
+	}
+
+	result := &Response{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %s, err: %v", body, err)
+	}
+	if result.RequestID != req.RequestID {
+		return result, fmt.Errorf("request ID mismatch, sent: %s received: %s", req.RequestID, result.RequestID)
+	}
+	return result, nil
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
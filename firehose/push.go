@@ -0,0 +1,24 @@
+package firehose
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// MaxPushDuration bounds pushing transformed records to Edge Delta endpoint besides the response,
+	// so an unreachable endpoint does not time out the transformation lambda
+	MaxPushDuration = 10 * time.Second
+	// ResponseReserve is the time left to return the response after pushing transformed records
+	ResponseReserve = time.Second
+)
+
+// WithPushDeadline returns the context to push transformed records with, it is done after max duration
+// or reserve before the deadline of the given context, whichever is earlier.
+func WithPushDeadline(ctx context.Context, maxDuration, reserve time.Duration) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(maxDuration)
+	if d, ok := ctx.Deadline(); ok && d.Add(-reserve).Before(deadline) {
+		deadline = d.Add(-reserve)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
@@ -0,0 +1,49 @@
+package firehose
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithPushDeadline(t *testing.T) {
+	tests := []struct {
+		desc          string
+		timeout       time.Duration
+		expectedAfter time.Duration
+	}{
+		{
+			desc:          "Lambda deadline is far",
+			timeout:       time.Minute,
+			expectedAfter: 10 * time.Second,
+		},
+		{
+			desc:          "Reserve before lambda deadline",
+			timeout:       5 * time.Second,
+			expectedAfter: 4 * time.Second,
+		},
+		{
+			desc:          "Lambda deadline is closer than reserve",
+			timeout:       500 * time.Millisecond,
+			expectedAfter: -500 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			now := time.Now()
+			pushCtx, pushCancel := WithPushDeadline(ctx, 10*time.Second, time.Second)
+			defer pushCancel()
+
+			deadline, ok := pushCtx.Deadline()
+			if !ok {
+				t.Fatalf("Expected push context to have a deadline")
+			}
+			if got := deadline.Sub(now); got < tc.expectedAfter-100*time.Millisecond || got > tc.expectedAfter+100*time.Millisecond {
+				t.Errorf("Expected deadline after %v, got %v", tc.expectedAfter, got)
+			}
+		})
+	}
+}
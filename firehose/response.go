@@ -0,0 +1,51 @@
+package firehose
+
+import (
+	"encoding/base64"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// MaxResponseSize is kept below lambda's 6MB response payload limit
+	MaxResponseSize = 6 * 1000 * 1000
+	// responseRecordOverhead is the approximate size of JSON keys and result of a response record
+	responseRecordOverhead = 128
+)
+
+// LimitResponse marks transformed records which do not fit into the response of a transformation lambda as failed,
+// so firehose delivers them to processing failed output instead of losing them. Failed records are responded with their
+// original data, so room for the original data of every record is reserved before transformed data is accepted.
+func LimitResponse(records []events.KinesisFirehoseEventRecord, responseRecords []events.KinesisFirehoseResponseRecord, maxSize int) {
+	responseSize := 0
+	for i := range responseRecords {
+		record := &responseRecords[i]
+		if record.Result == events.KinesisFirehoseTransformedStateOk {
+			responseSize += responseRecordSize(record.RecordID, records[i].Data)
+		} else {
+			responseSize += responseRecordSize(record.RecordID, record.Data)
+		}
+	}
+
+	for i := range responseRecords {
+		record := &responseRecords[i]
+		if record.Result != events.KinesisFirehoseTransformedStateOk {
+			continue
+		}
+		original := responseRecordSize(record.RecordID, records[i].Data)
+		transformed := responseRecordSize(record.RecordID, record.Data)
+		if responseSize-original+transformed > maxSize {
+			log.Printf("Firehose response size limit is exceeded, marking record: %s as failed", record.RecordID)
+			record.Result = events.KinesisFirehoseTransformedStateProcessingFailed
+			record.Data = records[i].Data
+			continue
+		}
+		responseSize += transformed - original
+	}
+}
+
+// responseRecordSize returns the size of a response record, data is base64 encoded in the response.
+func responseRecordSize(recordID string, data []byte) int {
+	return base64.StdEncoding.EncodedLen(len(data)) + len(recordID) + responseRecordOverhead
+}
@@ -0,0 +1,48 @@
+package firehose
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestLimitResponse(t *testing.T) {
+	var records []events.KinesisFirehoseEventRecord
+	var responseRecords []events.KinesisFirehoseResponseRecord
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("record-%d", i)
+		records = append(records, events.KinesisFirehoseEventRecord{RecordID: id, Data: bytes.Repeat([]byte("o"), 300)})
+		responseRecords = append(responseRecords, events.KinesisFirehoseResponseRecord{
+			RecordID: id,
+			Result:   events.KinesisFirehoseTransformedStateOk,
+			Data:     bytes.Repeat([]byte("t"), 600),
+		})
+	}
+	responseRecords[1] = events.KinesisFirehoseResponseRecord{RecordID: "record-1", Result: events.KinesisFirehoseTransformedStateDropped}
+
+	// originals take 3*(400+8+128)+(8+128) bytes, transformed records 800 bytes more than originals
+	maxSize := 3*(400+8+128) + (8 + 128) + 400
+	LimitResponse(records, responseRecords, maxSize)
+
+	wantResults := []string{
+		events.KinesisFirehoseTransformedStateOk,
+		events.KinesisFirehoseTransformedStateDropped,
+		events.KinesisFirehoseTransformedStateProcessingFailed,
+		events.KinesisFirehoseTransformedStateProcessingFailed,
+	}
+	size := 0
+	for i, r := range responseRecords {
+		if r.Result != wantResults[i] {
+			t.Errorf("Expected record %d to be %s, got %s", i, wantResults[i], r.Result)
+		}
+		if r.Result == events.KinesisFirehoseTransformedStateProcessingFailed && !bytes.Equal(r.Data, records[i].Data) {
+			t.Errorf("Expected failed record %d to have original data", i)
+		}
+		size += responseRecordSize(r.RecordID, r.Data)
+	}
+	if size > maxSize {
+		t.Errorf("Expected response size to be at most %d, got %d", maxSize, size)
+	}
+}
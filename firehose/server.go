@@ -0,0 +1,233 @@
+package firehose
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/metricstream"
+	"github.com/edgedelta/edgedelta-forwarder/utils"
+)
+
+const (
+	requestIDHeader = "X-Amz-Firehose-Request-Id"
+	accessKeyHeader = "X-Amz-Firehose-Access-Key"
+	sourceARNHeader = "X-Amz-Firehose-Source-Arn"
+	// Firehose buffers at most 64MB for HTTP endpoints, some room is left for base64 encoding
+	maxRequestBodySize = 100 * 1024 * 1024
+)
+
+// Request is the body of a Firehose HTTP endpoint delivery request.
+type Request struct {
+	RequestID string   `json:"requestId"`
+	Timestamp int64    `json:"timestamp"`
+	Records   []Record `json:"records"`
+}
+
+type Record struct {
+	Data []byte `json:"data"`
+}
+
+// Response is the body Firehose expects in return, error message is set only for failed requests.
+type Response struct {
+	RequestID    string `json:"requestId"`
+	Timestamp    int64  `json:"timestamp"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// Server implements Firehose HTTP endpoint delivery protocol, received records are enriched and forwarded.
+// Records containing CloudWatch Logs subscription data are enriched by their log group,
+// other records are forwarded line by line with the delivery stream as their source.
+// If metric stream format is set, records are decoded as CloudWatch metric stream metrics.
+type Server struct {
+	accessKey          string
+	workerCount        int
+	metricStreamFormat string
+	enricher           *enrich.Enricher
+	forwarder          *forward.Forwarder
+}
+
+func NewServer(conf *cfg.Config, enricher *enrich.Enricher, forwarder *forward.Forwarder) *Server {
+	return &Server{
+		accessKey:          conf.FirehoseAccessKey,
+		workerCount:        conf.WorkerCount,
+		metricStreamFormat: conf.MetricStreamFormat,
+		enricher:           enricher,
+		forwarder:          forwarder,
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Header.Get(requestIDHeader)
+	if r.Method != http.MethodPost {
+		s.respond(w, requestID, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	if s.accessKey == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(accessKeyHeader)), []byte(s.accessKey)) != 1 {
+		s.respond(w, requestID, http.StatusUnauthorized, fmt.Errorf("invalid access key"))
+		return
+	}
+
+	req, err := decodeRequest(r)
+	if err != nil {
+		s.respond(w, requestID, http.StatusBadRequest, err)
+		return
+	}
+	if req.RequestID != "" {
+		requestID = req.RequestID
+	}
+
+	if err := s.process(r, requestID, req); err != nil {
+		log.Printf("Failed to process Firehose request: %s, err: %v", requestID, err)
+		s.respond(w, requestID, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.respond(w, requestID, http.StatusOK, nil)
+}
+
+func decodeRequest(r *http.Request) (*Request, error) {
+	var body io.Reader = http.MaxBytesReader(nil, r.Body, maxRequestBodySize)
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader, err: %v", err)
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	var req Request
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode request body, err: %v", err)
+	}
+	return &req, nil
+}
+
+func (s *Server) process(r *http.Request, requestID string, req *Request) error {
+	ctx := r.Context()
+	if s.metricStreamFormat != "" {
+		return s.processMetrics(ctx, req)
+	}
+
+	timestamp := req.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().UnixMilli()
+	}
+
+	var logsData []*events.CloudwatchLogsData
+	var logEvents []events.CloudwatchLogsLogEvent
+	for _, record := range req.Records {
+		if data, ok := decodeCloudwatchLogsData(record.Data); ok {
+			logsData = append(logsData, data)
+			continue
+		}
+		for _, line := range strings.Split(string(record.Data), "\n") {
+			if line == "" {
+				continue
+			}
+			logEvents = append(logEvents, events.CloudwatchLogsLogEvent{Timestamp: timestamp, Message: line})
+		}
+	}
+
+	var errLock sync.Mutex
+	var firstErr error
+	utils.ProcessInParallel(len(logsData), s.workerCount, func(i int) {
+		if err := s.forwardCloudwatchLogsData(ctx, logsData[i]); err != nil {
+			errLock.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			errLock.Unlock()
+		}
+	})
+	if firstErr != nil {
+		return firstErr
+	}
+
+	if len(logEvents) == 0 {
+		return nil
+	}
+	common := s.enricher.GetFirehoseCommon(ctx, r.Header.Get(sourceARNHeader), requestID)
+	return s.forward(ctx, common, logEvents)
+}
+
+func (s *Server) processMetrics(ctx context.Context, req *Request) error {
+	var metrics []metricstream.Metric
+	for i, record := range req.Records {
+		m, err := metricstream.Decode(record.Data, s.metricStreamFormat)
+		if err != nil {
+			// retrying would not help, so record is skipped instead of failing the request
+			log.Printf("Failed to decode metric stream record %d, err: %v", i, err)
+			continue
+		}
+		metrics = append(metrics, m...)
+	}
+
+	for _, batch := range metricstream.Enrich(ctx, s.enricher, metrics) {
+		if err := s.forwarder.ForwardMetrics(ctx, batch.Common, batch.Metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forwardCloudwatchLogsData forwards control messages, which are sent by CloudWatch Logs to check if destination
+// is reachable, as health events and the rest as logs.
+func (s *Server) forwardCloudwatchLogsData(ctx context.Context, data *events.CloudwatchLogsData) error {
+	if data.MessageType == cwlogs.ControlMessage {
+		common := s.enricher.GetHealthCommon(ctx, data.MessageType, data.LogGroup, data.Owner, data.SubscriptionFilters)
+		return s.forwarder.ForwardHealth(ctx, common, cwlogs.GetHealthEvents(data))
+	}
+	common := s.enricher.GetEDCommon(ctx, data.SubscriptionFilters, data.MessageType, data.LogGroup, data.LogStream, data.Owner)
+	return s.forward(ctx, common, data.LogEvents)
+}
+
+// forward forwards log events of each web ACL with its own common fields, other logs are forwarded together.
+func (s *Server) forward(ctx context.Context, common *enrich.Common, logEvents []events.CloudwatchLogsLogEvent) error {
+	for _, b := range s.enricher.GroupByWebACL(ctx, common, logEvents) {
+		if err := s.forwarder.Forward(ctx, b.Common, b.LogEvents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeCloudwatchLogsData returns subscription data if record contains a CloudWatch Logs subscription payload.
+func decodeCloudwatchLogsData(data []byte) (*events.CloudwatchLogsData, bool) {
+	d, err := cwlogs.Decode(data)
+	if err != nil || (d.MessageType != cwlogs.DataMessage && d.MessageType != cwlogs.ControlMessage) {
+		return nil, false
+	}
+	return d, true
+}
+
+func (s *Server) respond(w http.ResponseWriter, requestID string, statusCode int, err error) {
+	resp := Response{
+		RequestID: requestID,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err != nil {
+		resp.ErrorMessage = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed to write Firehose response: %s, err: %v", requestID, err)
+	}
+}
@@ -0,0 +1,206 @@
+package firehose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/firehose/firehosetest"
+	"github.com/edgedelta/edgedelta-forwarder/forward/forwardtest"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+)
+
+const (
+	deliveryStreamARN = "arn:aws:firehose:us-west-2:123456789012:deliverystream/my-stream"
+	accessKey         = "my-access-key"
+	dataMessage       = `{"messageType":"DATA_MESSAGE","owner":"123456789012","logGroup":"/aws/lambda/my-function","logStream":"stream","subscriptionFilters":["filter"],"logEvents":[{"id":"1","timestamp":1704067200000,"message":"hello"}]}`
+	controlMessage    = `{"messageType":"CONTROL_MESSAGE","owner":"CloudwatchLogs","logGroup":"","logStream":"","subscriptionFilters":[],"logEvents":[{"id":"","timestamp":1704067200000,"message":"CWL CONTROL MESSAGE: Checking health of destination Firehose."}]}`
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatalf("Failed to write gzip content: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		desc           string
+		accessKey      string
+		gzip           bool
+		records        [][]byte
+		wantStatusCode int
+		wantMessages   []string
+		wantHealth     int
+		// noServerKey starts the server without an access key
+		noServerKey bool
+	}{
+		{
+			desc:           "CloudWatch logs and raw records",
+			accessKey:      accessKey,
+			gzip:           true,
+			records:        [][]byte{gzipBytes(t, []byte(dataMessage)), []byte("raw line 1\nraw line 2\n")},
+			wantStatusCode: http.StatusOK,
+			wantMessages:   []string{"hello", "raw line 1", "raw line 2"},
+		},
+		{
+			desc:           "Control message is forwarded as health event",
+			accessKey:      accessKey,
+			records:        [][]byte{gzipBytes(t, []byte(controlMessage))},
+			wantStatusCode: http.StatusOK,
+			wantHealth:     1,
+		},
+		{
+			desc:           "Invalid access key",
+			accessKey:      "wrong-key",
+			records:        [][]byte{[]byte("raw line")},
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			desc:           "Access key is not configured",
+			records:        [][]byte{[]byte("raw line")},
+			wantStatusCode: http.StatusUnauthorized,
+			noServerKey:    true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			ed := &forwardtest.Endpoint{}
+			edServer := httptest.NewServer(ed)
+			defer edServer.Close()
+
+			conf := &cfg.Config{
+				Region:            "us-west-2",
+				EDEndpoint:        edServer.URL,
+				BatchSize:         cfg.MaxChunkSize,
+				PushTimeout:       time.Second,
+				RetryInterval:     10 * time.Millisecond,
+				WorkerCount:       2,
+				ForwardSourceTags: true,
+				FirehoseAccessKey: accessKey,
+			}
+			if tc.noServerKey {
+				conf.FirehoseAccessKey = ""
+			}
+			enricher, forwarder := forwardtest.NewForwarder(conf, push.NewPusher(conf))
+			server := httptest.NewServer(NewServer(conf, enricher, forwarder))
+			defer server.Close()
+
+			client := &firehosetest.Client{
+				Endpoint:  server.URL,
+				AccessKey: tc.accessKey,
+				SourceARN: deliveryStreamARN,
+				Gzip:      tc.gzip,
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := client.Send(ctx, tc.records...)
+			if err != nil {
+				t.Fatalf("Failed to send records: %v", err)
+			}
+			if resp.StatusCode != tc.wantStatusCode {
+				t.Fatalf("Expected status code %d, got %d (error message: %s)", tc.wantStatusCode, resp.StatusCode, resp.ErrorMessage)
+			}
+
+			var gotMessages []string
+			for _, l := range ed.Logs() {
+				for _, e := range l.LogEvents {
+					gotMessages = append(gotMessages, e.Message)
+				}
+				if l.AwsCommon.LogGroup == "" && l.AwsCommon.Firehose == nil {
+					t.Errorf("Expected raw records to have firehose fields")
+				}
+			}
+			if len(ed.Health()) != tc.wantHealth {
+				t.Errorf("Expected %d health payloads, got %d", tc.wantHealth, len(ed.Health()))
+			}
+			for _, h := range ed.Health() {
+				if h.HealthEvents[0].Type != core.HealthEventSubscriptionEstablished {
+					t.Errorf("Expected health event type %s, got %s", core.HealthEventSubscriptionEstablished, h.HealthEvents[0].Type)
+				}
+			}
+			sort.Strings(gotMessages)
+			if len(gotMessages) != len(tc.wantMessages) {
+				t.Fatalf("Expected messages %v, got %v", tc.wantMessages, gotMessages)
+			}
+			for i := range gotMessages {
+				if gotMessages[i] != tc.wantMessages[i] {
+					t.Errorf("Expected message %q, got %q", tc.wantMessages[i], gotMessages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServerMetricStream(t *testing.T) {
+	ed := &forwardtest.Endpoint{}
+	edServer := httptest.NewServer(ed)
+	defer edServer.Close()
+
+	conf := &cfg.Config{
+		Region:             "us-west-2",
+		EDEndpoint:         edServer.URL,
+		BatchSize:          cfg.MaxChunkSize,
+		PushTimeout:        time.Second,
+		RetryInterval:      10 * time.Millisecond,
+		WorkerCount:        2,
+		ForwardSourceTags:  true,
+		MetricStreamFormat: cfg.MetricStreamFormatJSON,
+		FirehoseAccessKey:  accessKey,
+	}
+	enricher, forwarder := forwardtest.NewForwarder(conf, push.NewPusher(conf))
+	server := httptest.NewServer(NewServer(conf, enricher, forwarder))
+	defer server.Close()
+
+	records := [][]byte{
+		[]byte(`{"metric_stream_name":"my-stream","account_id":"123456789012","region":"us-east-1","namespace":"AWS/EC2","metric_name":"CPUUtilization","dimensions":{"InstanceId":"i-0123456789abcdef0"},"timestamp":1611929698000,"value":{"max":10.5,"min":1,"sum":20,"count":4},"unit":"Percent"}` + "\n" +
+			`{"metric_stream_name":"my-stream","account_id":"123456789012","region":"us-east-1","namespace":"AWS/SQS","metric_name":"NumberOfMessagesSent","dimensions":{"QueueName":"my-queue"},"timestamp":1611929698000,"value":{"max":1,"min":1,"sum":3,"count":3},"unit":"Count"}` + "\n"),
+		[]byte("invalid record"),
+	}
+	client := &firehosetest.Client{Endpoint: server.URL, AccessKey: accessKey, SourceARN: deliveryStreamARN}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.Send(ctx, records...)
+	if err != nil {
+		t.Fatalf("Failed to send records: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d (error message: %s)", http.StatusOK, resp.StatusCode, resp.ErrorMessage)
+	}
+
+	if len(ed.Logs()) != 0 {
+		t.Errorf("Expected no logs, got %d", len(ed.Logs()))
+	}
+	if len(ed.Metrics()) != 2 {
+		t.Fatalf("Expected metrics of 2 resources, got %d", len(ed.Metrics()))
+	}
+	wantResources := []string{
+		"arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0",
+		"arn:aws:sqs:us-east-1:123456789012:my-queue",
+	}
+	for i, m := range ed.Metrics() {
+		if m.Cloud.ResourceID != wantResources[i] {
+			t.Errorf("Expected resource %s, got %s", wantResources[i], m.Cloud.ResourceID)
+		}
+		if m.Cloud.Region != "us-east-1" || m.AwsCommon.MetricStream == nil || m.AwsCommon.MetricStream.Name != "my-stream" {
+			t.Errorf("Expected metric stream fields to be set, got %+v", m.AwsCommon.MetricStream)
+		}
+		if len(m.Metrics) != 1 {
+			t.Errorf("Expected 1 metric, got %d", len(m.Metrics))
+		}
+	}
+}
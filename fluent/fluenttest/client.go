@@ -0,0 +1,159 @@
+// Package fluenttest provides a Fluent Forward protocol client which sends records the way Fluent Bit and Fluentd do,
+// so receivers can be tested locally.
+package fluenttest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type Mode int
+
+const (
+	// ModeMessage sends each record in its own message
+	ModeMessage Mode = iota
+	// ModeForward sends records as an array of entries
+	ModeForward
+	// ModePackedForward sends records as a msgpack stream of entries in a binary
+	ModePackedForward
+	// ModeCompressedPackedForward is the same as ModePackedForward with gzip compressed entries
+	ModeCompressedPackedForward
+)
+
+// eventTime encodes time as event time extension, it is not registered so decoding of receivers is not affected.
+type eventTime time.Time
+
+func (t eventTime) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeExtHeader(0, 8); err != nil {
+		return err
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(time.Time(t).Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(time.Time(t).Nanosecond()))
+	_, err := enc.Writer().Write(b)
+	return err
+}
+
+type Client struct {
+	Addr string
+	Mode Mode
+	// RequireAck sets chunk option and waits for the acknowledgement of each message
+	RequireAck bool
+	// Timeout of sending the records and receiving acknowledgements
+	Timeout time.Duration
+
+	conn net.Conn
+	dec  *msgpack.Decoder
+}
+
+// Send sends the records with the given tag and time, connection is opened on the first send and kept open.
+func (c *Client) Send(tag string, t time.Time, records ...map[string]any) error {
+	if c.conn == nil {
+		conn, err := net.Dial("tcp", c.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s, err: %v", c.Addr, err)
+		}
+		c.conn = conn
+		c.dec = msgpack.NewDecoder(conn)
+	}
+	if c.Timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+
+	messages, chunks, err := c.newMessages(tag, eventTime(t), records)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for _, m := range messages {
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("failed to encode message, err: %v", err)
+		}
+	}
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to send messages, err: %v", err)
+	}
+
+	for _, chunk := range chunks {
+		var ack map[string]string
+		if err := c.dec.Decode(&ack); err != nil {
+			return fmt.Errorf("failed to receive acknowledgement of chunk %s, err: %v", chunk, err)
+		}
+		if ack["ack"] != chunk {
+			return fmt.Errorf("expected acknowledgement of chunk %s, got %s", chunk, ack["ack"])
+		}
+	}
+	return nil
+}
+
+func (c *Client) newMessages(tag string, t eventTime, records []map[string]any) ([][]any, []string, error) {
+	var chunks []string
+	newOption := func(option map[string]any) map[string]any {
+		if c.RequireAck {
+			chunk := newChunkID()
+			chunks = append(chunks, chunk)
+			option["chunk"] = chunk
+		}
+		return option
+	}
+
+	switch c.Mode {
+	case ModeMessage:
+		var messages [][]any
+		for _, r := range records {
+			messages = append(messages, []any{tag, t, r, newOption(map[string]any{})})
+		}
+		return messages, chunks, nil
+	case ModeForward:
+		var entries []any
+		for _, r := range records {
+			entries = append(entries, []any{t, r})
+		}
+		return [][]any{{tag, entries, newOption(map[string]any{"size": len(records)})}}, chunks, nil
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode([]any{t, r}); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode entry, err: %v", err)
+		}
+	}
+	option := map[string]any{"size": len(records)}
+	entries := buf.Bytes()
+	if c.Mode == ModeCompressedPackedForward {
+		var zbuf bytes.Buffer
+		zw := gzip.NewWriter(&zbuf)
+		if _, err := zw.Write(entries); err != nil {
+			return nil, nil, fmt.Errorf("failed to compress entries, err: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, nil, fmt.Errorf("failed to compress entries, err: %v", err)
+		}
+		entries = zbuf.Bytes()
+		option["compressed"] = "gzip"
+	}
+	return [][]any{{tag, entries, newOption(option)}}, chunks, nil
+}
+
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func newChunkID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
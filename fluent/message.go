@@ -0,0 +1,262 @@
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	eventTimeExtID   = 0
+	eventTimeLength  = 8
+	optionChunk      = "chunk"
+	optionCompressed = "compressed"
+	compressionGzip  = "gzip"
+	tagAttribute     = "fluent.tag"
+	// maxDecompressedSize limits memory used by a compressed packed forward message
+	maxDecompressedSize = 64 * 1024 * 1024
+)
+
+var (
+	// messageKeys are the record keys used as log message, i.e. "log" of tail and docker inputs of Fluent Bit
+	messageKeys = []string{"log", "message"}
+)
+
+func init() {
+	msgpack.RegisterExt(eventTimeExtID, (*EventTime)(nil))
+}
+
+// EventTime is the nanosecond precision time extension of Fluent Forward protocol.
+type EventTime struct {
+	time.Time
+}
+
+func (t *EventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, eventTimeLength)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b, nil
+}
+
+func (t *EventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != eventTimeLength {
+		return fmt.Errorf("invalid event time length: %d", len(b))
+	}
+	t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:])))
+	return nil
+}
+
+type entry struct {
+	time   time.Time
+	record map[string]any
+}
+
+// message is a decoded Fluent Forward message, chunk is set if the client requires an acknowledgement.
+type message struct {
+	tag     string
+	entries []entry
+	chunk   string
+}
+
+// decodeMessage decodes the next message in Message, Forward, PackedForward or CompressedPackedForward mode.
+func decodeMessage(dec *msgpack.Decoder) (*message, error) {
+	arr, err := dec.DecodeSlice()
+	if err != nil {
+		return nil, err
+	}
+	if len(arr) < 2 {
+		return nil, fmt.Errorf("message has %d elements, at least 2 are expected", len(arr))
+	}
+	tag, ok := arr[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("message tag is %T, string is expected", arr[0])
+	}
+
+	msg := &message{tag: tag}
+	var option any
+	switch v := arr[1].(type) {
+	case []any:
+		// Forward mode: [tag, [[time, record], ...], option]
+		for _, e := range v {
+			ent, err := decodeEntry(e)
+			if err != nil {
+				return nil, err
+			}
+			msg.entries = append(msg.entries, ent)
+		}
+		option = optionAt(arr, 2)
+	case string, []byte:
+		// PackedForward mode: [tag, msgpack stream of [time, record], option]
+		option = optionAt(arr, 2)
+		compression, _ := optionValue(option, optionCompressed).(string)
+		if msg.entries, err = decodePackedEntries(toBytes(v), compression); err != nil {
+			return nil, err
+		}
+	default:
+		// Message mode: [tag, time, record, option]
+		if len(arr) < 3 {
+			return nil, errors.New("message mode requires time and record")
+		}
+		ent, err := decodeEntry([]any{arr[1], arr[2]})
+		if err != nil {
+			return nil, err
+		}
+		msg.entries = []entry{ent}
+		option = optionAt(arr, 3)
+	}
+
+	msg.chunk, _ = optionValue(option, optionChunk).(string)
+	return msg, nil
+}
+
+func decodePackedEntries(b []byte, compression string) ([]entry, error) {
+	var r io.Reader = bytes.NewReader(b)
+	switch compression {
+	case "":
+	case compressionGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader, err: %v", err)
+		}
+		defer zr.Close()
+		r = io.LimitReader(zr, maxDecompressedSize)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", compression)
+	}
+
+	dec := msgpack.NewDecoder(r)
+	dec.UseLooseInterfaceDecoding(true)
+	var entries []entry
+	for {
+		v, err := dec.DecodeInterfaceLoose()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode packed entry, err: %v", err)
+		}
+		e, err := decodeEntry(v)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+func decodeEntry(v any) (entry, error) {
+	arr, ok := v.([]any)
+	if !ok || len(arr) < 2 {
+		return entry{}, errors.New("entry is not a [time, record] array")
+	}
+	t, err := decodeTime(arr[0])
+	if err != nil {
+		return entry{}, err
+	}
+	record, ok := normalize(arr[1]).(map[string]any)
+	if !ok {
+		return entry{}, fmt.Errorf("record is %T, map is expected", arr[1])
+	}
+	return entry{time: t, record: record}, nil
+}
+
+// decodeTime decodes event time extension or epoch seconds which older clients send.
+func decodeTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case *EventTime:
+		return t.Time, nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		return time.UnixMilli(int64(t * 1000)), nil
+	}
+	return time.Time{}, fmt.Errorf("event time is %T, integer or event time is expected", v)
+}
+
+// normalize converts binary values to strings and map keys to strings, so records can be marshalled as JSON.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case []any:
+		for i := range t {
+			t[i] = normalize(t[i])
+		}
+		return t
+	case map[string]any:
+		for k, val := range t {
+			t[k] = normalize(val)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(normalize(k))] = normalize(val)
+		}
+		return m
+	}
+	return v
+}
+
+func optionAt(arr []any, i int) any {
+	if len(arr) <= i {
+		return nil
+	}
+	return normalize(arr[i])
+}
+
+func optionValue(option any, key string) any {
+	m, ok := option.(map[string]any)
+	if !ok {
+		return nil
+	}
+	return m[key]
+}
+
+func toBytes(v any) []byte {
+	if s, ok := v.(string); ok {
+		return []byte(s)
+	}
+	return v.([]byte)
+}
+
+// newLogEvent uses "log" or "message" field of the record as the message and other fields as attributes,
+// records without them are sent as JSON.
+func newLogEvent(tag string, e entry) (core.LogEvent, error) {
+	event := core.LogEvent{
+		CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{Timestamp: e.time.UnixMilli()},
+		Attributes:             map[string]any{tagAttribute: tag},
+	}
+	messageKey := ""
+	for _, key := range messageKeys {
+		if s, ok := e.record[key].(string); ok {
+			event.Message, messageKey = s, key
+			break
+		}
+	}
+	if messageKey == "" {
+		b, err := json.Marshal(e.record)
+		if err != nil {
+			return core.LogEvent{}, fmt.Errorf("failed to marshal record, err: %v", err)
+		}
+		event.Message = string(b)
+		return event, nil
+	}
+
+	for k, v := range e.record {
+		if k != messageKey {
+			event.Attributes[k] = v
+		}
+	}
+	return event, nil
+}
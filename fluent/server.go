@@ -0,0 +1,227 @@
+package fluent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/imds"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	readBufferSize = 64 * 1024 // 64KB
+)
+
+var (
+	ErrServerClosed = errors.New("fluent: server closed")
+)
+
+// Server implements Fluent Forward protocol over TCP, so Fluent Bit and Fluentd can use the forwarder as an aggregator.
+// Records are enriched with the host the forwarder runs on and pushed in batches,
+// acknowledgements are sent only after the records of a chunk are pushed.
+type Server struct {
+	addr      string
+	batchSize int
+	enricher  *enrich.Enricher
+	forwarder *forward.Forwarder
+	identity  *imds.InstanceIdentity
+	hostName  string
+
+	// ctx is cancelled when shutdown times out, so pending pushes are stopped
+	ctx      context.Context
+	cancel   context.CancelFunc
+	lock     sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewServer(conf *cfg.Config, enricher *enrich.Enricher, forwarder *forward.Forwarder, identity *imds.InstanceIdentity, hostName string) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		addr:      conf.FluentListenAddress,
+		batchSize: conf.BatchSize,
+		enricher:  enricher,
+		forwarder: forwarder,
+		identity:  identity,
+		hostName:  hostName,
+		ctx:       ctx,
+		cancel:    cancel,
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections until the server is shut down, it always returns a non-nil error.
+func (s *Server) Serve(l net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.lock.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.lock.Unlock()
+		go s.handleConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections and stops reading from open connections,
+// records that are already read are pushed and acknowledged before their connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		// unblocks the pending read, connection handler flushes its batch and returns
+		conn.SetReadDeadline(time.Now())
+	}
+	s.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		s.wg.Done()
+	}()
+
+	br := bufio.NewReaderSize(conn, readBufferSize)
+	dec := msgpack.NewDecoder(br)
+	dec.UseLooseInterfaceDecoding(true)
+	b := &batch{server: s, conn: conn}
+	for {
+		msg, err := decodeMessage(dec)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.ErrUnexpectedEOF) && !isTimeout(err) {
+				log.Printf("Failed to decode Fluent Forward message from %s, err: %v", conn.RemoteAddr(), err)
+			}
+			if err := b.flush(); err != nil {
+				log.Printf("Failed to forward Fluent Forward records from %s, err: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		if err := b.add(msg); err != nil {
+			log.Printf("Failed to read Fluent Forward message from %s, err: %v", conn.RemoteAddr(), err)
+			return
+		}
+		// messages already received are batched together, so clients sending a record per message are not pushed one by one
+		if br.Buffered() > 0 && b.size < s.batchSize {
+			continue
+		}
+		if err := b.flush(); err != nil {
+			// chunks are not acknowledged, client sends them again on a new connection
+			log.Printf("Failed to forward Fluent Forward records from %s, err: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// batch keeps the records and acknowledgement chunks of a connection until they are pushed.
+type batch struct {
+	server    *Server
+	conn      net.Conn
+	logEvents []core.LogEvent
+	chunks    []string
+	size      int
+}
+
+func (b *batch) add(msg *message) error {
+	for _, e := range msg.entries {
+		event, err := newLogEvent(msg.tag, e)
+		if err != nil {
+			return err
+		}
+		b.logEvents = append(b.logEvents, event)
+		b.size += len(event.Message)
+	}
+	if msg.chunk != "" {
+		b.chunks = append(b.chunks, msg.chunk)
+	}
+	return nil
+}
+
+func (b *batch) flush() error {
+	s := b.server
+	if len(b.logEvents) > 0 {
+		common := s.enricher.GetHostCommon(s.ctx, s.identity.AccountID, s.identity.Region, s.identity.InstanceID, s.hostName)
+		if err := s.forwarder.ForwardEvents(s.ctx, common, b.logEvents); err != nil {
+			return err
+		}
+	}
+	for _, chunk := range b.chunks {
+		ack, err := msgpack.Marshal(map[string]string{"ack": chunk})
+		if err != nil {
+			return err
+		}
+		if _, err := b.conn.Write(ack); err != nil {
+			return err
+		}
+	}
+	// chunks are already marshalled, batch can be reused
+	b.logEvents, b.chunks, b.size = b.logEvents[:0], b.chunks[:0], 0
+	return nil
+}
@@ -0,0 +1,157 @@
+package fluent
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/fluent/fluenttest"
+	"github.com/edgedelta/edgedelta-forwarder/forward/forwardtest"
+	"github.com/edgedelta/edgedelta-forwarder/imds"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestServer(t *testing.T) {
+	eventTime := time.Date(2024, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	records := []map[string]any{
+		{"log": "hello", "container_name": "app", "kubernetes": map[string]any{"pod_name": "app-1"}},
+		{"message": "world"},
+		{"level": "info", "count": 3},
+	}
+	wantEvents := []core.LogEvent{
+		{Attributes: map[string]any{"fluent.tag": "app.logs", "container_name": "app", "kubernetes": map[string]any{"pod_name": "app-1"}}},
+		{Attributes: map[string]any{"fluent.tag": "app.logs"}},
+		{Attributes: map[string]any{"fluent.tag": "app.logs"}},
+	}
+	wantEvents[0].Message, wantEvents[1].Message, wantEvents[2].Message = "hello", "world", `{"count":3,"level":"info"}`
+	for i := range wantEvents {
+		wantEvents[i].Timestamp = eventTime.UnixMilli()
+	}
+
+	tests := []struct {
+		desc       string
+		mode       fluenttest.Mode
+		requireAck bool
+	}{
+		{desc: "Message mode", mode: fluenttest.ModeMessage},
+		{desc: "Message mode with ack", mode: fluenttest.ModeMessage, requireAck: true},
+		{desc: "Forward mode with ack", mode: fluenttest.ModeForward, requireAck: true},
+		{desc: "PackedForward mode with ack", mode: fluenttest.ModePackedForward, requireAck: true},
+		{desc: "CompressedPackedForward mode", mode: fluenttest.ModeCompressedPackedForward},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ed := &forwardtest.Endpoint{}
+			edServer := httptest.NewServer(ed)
+			defer edServer.Close()
+
+			conf := &cfg.Config{
+				Region:            "us-west-2",
+				EDEndpoint:        edServer.URL,
+				BatchSize:         cfg.MaxChunkSize,
+				PushTimeout:       time.Second,
+				RetryInterval:     10 * time.Millisecond,
+				ForwardSourceTags: true,
+			}
+			enricher, forwarder := forwardtest.NewForwarder(conf, push.NewPusher(conf))
+			identity := &imds.InstanceIdentity{AccountID: "123456789012", Region: "us-east-1", InstanceID: "i-0123456789abcdef0"}
+			server := NewServer(conf, enricher, forwarder, identity, "my-host")
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- server.Serve(l) }()
+
+			client := &fluenttest.Client{Addr: l.Addr().String(), Mode: tt.mode, RequireAck: tt.requireAck, Timeout: 5 * time.Second}
+			defer client.Close()
+			require.NoError(t, client.Send("app.logs", eventTime, records...))
+
+			if !tt.requireAck {
+				// without acknowledgements send returns before records are pushed
+				require.Eventually(t, func() bool { return len(ed.LogEvents()) == len(records) }, 5*time.Second, 10*time.Millisecond)
+			}
+			assert.Equal(t, wantEvents, ed.LogEvents())
+
+			for _, l := range ed.Logs() {
+				assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0", l.Cloud.ResourceID)
+				assert.Equal(t, "123456789012", l.Cloud.AccountID)
+				assert.Equal(t, "us-east-1", l.Cloud.Region)
+				assert.Equal(t, "my-host", l.HostName)
+				assert.Equal(t, "i-0123456789abcdef0", l.HostID)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.NoError(t, server.Shutdown(ctx))
+			assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+		})
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	tests := []struct {
+		desc      string
+		message   []any
+		want      *message
+		expectErr bool
+	}{
+		{
+			desc:    "Message mode with integer time and binary values",
+			message: []any{"tag", int64(1704067200), map[string]any{"log": []byte("hello"), "nested": map[string]any{"key": []byte("value")}}, map[string]any{"chunk": "abc"}},
+			want: &message{
+				tag:     "tag",
+				entries: []entry{{time: time.Unix(1704067200, 0), record: map[string]any{"log": "hello", "nested": map[string]any{"key": "value"}}}},
+				chunk:   "abc",
+			},
+		},
+		{
+			desc:    "Forward mode with float time",
+			message: []any{"tag", []any{[]any{1704067200.5, map[string]any{"log": "hello"}}}},
+			want: &message{
+				tag:     "tag",
+				entries: []entry{{time: time.UnixMilli(1704067200500), record: map[string]any{"log": "hello"}}},
+			},
+		},
+		{
+			desc:      "Record is not a map",
+			message:   []any{"tag", int64(1704067200), "hello"},
+			expectErr: true,
+		},
+		{
+			desc:      "Tag is not a string",
+			message:   []any{1, int64(1704067200), map[string]any{}},
+			expectErr: true,
+		},
+		{
+			desc:      "Unsupported compression",
+			message:   []any{"tag", []byte{0x01}, map[string]any{"compressed": "zstd"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			b, err := msgpack.Marshal(tt.message)
+			require.NoError(t, err)
+			dec := msgpack.NewDecoder(bytes.NewReader(b))
+			dec.UseLooseInterfaceDecoding(true)
+
+			got, err := decodeMessage(dec)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package forward
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/chunker"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/logparser"
+)
+
+// Pusher pushes a chunk to the destination, push.Pusher sends it to Edge Delta endpoint.
+type Pusher interface {
+	// Push blocks until payload is pushed or context is done
+	Push(ctx context.Context, payload []byte) error
+}
+
+// Forwarder chunks logs and pushes them to Edge Delta endpoint, it is shared by all event sources.
+type Forwarder struct {
+	batchSize int
+	pusher    Pusher
+	parsers   *logparser.Registry
+}
+
+func NewForwarder(conf *cfg.Config, pusher Pusher) *Forwarder {
+	return &Forwarder{
+		batchSize: conf.BatchSize,
+		pusher:    pusher,
+		parsers:   logparser.NewRegistry(conf),
+	}
+}
+
+// Forward chunks log events with the given common fields and pushes chunks in order.
+// Log events of sources with a known format (i.e. VPC flow logs) are parsed into attributes.
+// It blocks until all chunks are pushed or context is done.
+func (f *Forwarder) Forward(ctx context.Context, common *enrich.Common, logEvents []events.CloudwatchLogsLogEvent) error {
+	return f.ForwardWithParser(ctx, common, logEvents, f.Parser(common))
+}
+
+// ForwardWithParser is the same as Forward with the given parser, log events are not parsed if it is nil.
+// It is used for logs of a source which are forwarded in multiple batches, i.e. an S3 object with a header.
+func (f *Forwarder) ForwardWithParser(ctx context.Context, common *enrich.Common, logEvents []events.CloudwatchLogsLogEvent, parser logparser.Parser) error {
+	parsed := parse(parser, logEvents)
+	if len(parsed) == 0 && len(logEvents) > 0 {
+		// all log events are skipped by the parser, i.e. a batch with only the header of an object
+		return nil
+	}
+	return f.ForwardEvents(ctx, common, parsed)
+}
+
+// Parser returns the parser of the logs of the source, nil if they are not parsed.
+func (f *Forwarder) Parser(common *enrich.Common) logparser.Parser {
+	if common.AwsCommon == nil {
+		return nil
+	}
+	switch {
+	case common.AwsCommon.LogGroup != "":
+		return f.parsers.ForLogGroup(common.AwsCommon.LogGroup, common.AwsCommon.LogStream)
+	case common.AwsCommon.S3 != nil:
+		return f.parsers.ForS3Object(common.AwsCommon.S3.Key)
+	case common.AwsCommon.CloudFront != nil && common.AwsCommon.CloudFront.KinesisStreamARN != "":
+		return f.parsers.ForCloudFrontRealtime()
+	case common.AwsCommon.Firehose != nil:
+		return f.parsers.ForFirehoseDeliveryStream(common.AwsCommon.Firehose.DeliveryStreamARN)
+	}
+	return nil
+}
+
+// parse sets attributes of log events which are parsed by the parser, other events are kept as is.
+// Log events with multiple records are split into an event per record if the parser is a splitter,
+// and log events which are not records are dropped if the parser is a filter.
+func parse(parser logparser.Parser, logEvents []events.CloudwatchLogsLogEvent) []core.LogEvent {
+	if parser == nil {
+		return core.NewLogEvents(logEvents)
+	}
+	if s, ok := parser.(logparser.Splitter); ok {
+		logEvents = split(s, logEvents)
+	}
+	filter, _ := parser.(logparser.Filter)
+	res := core.NewLogEvents(logEvents)
+	n := 0
+	for i := range res {
+		attributes, timestamp, ok := parser.Parse(res[i].Message)
+		if filter != nil && filter.Skip(res[i].Message) {
+			continue
+		}
+		if ok {
+			res[i].Attributes = attributes
+			if timestamp > 0 {
+				res[i].Timestamp = timestamp
+			}
+		}
+		res[n] = res[i]
+		n++
+	}
+	return res[:n]
+}
+
+// split replaces log events with multiple records by an event per record, IDs of the records are suffixed with their index.
+func split(s logparser.Splitter, logEvents []events.CloudwatchLogsLogEvent) []events.CloudwatchLogsLogEvent {
+	var res []events.CloudwatchLogsLogEvent
+	for i, e := range logEvents {
+		records, ok := s.Split(e.Message)
+		if !ok {
+			if res != nil {
+				res = append(res, e)
+			}
+			continue
+		}
+		if res == nil {
+			res = append(make([]events.CloudwatchLogsLogEvent, 0, len(logEvents)+len(records)), logEvents[:i]...)
+		}
+		for j, r := range records {
+			res = append(res, events.CloudwatchLogsLogEvent{ID: fmt.Sprintf("%s_%d", e.ID, j), Timestamp: e.Timestamp, Message: r})
+		}
+	}
+	if res == nil {
+		return logEvents
+	}
+	return res
+}
+
+// ForwardEvents is the same as Forward for log events with attributes.
+func (f *Forwarder) ForwardEvents(ctx context.Context, common *enrich.Common, logEvents []core.LogEvent) error {
+	edLog := &core.Log{
+		Common: core.Common(*common),
+		Data: core.Data{
+			LogEvents: logEvents,
+		},
+	}
+
+	logChunker, err := chunker.NewChunker(f.batchSize, edLog)
+	if err != nil {
+		log.Printf("Failed to create log chunker, err: %v", err)
+		return err
+	}
+
+	chunks, err := logChunker.Chunk()
+	if err != nil {
+		log.Printf("Failed to chunk logs, err: %v", err)
+		return err
+	}
+
+	if err := f.push(ctx, chunks); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully pushed %d log chunks", len(chunks))
+	return nil
+}
+
+// ForwardMetrics chunks metrics with the given common fields and pushes them in metrics envelope.
+func (f *Forwarder) ForwardMetrics(ctx context.Context, common *enrich.Common, metrics []core.Metric) error {
+	edMetrics := &core.Metrics{
+		Common: core.Common(*common),
+		MetricData: core.MetricData{
+			Metrics: metrics,
+		},
+	}
+
+	metricChunker, err := chunker.NewMetricChunker(f.batchSize, edMetrics)
+	if err != nil {
+		log.Printf("Failed to create metric chunker, err: %v", err)
+		return err
+	}
+
+	chunks, err := metricChunker.Chunk()
+	if err != nil {
+		log.Printf("Failed to chunk metrics, err: %v", err)
+		return err
+	}
+
+	if err := f.push(ctx, chunks); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully pushed %d metric chunks", len(chunks))
+	return nil
+}
+
+// ForwardHealth pushes health events of the forwarder in their own envelope, they are never mixed with logs.
+func (f *Forwarder) ForwardHealth(ctx context.Context, common *enrich.Common, healthEvents []core.HealthEvent) error {
+	payload, err := json.Marshal(&core.Health{
+		Common:     core.Common(*common),
+		HealthData: core.HealthData{HealthEvents: healthEvents},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal health events, err: %v", err)
+	}
+
+	if err := f.push(ctx, [][]byte{payload}); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully pushed %d health events", len(healthEvents))
+	return nil
+}
+
+func (f *Forwarder) push(ctx context.Context, chunks [][]byte) error {
+	for i, chunk := range chunks {
+		log.Printf("Sending chunk %d of %d, size: %d bytes", i+1, len(chunks), len(chunk))
+		// blocks until context deadline
+		if err := f.pusher.Push(ctx, chunk); err != nil {
+			log.Printf("Failed to push chunk %d, err: %v", i+1, err)
+			return fmt.Errorf("failed to push chunk %d of %d, err: %v", i+1, len(chunks), err)
+		}
+	}
+	return nil
+}
+
+// BatchSize returns the max size of a pushed chunk, sources reading unbounded input use it to limit their batches.
+func (f *Forwarder) BatchSize() int {
+	return f.batchSize
+}
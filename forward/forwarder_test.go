@@ -0,0 +1,28 @@
+package forward
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/logparser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSkipsHeaders(t *testing.T) {
+	logEvents := []events.CloudwatchLogsLogEvent{
+		{ID: "1", Timestamp: 1, Message: "#Version: 1.0"},
+		{ID: "2", Timestamp: 1, Message: "#Fields: date time c-ip"},
+		{ID: "3", Timestamp: 1, Message: "2019-12-04\t21:02:31\t192.0.2.100"},
+		{ID: "4", Timestamp: 1, Message: "not a record"},
+	}
+
+	got := parse(logparser.NewCloudFrontParser(logparser.DefaultCloudFrontFields), logEvents)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "3", got[0].ID)
+		// fields of the skipped header are used
+		assert.Equal(t, map[string]any{"cloudfront.c_ip": "192.0.2.100"}, got[0].Attributes)
+		assert.Equal(t, int64(1575493351000), got[0].Timestamp)
+		assert.Equal(t, "4", got[1].ID)
+		assert.Nil(t, got[1].Attributes)
+	}
+}
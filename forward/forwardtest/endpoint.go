@@ -0,0 +1,116 @@
+// Package forwardtest provides a fake ED_ENDPOINT and a resource client without tags, so packages which enrich
+// and forward logs can be tested without AWS and Edge Delta.
+package forwardtest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/ecs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/lambda"
+)
+
+// ResourceClient returns no tags for any resource.
+type ResourceClient struct{}
+
+func (c *ResourceClient) GetResourceTags(ctx context.Context, resourceARNs ...string) (map[string]map[string]string, error) {
+	return nil, nil
+}
+
+// NewForwarder returns an enricher of resources without tags and a forwarder which pushes to the given pusher.
+func NewForwarder(conf *cfg.Config, pusher forward.Pusher) (*enrich.Enricher, *forward.Forwarder) {
+	return enrich.NewEnricher(conf, &ResourceClient{}, lambda.NewNoOpClient(), ecs.NewNoOpClient()), forward.NewForwarder(conf, pusher)
+}
+
+// Endpoint keeps the logs, metrics and health events pushed to it. It is either used as the pusher of a forwarder
+// or served over HTTP as ED_ENDPOINT of a forwarder with the default pusher.
+type Endpoint struct {
+	// Fail is called with each log before it is kept if it is set, push fails with the error it returns
+	Fail func(l core.Log) error
+
+	lock    sync.Mutex
+	logs    []core.Log
+	metrics []core.Metrics
+	health  []core.Health
+}
+
+func (e *Endpoint) Push(ctx context.Context, payload []byte) error {
+	var l core.Log
+	if err := json.Unmarshal(payload, &l); err != nil {
+		return err
+	}
+	var m core.Metrics
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return err
+	}
+	var h core.Health
+	if err := json.Unmarshal(payload, &h); err != nil {
+		return err
+	}
+	if e.Fail != nil {
+		if err := e.Fail(l); err != nil {
+			return err
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(m.Metrics) > 0 {
+		e.metrics = append(e.metrics, m)
+	} else if len(h.HealthEvents) > 0 {
+		e.health = append(e.health, h)
+	} else {
+		e.logs = append(e.logs, l)
+	}
+	return nil
+}
+
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := e.Push(r.Context(), body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Logs returns the pushed logs in the order they are pushed.
+func (e *Endpoint) Logs() []core.Log {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]core.Log(nil), e.logs...)
+}
+
+// LogEvents returns log events of all pushed logs.
+func (e *Endpoint) LogEvents() []core.LogEvent {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var res []core.LogEvent
+	for _, l := range e.logs {
+		res = append(res, l.LogEvents...)
+	}
+	return res
+}
+
+// Metrics returns the pushed metrics in the order they are pushed.
+func (e *Endpoint) Metrics() []core.Metrics {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]core.Metrics(nil), e.metrics...)
+}
+
+// Health returns the pushed health events in the order they are pushed.
+func (e *Endpoint) Health() []core.Health {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]core.Health(nil), e.health...)
+}
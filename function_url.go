@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/otlp"
+	"github.com/edgedelta/edgedelta-forwarder/webhook"
+)
+
+type functionURLResponseBody struct {
+	Message  string `json:"message,omitempty"`
+	Accepted int    `json:"accepted"`
+}
+
+// handleFunctionURLRequest forwards logs posted to the Function URL of the forwarder, API Gateway HTTP API requests
+// with payload format 2.0 have the same shape. Status codes tell senders whether the request should be retried:
+// 4xx for requests that would fail again and 5xx for push failures. Requests to OTLP logs path are handled as OTLP/HTTP
+// export requests.
+func handleFunctionURLRequest(ctx context.Context, req events.LambdaFunctionURLRequest) (resp events.LambdaFunctionURLResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in handleFunctionURLRequest, err: %v", r)
+			resp = newFunctionURLResponse(http.StatusInternalServerError, 0, fmt.Errorf("internal error"))
+		}
+	}()
+
+	httpContext := req.RequestContext.HTTP
+	if httpContext.Method != http.MethodPost {
+		return newFunctionURLResponse(http.StatusMethodNotAllowed, 0, fmt.Errorf("method %s is not allowed", httpContext.Method)), nil
+	}
+
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		body, err = base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return newFunctionURLResponse(http.StatusBadRequest, 0, fmt.Errorf("failed to decode base64 body, err: %v", err)), nil
+		}
+	}
+
+	var callerARN string
+	if authorizer := req.RequestContext.Authorizer; authorizer != nil && authorizer.IAM != nil {
+		callerARN = authorizer.IAM.UserARN
+	}
+	authMethod, err := authenticator.Authenticate(req.Headers, body, callerARN != "")
+	if err != nil {
+		log.Printf("Rejected HTTP request: %s from: %s, err: %v", req.RequestContext.RequestID, httpContext.SourceIP, err)
+		return newFunctionURLResponse(http.StatusUnauthorized, 0, err), nil
+	}
+
+	if httpContext.Path == otlp.LogsPath {
+		return newOTLPFunctionURLResponse(otlpServer.Export(ctx, body, req.Headers["content-type"], req.Headers["content-encoding"])), nil
+	}
+
+	logEvents, err := webhook.DecodeBody(body, req.Headers["content-type"], req.Headers["content-encoding"], req.RequestContext.TimeEpoch)
+	if err != nil {
+		return newFunctionURLResponse(http.StatusBadRequest, 0, err), nil
+	}
+	if len(logEvents) == 0 {
+		return newFunctionURLResponse(http.StatusOK, 0, nil), nil
+	}
+
+	common := enricher.GetHTTPCommon(ctx, req.RequestContext.DomainName, httpContext.Path, httpContext.SourceIP, httpContext.UserAgent, authMethod, callerARN)
+	if err := forwarder.Forward(ctx, common, logEvents); err != nil {
+		log.Printf("Failed to forward HTTP request: %s, err: %v", req.RequestContext.RequestID, err)
+		return newFunctionURLResponse(http.StatusServiceUnavailable, 0, errors.New("failed to forward logs")), nil
+	}
+
+	return newFunctionURLResponse(http.StatusOK, len(logEvents), nil), nil
+}
+
+func newFunctionURLResponse(statusCode, accepted int, err error) events.LambdaFunctionURLResponse {
+	body := functionURLResponseBody{Accepted: accepted}
+	if err != nil {
+		body.Message = err.Error()
+	}
+	b, _ := json.Marshal(body)
+	return events.LambdaFunctionURLResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}
+}
+
+// newOTLPFunctionURLResponse returns protobuf response bodies base64 encoded, as Function URL responses are strings.
+func newOTLPFunctionURLResponse(resp *otlp.Response) events.LambdaFunctionURLResponse {
+	res := events.LambdaFunctionURLResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    map[string]string{"Content-Type": resp.ContentType},
+		Body:       string(resp.Body),
+	}
+	if resp.ContentType == otlp.ContentTypeProtobuf {
+		res.Body = base64.StdEncoding.EncodeToString(resp.Body)
+		res.IsBase64Encoded = true
+	}
+	return res
+}
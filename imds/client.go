@@ -0,0 +1,55 @@
+package imds
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	// metadataTimeout is kept short since metadata endpoint is not reachable on-prem
+	metadataTimeout = 2 * time.Second
+)
+
+// InstanceIdentity identifies the EC2 instance the forwarder runs on.
+type InstanceIdentity struct {
+	AccountID  string
+	Region     string
+	InstanceID string
+}
+
+type Client interface {
+	GetInstanceIdentity(ctx context.Context) (*InstanceIdentity, error)
+}
+
+type DefaultClient struct {
+	svc *ec2metadata.EC2Metadata
+}
+
+func NewClient() (*DefaultClient, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS EC2 metadata client, err: %v", err)
+	}
+	return &DefaultClient{svc: ec2metadata.New(sess, &aws.Config{
+		HTTPClient: &http.Client{Timeout: metadataTimeout},
+		MaxRetries: aws.Int(0),
+	})}, nil
+}
+
+func (c *DefaultClient) GetInstanceIdentity(ctx context.Context) (*InstanceIdentity, error) {
+	doc, err := c.svc.GetInstanceIdentityDocumentWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &InstanceIdentity{
+		AccountID:  doc.AccountID,
+		Region:     doc.Region,
+		InstanceID: doc.InstanceID,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/kafka"
+	"github.com/edgedelta/edgedelta-forwarder/utils"
+)
+
+// handleKafkaRequest forwards records of MSK or self-managed Kafka event source mappings. Partitions are processed
+// concurrently and records of a partition are forwarded in order. Kafka event source mappings do not support partial
+// batch responses, so an error is returned when any partition fails and the batch is retried from the first offsets.
+func handleKafkaRequest(ctx context.Context, kafkaEvent events.KafkaEvent) error {
+	// records are keyed by {topic}-{partition}
+	partitions := make([]string, 0, len(kafkaEvent.Records))
+	for p := range kafkaEvent.Records {
+		partitions = append(partitions, p)
+	}
+	sort.Strings(partitions)
+
+	errs := make([]error, len(partitions))
+	utils.ProcessInParallel(len(partitions), config.WorkerCount, func(i int) {
+		errs[i] = processKafkaPartition(ctx, kafkaEvent, kafkaEvent.Records[partitions[i]])
+	})
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Failed to process Kafka partition: %s, err: %v", partitions[i], err)
+			failed = append(failed, partitions[i])
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to process %d of %d Kafka partitions: %s", len(failed), len(partitions), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func processKafkaPartition(ctx context.Context, kafkaEvent events.KafkaEvent, records []events.KafkaRecord) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in processKafkaPartition, err: %v", r)
+			err = fmt.Errorf("panic while processing partition: %v", r)
+		}
+	}()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	logEvents := make([]core.LogEvent, 0, len(records))
+	for _, record := range records {
+		logEvent, err := kafka.DecodeRecord(record)
+		if err != nil {
+			// retrying would not help, so record is skipped instead of blocking the partition
+			log.Printf("Failed to decode Kafka record: %s-%d offset: %d, err: %v", record.Topic, record.Partition, record.Offset, err)
+			continue
+		}
+		logEvents = append(logEvents, logEvent)
+	}
+
+	first := records[0]
+	common := enricher.GetKafkaCommon(ctx, kafkaEvent.EventSource, kafkaEvent.EventSourceARN, kafkaEvent.BootstrapServers, first.Topic, first.Partition)
+	if err := forwarder.ForwardEvents(ctx, common, logEvents); err != nil {
+		return fmt.Errorf("failed to forward records from offset %d, err: %v", first.Offset, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+)
+
+const (
+	EventSourceMSK         = "aws:kafka"
+	EventSourceSelfManaged = "SelfManagedKafka"
+	// maxDecompressedValueSize protects against gzip bombs, lambda request payloads are limited to 6MB
+	maxDecompressedValueSize = 64 * 1024 * 1024
+)
+
+// DecodeRecord returns the log event of a Kafka record. Values are base64 encoded by lambda and may be gzip compressed,
+// JSON values are compacted and other values are used as text. Offset, key and headers are added as attributes.
+func DecodeRecord(record events.KafkaRecord) (core.LogEvent, error) {
+	value, err := base64.StdEncoding.DecodeString(record.Value)
+	if err != nil {
+		return core.LogEvent{}, fmt.Errorf("failed to decode base64 value, err: %v", err)
+	}
+	value, err = decompress(value)
+	if err != nil {
+		return core.LogEvent{}, err
+	}
+
+	attributes := map[string]any{
+		"kafka.offset": record.Offset,
+	}
+	if record.TimestampType != "" {
+		attributes["kafka.timestamp_type"] = record.TimestampType
+	}
+	if key, err := base64.StdEncoding.DecodeString(record.Key); err == nil && len(key) > 0 {
+		attributes["kafka.key"] = bytesToString(key)
+	}
+	if len(record.Headers) > 0 {
+		headers := make(map[string]string)
+		for _, h := range record.Headers {
+			for k, v := range h {
+				headers[k] = bytesToString(v)
+			}
+		}
+		attributes["kafka.headers"] = headers
+	}
+
+	return core.LogEvent{
+		CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{
+			ID:        strconv.FormatInt(record.Offset, 10),
+			Timestamp: record.Timestamp.UnixMilli(),
+			Message:   decodeMessage(value),
+		},
+		Attributes: attributes,
+	}, nil
+}
+
+func decompress(value []byte) ([]byte, error) {
+	if len(value) < 2 || value[0] != 0x1f || value[1] != 0x8b {
+		return value, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader, err: %v", err)
+	}
+	defer zr.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(zr, maxDecompressedValueSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value, err: %v", err)
+	}
+	if len(decompressed) > maxDecompressedValueSize {
+		return nil, fmt.Errorf("decompressed value exceeds %d bytes", maxDecompressedValueSize)
+	}
+	return decompressed, nil
+}
+
+func decodeMessage(value []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err == nil {
+		return buf.String()
+	}
+	return strings.TrimRight(bytesToString(value), "\r\n")
+}
+
+// bytesToString returns binary keys, headers and values base64 encoded so they are not corrupted.
+func bytesToString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRecord(t *testing.T) {
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, err := zw.Write([]byte(`{"level": "error", "msg": "failed"}`))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	encode := func(b []byte) string {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	timestamp := events.MilliSecondsEpochTime{Time: time.UnixMilli(1704067200000)}
+
+	tests := []struct {
+		desc      string
+		record    events.KafkaRecord
+		want      core.LogEvent
+		expectErr bool
+	}{
+		{
+			desc: "Plain text value with key and headers",
+			record: events.KafkaRecord{
+				Topic:         "logs",
+				Partition:     1,
+				Offset:        15,
+				Timestamp:     timestamp,
+				TimestampType: "CREATE_TIME",
+				Key:           encode([]byte("order-1")),
+				Value:         encode([]byte("order created\n")),
+				Headers:       []map[string]events.JSONNumberBytes{{"trace-id": []byte("abc")}, {"binary": []byte{0xff, 0xfe}}},
+			},
+			want: core.LogEvent{
+				CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{ID: "15", Timestamp: 1704067200000, Message: "order created"},
+				Attributes: map[string]any{
+					"kafka.offset":         int64(15),
+					"kafka.timestamp_type": "CREATE_TIME",
+					"kafka.key":            "order-1",
+					"kafka.headers":        map[string]string{"trace-id": "abc", "binary": "//4="},
+				},
+			},
+		},
+		{
+			desc: "Gzip compressed JSON value",
+			record: events.KafkaRecord{
+				Topic:     "logs",
+				Offset:    16,
+				Timestamp: timestamp,
+				Value:     encode(gzipped.Bytes()),
+			},
+			want: core.LogEvent{
+				CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{ID: "16", Timestamp: 1704067200000, Message: `{"level":"error","msg":"failed"}`},
+				Attributes:             map[string]any{"kafka.offset": int64(16)},
+			},
+		},
+		{
+			desc:      "Invalid base64 value",
+			record:    events.KafkaRecord{Value: "not base64!"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := DecodeRecord(tc.record)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
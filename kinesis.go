@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/kinesis"
+	"github.com/edgedelta/edgedelta-forwarder/logparser"
+	"github.com/edgedelta/edgedelta-forwarder/utils"
+)
+
+// handleKinesisRequest processes CloudWatch Logs subscription payloads delivered through a Kinesis stream,
+// i.e. cross account log destinations. Records are processed concurrently and the first failed record is reported,
+// so the event source mapping checkpoints before it and retries from it. Records after the first failed record are
+// forwarded again on retry even if they succeeded (at-least-once delivery).
+// Requires ReportBatchItemFailures to be enabled on the event source mapping.
+// CloudFront real-time log records, one log line each, are forwarded together instead.
+func handleKinesisRequest(ctx context.Context, kinesisEvent events.KinesisEvent) (events.KinesisEventResponse, error) {
+	records := kinesisEvent.Records
+	failed := make([]bool, len(records))
+	realtime := make([]bool, len(records))
+	var realtimeRecords []events.KinesisEventRecord
+	for i := range records {
+		if logparser.IsCloudFrontRealtimeRecord(records[i].Kinesis.Data) {
+			realtime[i] = true
+			realtimeRecords = append(realtimeRecords, records[i])
+		}
+	}
+	if len(realtimeRecords) > 0 {
+		if err := forwardCloudFrontRealtimeRecords(ctx, realtimeRecords); err != nil {
+			log.Printf("Failed to forward %d CloudFront real-time log records, err: %v", len(realtimeRecords), err)
+			copy(failed, realtime)
+		}
+	}
+
+	utils.ProcessInParallel(len(records), config.WorkerCount, func(i int) {
+		if realtime[i] {
+			return
+		}
+		if err := processKinesisRecord(ctx, records[i]); err != nil {
+			log.Printf("Failed to process Kinesis record: %s, err: %v", records[i].Kinesis.SequenceNumber, err)
+			failed[i] = true
+		}
+	})
+
+	failures := 0
+	for _, f := range failed {
+		if f {
+			failures++
+		}
+	}
+	if failures > 0 {
+		log.Printf("Failed to process %d of %d Kinesis records", failures, len(records))
+	}
+	return kinesis.NewResponse(records, failed), nil
+}
+
+func processKinesisRecord(ctx context.Context, record events.KinesisEventRecord) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in processKinesisRecord, err: %v", r)
+			err = fmt.Errorf("panic while processing record: %v", r)
+		}
+	}()
+
+	data, err := cwlogs.Decode(record.Kinesis.Data)
+	if err != nil {
+		return err
+	}
+
+	return forwardCloudwatchLogsData(ctx, data)
+}
+
+// forwardCloudFrontRealtimeRecords forwards CloudFront real-time log records of a stream,
+// approximate arrival time of a record is its timestamp until it is parsed.
+func forwardCloudFrontRealtimeRecords(ctx context.Context, records []events.KinesisEventRecord) error {
+	logEvents := make([]events.CloudwatchLogsLogEvent, 0, len(records))
+	for _, r := range records {
+		logEvents = append(logEvents, events.CloudwatchLogsLogEvent{
+			ID:        r.Kinesis.SequenceNumber,
+			Timestamp: r.Kinesis.ApproximateArrivalTimestamp.UnixMilli(),
+			Message:   strings.TrimRight(string(r.Kinesis.Data), "\n"),
+		})
+	}
+	common := enricher.GetCloudFrontRealtimeCommon(ctx, records[0].EventSourceArn)
+	return forwarder.Forward(ctx, common, logEvents)
+}
@@ -0,0 +1,19 @@
+package kinesis
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// NewResponse reports the first failed record of the batch. Lambda checkpoints the shard at the lowest reported sequence
+// number and retries the batch from that record, so records after it are retried even if they were forwarded, and
+// reporting them too would not change what is retried. Records are in sequence number order in the event.
+func NewResponse(records []events.KinesisEventRecord, failed []bool) events.KinesisEventResponse {
+	for i, f := range failed {
+		if f {
+			return events.KinesisEventResponse{
+				BatchItemFailures: []events.KinesisBatchItemFailure{{ItemIdentifier: records[i].Kinesis.SequenceNumber}},
+			}
+		}
+	}
+	return events.KinesisEventResponse{}
+}
@@ -0,0 +1,54 @@
+package kinesis
+
+import (
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestNewResponse(t *testing.T) {
+	records := []events.KinesisEventRecord{
+		{Kinesis: events.KinesisRecord{SequenceNumber: "1"}},
+		{Kinesis: events.KinesisRecord{SequenceNumber: "2"}},
+		{Kinesis: events.KinesisRecord{SequenceNumber: "3"}},
+	}
+	tests := []struct {
+		desc   string
+		failed []bool
+		want   []string
+	}{
+		{
+			desc:   "No failure",
+			failed: []bool{false, false, false},
+			want:   nil,
+		},
+		{
+			desc:   "Failures after a forwarded record are retried from the first failure",
+			failed: []bool{false, true, true},
+			want:   []string{"2"},
+		},
+		{
+			desc:   "Forwarded records after a failure are retried too",
+			failed: []bool{true, false, true},
+			want:   []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			response := NewResponse(records, tt.failed)
+			var got []string
+			for _, f := range response.BatchItemFailures {
+				got = append(got, f.ItemIdentifier)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected failures: %v, got: %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected failures: %v, got: %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
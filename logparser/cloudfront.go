@@ -0,0 +1,192 @@
+package logparser
+
+import (
+	"bytes"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cloudFrontPrefix = "cloudfront."
+	// cloudFrontFieldsDirective is the header line of standard log files which lists the fields of the records
+	cloudFrontFieldsDirective = "#Fields:"
+	cloudFrontDateTimeLayout  = "2006-01-02 15:04:05"
+)
+
+var (
+	// DefaultCloudFrontFields are the fields of standard log files, which are used until the "#Fields:" header is read
+	DefaultCloudFrontFields = []string{
+		"date", "time", "x-edge-location", "sc-bytes", "c-ip", "cs-method", "cs(Host)", "cs-uri-stem", "sc-status",
+		"cs(Referer)", "cs(User-Agent)", "cs-uri-query", "cs(Cookie)", "x-edge-result-type", "x-edge-request-id",
+		"x-host-header", "cs-protocol", "cs-bytes", "time-taken", "x-forwarded-for", "ssl-protocol", "ssl-cipher",
+		"x-edge-response-result-type", "cs-protocol-version", "fle-status", "fle-encrypted-fields", "c-port",
+		"time-to-first-byte", "x-edge-detailed-result-type", "sc-content-type", "sc-content-len", "sc-range-start",
+		"sc-range-end",
+	}
+
+	// DefaultCloudFrontRealtimeFields are all fields of real-time logs in the order they are documented
+	DefaultCloudFrontRealtimeFields = []string{
+		"timestamp", "c-ip", "time-to-first-byte", "sc-status", "sc-bytes", "cs-method", "cs-protocol", "cs-host",
+		"cs-uri-stem", "cs-bytes", "x-edge-location", "x-edge-request-id", "x-host-header", "time-taken",
+		"cs-protocol-version", "c-ip-version", "cs-user-agent", "cs-referer", "cs-cookie", "cs-uri-query",
+		"x-edge-response-result-type", "x-forwarded-for", "ssl-protocol", "ssl-cipher", "x-edge-result-type",
+		"fle-encrypted-fields", "fle-status", "sc-content-type", "sc-content-len", "sc-range-start", "sc-range-end",
+		"c-port", "x-edge-detailed-result-type", "c-country", "cs-accept-encoding", "cs-accept",
+		"cache-behavior-path-pattern", "cs-headers", "cs-header-names", "cs-headers-count", "primary-distribution-id",
+		"primary-distribution-dns-name", "origin-fbl", "origin-lbl", "asn",
+	}
+
+	// standard log files are named {distribution_id}.{yyyy}-{mm}-{dd}-{hh}.{unique_id}.gz
+	cloudFrontS3KeyRegex = regexp.MustCompile(`(^|/)[A-Z0-9]{8,}\.\d{4}-\d{2}-\d{2}-\d{2}\.[A-Za-z0-9]+\.gz$`)
+
+	cloudFrontIntegerFields = map[string]bool{
+		"sc-bytes":         true,
+		"sc-status":        true,
+		"cs-bytes":         true,
+		"c-port":           true,
+		"sc-content-len":   true,
+		"sc-range-start":   true,
+		"sc-range-end":     true,
+		"cs-headers-count": true,
+		"asn":              true,
+	}
+
+	cloudFrontFloatFields = map[string]bool{
+		"time-taken":         true,
+		"time-to-first-byte": true,
+		"origin-fbl":         true,
+		"origin-lbl":         true,
+	}
+
+	cloudFrontKeyReplacer = strings.NewReplacer("-", "_", "(", "_", ")", "")
+)
+
+// CloudFrontParser parses tab separated CloudFront standard log and real-time log records.
+// Parser of standard logs reads the fields from the header of the file, so a parser should be used for a single file.
+type CloudFrontParser struct {
+	fields []string
+	header bool
+}
+
+// NewCloudFrontParser returns a parser of standard log files, the given fields are used until the "#Fields:" header is read.
+func NewCloudFrontParser(fields []string) *CloudFrontParser {
+	return &CloudFrontParser{fields: fields, header: true}
+}
+
+// NewCloudFrontRealtimeParser returns a parser of real-time log records, which have the fields of the real-time log configuration.
+// Fields are given comma or space separated, all fields are used if empty.
+func NewCloudFrontRealtimeParser(fields string) *CloudFrontParser {
+	f := strings.FieldsFunc(fields, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(f) == 0 {
+		f = DefaultCloudFrontRealtimeFields
+	}
+	return &CloudFrontParser{fields: f}
+}
+
+// Parse returns fields of a record with "cloudfront." prefix, field names are lower cased and hyphens and parentheses
+// are replaced by underscores (i.e. "cs(User-Agent)" is "cloudfront.cs_user_agent"). URL encoded values are decoded,
+// numeric fields are numbers and fields without value ("-") are omitted. Timestamp is the date and time fields of
+// standard logs or the timestamp field of real-time logs, they are not added as fields.
+// Header lines of standard log files ("#Version:", "#Fields:") are not parsed.
+func (p *CloudFrontParser) Parse(message string) (map[string]any, int64, bool) {
+	if strings.HasPrefix(message, "#") {
+		if fields, ok := strings.CutPrefix(message, cloudFrontFieldsDirective); ok && p.header {
+			p.fields = strings.Fields(fields)
+		}
+		return nil, 0, false
+	}
+	values := strings.Split(message, "\t")
+	if len(values) != len(p.fields) {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(cloudFrontPrefix, len(values))
+	var date, clock string
+	var timestamp int64
+	for i, v := range values {
+		field := p.fields[i]
+		switch field {
+		case "date":
+			date = v
+			continue
+		case "time":
+			clock = v
+			continue
+		case "timestamp":
+			ts, ok := parseEpochSecondsMillis(v)
+			if !ok {
+				return nil, 0, false
+			}
+			timestamp = ts
+			continue
+		}
+
+		key := cloudFrontKeyReplacer.Replace(strings.ToLower(field))
+		switch {
+		case cloudFrontIntegerFields[field]:
+			f.setInt(key, v)
+		case cloudFrontFloatFields[field]:
+			f.setFloat(key, v)
+		default:
+			f.setString(key, unescapeURL(v))
+		}
+	}
+	if f.err != nil {
+		return nil, 0, false
+	}
+
+	if date != "" && clock != "" {
+		t, err := time.Parse(cloudFrontDateTimeLayout, date+" "+clock)
+		if err != nil {
+			return nil, 0, false
+		}
+		timestamp = t.UnixMilli()
+	}
+	return f.attributes, timestamp, true
+}
+
+// Skip returns true for header lines of standard log files ("#Version:", "#Fields:"), they are not forwarded.
+func (p *CloudFrontParser) Skip(message string) bool {
+	return strings.HasPrefix(message, "#")
+}
+
+// unescapeURL decodes percent encoded characters, value is returned as is if it is not valid.
+func unescapeURL(v string) string {
+	if !strings.Contains(v, "%") {
+		return v
+	}
+	u, err := url.PathUnescape(v)
+	if err != nil {
+		return v
+	}
+	return u
+}
+
+// parseEpochSecondsMillis parses epoch seconds with milliseconds, i.e. "1607554000.123".
+func parseEpochSecondsMillis(v string) (int64, bool) {
+	s, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(math.Round(s * 1000)), true
+}
+
+// IsCloudFrontS3Key returns true if the object is a standard log file of a CloudFront distribution.
+func IsCloudFrontS3Key(key string) bool {
+	return cloudFrontS3KeyRegex.MatchString(key)
+}
+
+// IsCloudFrontRealtimeRecord returns true if the data of a Kinesis record is a CloudFront real-time log record,
+// which starts with the timestamp field. CloudWatch Logs payloads delivered to the same stream are compressed JSON.
+func IsCloudFrontRealtimeRecord(data []byte) bool {
+	first, _, ok := bytes.Cut(data, []byte("\t"))
+	if !ok {
+		return false
+	}
+	_, ok = parseEpochSecondsMillis(string(first))
+	return ok
+}
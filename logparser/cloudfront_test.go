@@ -0,0 +1,165 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudFrontParser(t *testing.T) {
+	standard := "2019-12-04\t21:02:31\tLAX1\t392\t192.0.2.100\tGET\td111111abcdef8.cloudfront.net\t/index.html\t200\t-\tMozilla/5.0%20(Windows%20NT%2010.0)\tq=a%20b\t-\tHit\tSOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==\td111111abcdef8.cloudfront.net\thttps\t23\t0.001\t-\tTLSv1.2\tECDHE-RSA-AES128-GCM-SHA256\tHit\tHTTP/2.0\t-\t-\t11040\t0.001\tHit\ttext/html\t78\t-\t-"
+
+	tests := []struct {
+		desc          string
+		parser        *CloudFrontParser
+		messages      []string
+		want          map[string]any
+		wantTimestamp int64
+		wantOK        bool
+	}{
+		{
+			desc:     "Standard log with default fields",
+			parser:   NewCloudFrontParser(DefaultCloudFrontFields),
+			messages: []string{"#Version: 1.0", standard},
+			want: map[string]any{
+				"cloudfront.x_edge_location":             "LAX1",
+				"cloudfront.sc_bytes":                    int64(392),
+				"cloudfront.c_ip":                        "192.0.2.100",
+				"cloudfront.cs_method":                   "GET",
+				"cloudfront.cs_host":                     "d111111abcdef8.cloudfront.net",
+				"cloudfront.cs_uri_stem":                 "/index.html",
+				"cloudfront.sc_status":                   int64(200),
+				"cloudfront.cs_user_agent":               "Mozilla/5.0 (Windows NT 10.0)",
+				"cloudfront.cs_uri_query":                "q=a b",
+				"cloudfront.x_edge_result_type":          "Hit",
+				"cloudfront.x_edge_request_id":           "SOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==",
+				"cloudfront.x_host_header":               "d111111abcdef8.cloudfront.net",
+				"cloudfront.cs_protocol":                 "https",
+				"cloudfront.cs_bytes":                    int64(23),
+				"cloudfront.time_taken":                  0.001,
+				"cloudfront.ssl_protocol":                "TLSv1.2",
+				"cloudfront.ssl_cipher":                  "ECDHE-RSA-AES128-GCM-SHA256",
+				"cloudfront.x_edge_response_result_type": "Hit",
+				"cloudfront.cs_protocol_version":         "HTTP/2.0",
+				"cloudfront.c_port":                      int64(11040),
+				"cloudfront.time_to_first_byte":          0.001,
+				"cloudfront.x_edge_detailed_result_type": "Hit",
+				"cloudfront.sc_content_type":             "text/html",
+				"cloudfront.sc_content_len":              int64(78),
+			},
+			wantTimestamp: 1575493351000,
+			wantOK:        true,
+		},
+		{
+			desc:   "Standard log with fields in the header",
+			parser: NewCloudFrontParser(DefaultCloudFrontFields),
+			messages: []string{
+				"#Version: 1.0",
+				"#Fields: date time c-ip cs(User-Agent) sc-status",
+				"2019-12-04\t21:02:31\t2001:db8::1\tcurl/8.0\t404",
+			},
+			want: map[string]any{
+				"cloudfront.c_ip":          "2001:db8::1",
+				"cloudfront.cs_user_agent": "curl/8.0",
+				"cloudfront.sc_status":     int64(404),
+			},
+			wantTimestamp: 1575493351000,
+			wantOK:        true,
+		},
+		{
+			desc:     "Header line",
+			parser:   NewCloudFrontParser(DefaultCloudFrontFields),
+			messages: []string{"#Fields: date time c-ip"},
+		},
+		{
+			desc:     "Invalid date",
+			parser:   NewCloudFrontParser([]string{"date", "time", "c-ip"}),
+			messages: []string{"2019-12-04\t25:02:31\t192.0.2.100"},
+		},
+		{
+			desc:     "Missing fields",
+			parser:   NewCloudFrontParser(DefaultCloudFrontFields),
+			messages: []string{"2019-12-04\t21:02:31\tLAX1"},
+		},
+		{
+			desc:     "Real-time log with configured fields",
+			parser:   NewCloudFrontRealtimeParser("timestamp, c-ip, sc-status, cs-uri-stem, cs-user-agent, origin-fbl, asn"),
+			messages: []string{"1607554000.123\t192.0.2.100\t200\t/index%20page.html\tcurl/8.0\t0.015\t16509"},
+			want: map[string]any{
+				"cloudfront.c_ip":          "192.0.2.100",
+				"cloudfront.sc_status":     int64(200),
+				"cloudfront.cs_uri_stem":   "/index page.html",
+				"cloudfront.cs_user_agent": "curl/8.0",
+				"cloudfront.origin_fbl":    0.015,
+				"cloudfront.asn":           int64(16509),
+			},
+			wantTimestamp: 1607554000123,
+			wantOK:        true,
+		},
+		{
+			desc:     "Real-time log does not read header",
+			parser:   NewCloudFrontRealtimeParser("timestamp c-ip"),
+			messages: []string{"#Fields: c-ip", "1607554000.123\t192.0.2.100"},
+			want: map[string]any{
+				"cloudfront.c_ip": "192.0.2.100",
+			},
+			wantTimestamp: 1607554000123,
+			wantOK:        true,
+		},
+		{
+			desc:     "Real-time log with invalid number",
+			parser:   NewCloudFrontRealtimeParser("timestamp sc-status"),
+			messages: []string{"1607554000.123\tOK"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var got map[string]any
+			var timestamp int64
+			var ok bool
+			for _, m := range tt.messages {
+				got, timestamp, ok = tt.parser.Parse(m)
+			}
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTimestamp, timestamp)
+		})
+	}
+}
+
+func TestNewCloudFrontRealtimeParser(t *testing.T) {
+	assert.Equal(t, DefaultCloudFrontRealtimeFields, NewCloudFrontRealtimeParser("").fields)
+	assert.Equal(t, []string{"timestamp", "c-ip", "sc-status"}, NewCloudFrontRealtimeParser("timestamp,c-ip, sc-status").fields)
+}
+
+func TestIsCloudFrontS3Key(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"EMLARXS9EXAMPLE.2019-11-14-20.RT4KCN4SGK9.gz", true},
+		{"cloudfront/EMLARXS9EXAMPLE.2019-11-14-20.RT4KCN4SGK9.gz", true},
+		{"cloudfront/app.2019-11-14-20.log.gz", false},
+		{"AWSLogs/123456789012/elasticloadbalancing/us-east-2/2024/01/01/123456789012_elasticloadbalancing_us-east-2_app.my-loadbalancer.50dc6c495c0c9188_20240101T0000Z_192.168.131.39_2hiyxfm2.log.gz", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsCloudFrontS3Key(tt.key))
+		})
+	}
+}
+
+func TestIsCloudFrontRealtimeRecord(t *testing.T) {
+	assert.True(t, IsCloudFrontRealtimeRecord([]byte("1607554000.123\t192.0.2.100\t200\n")))
+	assert.False(t, IsCloudFrontRealtimeRecord([]byte{0x1f, 0x8b, 0x08, 0x00}))
+	assert.False(t, IsCloudFrontRealtimeRecord([]byte(`{"messageType":"DATA_MESSAGE"}`)))
+}
+
+func TestCloudFrontParserSkip(t *testing.T) {
+	p := NewCloudFrontParser(DefaultCloudFrontFields)
+	assert.True(t, p.Skip("#Version: 1.0"))
+	assert.True(t, p.Skip("#Fields: date time c-ip"))
+	assert.False(t, p.Skip("2019-12-04\t21:02:31\t192.0.2.100"))
+}
@@ -0,0 +1,192 @@
+package logparser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	cloudTrailPrefix = "cloudtrail."
+	// cloudTrailFilePrefix is the start of CloudTrail log files, which have the events of a delivery in Records
+	cloudTrailFilePrefix = `{"Records":`
+	// default name of the log group of a trail created in the console is aws-cloudtrail-logs-{account_id}-{random}
+	cloudTrailLogGroupPrefix = "aws-cloudtrail-logs-"
+)
+
+var (
+	// CloudTrail log files are delivered to [prefix/]AWSLogs/[{org_id}/]{account_id}/CloudTrail/{region}/yyyy/mm/dd/{account_id}_CloudTrail_{region}_...json.gz
+	cloudTrailS3KeyRegex = regexp.MustCompile(`(^|/)AWSLogs/(o-[a-z0-9]+/)?\d{12}/CloudTrail/[a-z0-9-]+/\d{4}/\d{2}/\d{2}/\d{12}_CloudTrail_[a-z0-9-]+_[^/]+\.json(\.gz)?$`)
+	// CloudTrail log streams are named [{org_id}_]{account_id}_CloudTrail_{region}[_{n}]
+	cloudTrailLogStreamRegex = regexp.MustCompile(`^(o-[a-z0-9]+_)?\d{12}_CloudTrail_[a-z0-9-]+(_\d+)?$`)
+
+	cloudTrailParser = &CloudTrailParser{}
+)
+
+type cloudTrailSessionIssuer struct {
+	Type        string `json:"type"`
+	PrincipalID string `json:"principalId"`
+	ARN         string `json:"arn"`
+	AccountID   string `json:"accountId"`
+	UserName    string `json:"userName"`
+}
+
+type cloudTrailEvent struct {
+	EventVersion string `json:"eventVersion"`
+	UserIdentity struct {
+		Type           string `json:"type"`
+		PrincipalID    string `json:"principalId"`
+		ARN            string `json:"arn"`
+		AccountID      string `json:"accountId"`
+		AccessKeyID    string `json:"accessKeyId"`
+		UserName       string `json:"userName"`
+		InvokedBy      string `json:"invokedBy"`
+		SessionContext *struct {
+			SessionIssuer *cloudTrailSessionIssuer `json:"sessionIssuer"`
+			Attributes    *struct {
+				MFAAuthenticated string `json:"mfaAuthenticated"`
+			} `json:"attributes"`
+		} `json:"sessionContext"`
+	} `json:"userIdentity"`
+	EventTime          string `json:"eventTime"`
+	EventSource        string `json:"eventSource"`
+	EventName          string `json:"eventName"`
+	AWSRegion          string `json:"awsRegion"`
+	SourceIPAddress    string `json:"sourceIPAddress"`
+	UserAgent          string `json:"userAgent"`
+	ErrorCode          string `json:"errorCode"`
+	ErrorMessage       string `json:"errorMessage"`
+	RequestID          string `json:"requestID"`
+	EventID            string `json:"eventID"`
+	ReadOnly           *bool  `json:"readOnly"`
+	EventType          string `json:"eventType"`
+	ManagementEvent    *bool  `json:"managementEvent"`
+	RecipientAccountID string `json:"recipientAccountId"`
+	SharedEventID      string `json:"sharedEventID"`
+	VPCEndpointID      string `json:"vpcEndpointId"`
+	EventCategory      string `json:"eventCategory"`
+	Resources          []struct {
+		ARN       string `json:"ARN"`
+		AccountID string `json:"accountId"`
+		Type      string `json:"type"`
+	} `json:"resources"`
+	TLSDetails *struct {
+		TLSVersion string `json:"tlsVersion"`
+	} `json:"tlsDetails"`
+}
+
+// CloudTrailParser parses CloudTrail events, log files with multiple events are split into events.
+type CloudTrailParser struct{}
+
+// Split returns the events in Records of a CloudTrail log file, false if the message is a single event.
+func (p *CloudTrailParser) Split(message string) ([]string, bool) {
+	if !strings.HasPrefix(message, cloudTrailFilePrefix) {
+		return nil, false
+	}
+	var file struct {
+		Records []json.RawMessage `json:"Records"`
+	}
+	if err := json.Unmarshal([]byte(message), &file); err != nil {
+		return nil, false
+	}
+	records := make([]string, len(file.Records))
+	for i, r := range file.Records {
+		records[i] = string(r)
+	}
+	return records, true
+}
+
+// Parse returns fields of a CloudTrail event with "cloudtrail." prefix. Identity of the principal is normalized into
+// "cloudtrail.user_identity.*" fields, i.e. session issuer of an assumed role, and service of the event source and
+// ARNs and types of the resources are added. Timestamp is the event time.
+func (p *CloudTrailParser) Parse(message string) (map[string]any, int64, bool) {
+	var e cloudTrailEvent
+	if err := json.Unmarshal([]byte(message), &e); err != nil || e.EventVersion == "" || e.EventTime == "" {
+		return nil, 0, false
+	}
+	timestamp, ok := parseRFC3339Millis(e.EventTime)
+	if !ok {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(cloudTrailPrefix, 32)
+	f.setString("event_version", e.EventVersion)
+	f.setString("event_time", e.EventTime)
+	f.setString("event_source", e.EventSource)
+	f.setString("event_service", strings.TrimSuffix(e.EventSource, ".amazonaws.com"))
+	f.setString("event_name", e.EventName)
+	f.setString("event_type", e.EventType)
+	f.setString("event_category", e.EventCategory)
+	f.setString("event_id", e.EventID)
+	f.setString("aws_region", e.AWSRegion)
+	f.setString("source_ip_address", e.SourceIPAddress)
+	f.setString("user_agent", e.UserAgent)
+	f.setString("error_code", e.ErrorCode)
+	f.setString("error_message", e.ErrorMessage)
+	f.setString("request_id", e.RequestID)
+	f.setString("recipient_account_id", e.RecipientAccountID)
+	f.setString("shared_event_id", e.SharedEventID)
+	f.setString("vpc_endpoint_id", e.VPCEndpointID)
+	if e.ReadOnly != nil {
+		f.attributes[cloudTrailPrefix+"read_only"] = *e.ReadOnly
+	}
+	if e.ManagementEvent != nil {
+		f.attributes[cloudTrailPrefix+"management_event"] = *e.ManagementEvent
+	}
+	if e.TLSDetails != nil {
+		f.setString("tls_version", e.TLSDetails.TLSVersion)
+	}
+
+	id := e.UserIdentity
+	f.setString("user_identity.type", id.Type)
+	f.setString("user_identity.principal_id", id.PrincipalID)
+	f.setString("user_identity.arn", id.ARN)
+	f.setString("user_identity.account_id", id.AccountID)
+	f.setString("user_identity.access_key_id", id.AccessKeyID)
+	f.setString("user_identity.invoked_by", id.InvokedBy)
+	// name of the principal, session issuer is the role of an assumed role session
+	name := id.UserName
+	if id.SessionContext != nil {
+		if issuer := id.SessionContext.SessionIssuer; issuer != nil {
+			f.setString("user_identity.session_issuer.type", issuer.Type)
+			f.setString("user_identity.session_issuer.arn", issuer.ARN)
+			f.setString("user_identity.session_issuer.account_id", issuer.AccountID)
+			f.setString("user_identity.session_issuer.user_name", issuer.UserName)
+			if name == "" {
+				name = issuer.UserName
+			}
+		}
+		if attributes := id.SessionContext.Attributes; attributes != nil {
+			f.setString("user_identity.mfa_authenticated", attributes.MFAAuthenticated)
+		}
+	}
+	if name == "" {
+		name = id.InvokedBy
+	}
+	f.setString("user_identity.name", name)
+
+	var resourceARNs, resourceTypes []string
+	for _, r := range e.Resources {
+		if r.ARN != "" {
+			resourceARNs = append(resourceARNs, r.ARN)
+		}
+		if r.Type != "" {
+			resourceTypes = append(resourceTypes, r.Type)
+		}
+	}
+	setStrings(f, "resource_arns", resourceARNs)
+	setStrings(f, "resource_types", resourceTypes)
+
+	return f.attributes, timestamp, true
+}
+
+// IsCloudTrailLogGroup returns true if the log group has CloudTrail events. Trails log to a log group of any name,
+// so they are recognized by the default log group name of the console or by their log streams.
+func IsCloudTrailLogGroup(logGroup, logStream string) bool {
+	return strings.HasPrefix(logGroup, cloudTrailLogGroupPrefix) || cloudTrailLogStreamRegex.MatchString(logStream)
+}
+
+// IsCloudTrailS3Key returns true if the object is a CloudTrail log file, digest and insights files are not.
+func IsCloudTrailS3Key(key string) bool {
+	return cloudTrailS3KeyRegex.MatchString(key)
+}
@@ -0,0 +1,136 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cloudTrailAssumedRoleEvent = `{"eventVersion":"1.08","userIdentity":{"type":"AssumedRole","principalId":"AROAEXAMPLE:session","arn":"arn:aws:sts::111122223333:assumed-role/Admin/session","accountId":"111122223333","accessKeyId":"ASIAEXAMPLE","sessionContext":{"sessionIssuer":{"type":"Role","principalId":"AROAEXAMPLE","arn":"arn:aws:iam::111122223333:role/Admin","accountId":"111122223333","userName":"Admin"},"webIdFederationData":{},"attributes":{"creationDate":"2024-01-01T00:00:00Z","mfaAuthenticated":"false"}}},"eventTime":"2024-01-01T12:00:00Z","eventSource":"s3.amazonaws.com","eventName":"DeleteBucket","awsRegion":"us-east-1","sourceIPAddress":"1.1.1.1","userAgent":"aws-cli/2.0","errorCode":"AccessDenied","errorMessage":"Access Denied","requestParameters":{"bucketName":"my-bucket","Host":"my-bucket.s3.amazonaws.com"},"responseElements":null,"requestID":"rid","eventID":"eid","readOnly":false,"resources":[{"accountId":"111122223333","type":"AWS::S3::Bucket","ARN":"arn:aws:s3:::my-bucket"}],"eventType":"AwsApiCall","managementEvent":true,"recipientAccountId":"111122223333","eventCategory":"Management","tlsDetails":{"tlsVersion":"TLSv1.3","cipherSuite":"TLS_AES_128_GCM_SHA256","clientProvidedHostHeader":"my-bucket.s3.amazonaws.com"}}`
+	cloudTrailServiceEvent     = `{"eventVersion":"1.08","userIdentity":{"type":"AWSService","invokedBy":"ec2.amazonaws.com"},"eventTime":"2024-01-01T12:00:01Z","eventSource":"sts.amazonaws.com","eventName":"AssumeRole","awsRegion":"us-east-1","sourceIPAddress":"ec2.amazonaws.com","userAgent":"ec2.amazonaws.com","requestID":"rid2","eventID":"eid2","readOnly":true,"eventType":"AwsApiCall","managementEvent":true,"recipientAccountId":"111122223333","sharedEventID":"sid","eventCategory":"Management"}`
+)
+
+func TestCloudTrailParser(t *testing.T) {
+	tests := []struct {
+		desc          string
+		message       string
+		want          map[string]any
+		wantTimestamp int64
+		wantOK        bool
+	}{
+		{
+			desc:    "Assumed role",
+			message: cloudTrailAssumedRoleEvent,
+			want: map[string]any{
+				"cloudtrail.event_version":                           "1.08",
+				"cloudtrail.event_time":                              "2024-01-01T12:00:00Z",
+				"cloudtrail.event_source":                            "s3.amazonaws.com",
+				"cloudtrail.event_service":                           "s3",
+				"cloudtrail.event_name":                              "DeleteBucket",
+				"cloudtrail.event_type":                              "AwsApiCall",
+				"cloudtrail.event_category":                          "Management",
+				"cloudtrail.event_id":                                "eid",
+				"cloudtrail.aws_region":                              "us-east-1",
+				"cloudtrail.source_ip_address":                       "1.1.1.1",
+				"cloudtrail.user_agent":                              "aws-cli/2.0",
+				"cloudtrail.error_code":                              "AccessDenied",
+				"cloudtrail.error_message":                           "Access Denied",
+				"cloudtrail.request_id":                              "rid",
+				"cloudtrail.recipient_account_id":                    "111122223333",
+				"cloudtrail.read_only":                               false,
+				"cloudtrail.management_event":                        true,
+				"cloudtrail.tls_version":                             "TLSv1.3",
+				"cloudtrail.user_identity.type":                      "AssumedRole",
+				"cloudtrail.user_identity.principal_id":              "AROAEXAMPLE:session",
+				"cloudtrail.user_identity.arn":                       "arn:aws:sts::111122223333:assumed-role/Admin/session",
+				"cloudtrail.user_identity.account_id":                "111122223333",
+				"cloudtrail.user_identity.access_key_id":             "ASIAEXAMPLE",
+				"cloudtrail.user_identity.session_issuer.type":       "Role",
+				"cloudtrail.user_identity.session_issuer.arn":        "arn:aws:iam::111122223333:role/Admin",
+				"cloudtrail.user_identity.session_issuer.account_id": "111122223333",
+				"cloudtrail.user_identity.session_issuer.user_name":  "Admin",
+				"cloudtrail.user_identity.mfa_authenticated":         "false",
+				"cloudtrail.user_identity.name":                      "Admin",
+				"cloudtrail.resource_arns":                           []string{"arn:aws:s3:::my-bucket"},
+				"cloudtrail.resource_types":                          []string{"AWS::S3::Bucket"},
+			},
+			wantTimestamp: 1704110400000,
+			wantOK:        true,
+		},
+		{
+			desc:    "AWS service",
+			message: cloudTrailServiceEvent,
+			want: map[string]any{
+				"cloudtrail.event_version":            "1.08",
+				"cloudtrail.event_time":               "2024-01-01T12:00:01Z",
+				"cloudtrail.event_source":             "sts.amazonaws.com",
+				"cloudtrail.event_service":            "sts",
+				"cloudtrail.event_name":               "AssumeRole",
+				"cloudtrail.event_type":               "AwsApiCall",
+				"cloudtrail.event_category":           "Management",
+				"cloudtrail.event_id":                 "eid2",
+				"cloudtrail.aws_region":               "us-east-1",
+				"cloudtrail.source_ip_address":        "ec2.amazonaws.com",
+				"cloudtrail.user_agent":               "ec2.amazonaws.com",
+				"cloudtrail.request_id":               "rid2",
+				"cloudtrail.recipient_account_id":     "111122223333",
+				"cloudtrail.shared_event_id":          "sid",
+				"cloudtrail.read_only":                true,
+				"cloudtrail.management_event":         true,
+				"cloudtrail.user_identity.type":       "AWSService",
+				"cloudtrail.user_identity.invoked_by": "ec2.amazonaws.com",
+				"cloudtrail.user_identity.name":       "ec2.amazonaws.com",
+			},
+			wantTimestamp: 1704110401000,
+			wantOK:        true,
+		},
+		{
+			desc:    "Invalid event time",
+			message: `{"eventVersion":"1.08","eventTime":"yesterday"}`,
+		},
+		{
+			desc:    "Not a CloudTrail event",
+			message: `{"level":"info","msg":"hello"}`,
+		},
+		{
+			desc:    "Not JSON",
+			message: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, timestamp, ok := cloudTrailParser.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTimestamp, timestamp)
+		})
+	}
+}
+
+func TestCloudTrailParserSplit(t *testing.T) {
+	records, ok := cloudTrailParser.Split(`{"Records":[` + cloudTrailAssumedRoleEvent + "," + cloudTrailServiceEvent + `]}`)
+	assert.True(t, ok)
+	assert.Equal(t, []string{cloudTrailAssumedRoleEvent, cloudTrailServiceEvent}, records)
+
+	records, ok = cloudTrailParser.Split(`{"Records":[]}`)
+	assert.True(t, ok)
+	assert.Empty(t, records)
+
+	_, ok = cloudTrailParser.Split(cloudTrailAssumedRoleEvent)
+	assert.False(t, ok)
+	_, ok = cloudTrailParser.Split(`{"Records":[`)
+	assert.False(t, ok)
+}
+
+func TestIsCloudTrailLog(t *testing.T) {
+	assert.True(t, IsCloudTrailLogGroup("aws-cloudtrail-logs-111122223333-1a2b3c4d", "stream"))
+	assert.True(t, IsCloudTrailLogGroup("my-trail-logs", "111122223333_CloudTrail_us-east-1"))
+	assert.True(t, IsCloudTrailLogGroup("my-trail-logs", "o-exampleorgid_111122223333_CloudTrail_us-east-1_2"))
+	assert.False(t, IsCloudTrailLogGroup("/aws/lambda/my-function", "2024/01/01/[$LATEST]abc"))
+	assert.True(t, IsCloudTrailS3Key("AWSLogs/111122223333/CloudTrail/us-east-1/2024/01/01/111122223333_CloudTrail_us-east-1_20240101T0000Z_abc.json.gz"))
+	assert.True(t, IsCloudTrailS3Key("prefix/AWSLogs/o-exampleorgid/111122223333/CloudTrail/us-east-1/2024/01/01/111122223333_CloudTrail_us-east-1_20240101T0000Z_abc.json.gz"))
+	assert.False(t, IsCloudTrailS3Key("AWSLogs/111122223333/CloudTrail-Digest/us-east-1/2024/01/01/111122223333_CloudTrail-Digest_us-east-1_trail_us-east-1_20240101T000000Z.json.gz"))
+	assert.False(t, IsCloudTrailS3Key("logs/app.log"))
+}
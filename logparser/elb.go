@@ -0,0 +1,165 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	albPrefix = "alb."
+	nlbPrefix = "nlb."
+	// nlbLogType is the type of NLB TLS access log records, ALB records have request protocol as type
+	nlbLogType = "tls"
+	// minALBFields is the number of fields of the oldest ALB access log format
+	minALBFields = 17
+	minNLBFields = 20
+)
+
+var (
+	// elbS3KeyRegex matches access log objects of application and network load balancers:
+	// [prefix/]AWSLogs/{account_id}/elasticloadbalancing/{region}/yyyy/mm/dd/{account_id}_elasticloadbalancing_{region}_{app|net}.{name}.{id}_...
+	elbS3KeyRegex = regexp.MustCompile(`(^|/)AWSLogs/\d{12}/elasticloadbalancing/[a-z0-9-]+/\d{4}/\d{2}/\d{2}/\d{12}_elasticloadbalancing_[a-z0-9-]+_(app|net)\.[^/]+$`)
+
+	elbParser = &ELBParser{}
+)
+
+// ELBParser parses access logs of application load balancers and TLS access logs of network load balancers.
+// Fields added to the formats later are optional, so records of older formats are parsed too.
+type ELBParser struct{}
+
+// Parse returns ALB fields with "alb." prefix and NLB fields with "nlb." prefix, fields without value ("-") are omitted.
+// Timestamp is the time of the record, i.e. when ALB sends the response.
+func (p *ELBParser) Parse(message string) (map[string]any, int64, bool) {
+	fields, ok := splitQuoted(message)
+	if !ok || len(fields) == 0 {
+		return nil, 0, false
+	}
+	if fields[0] == nlbLogType {
+		return parseNLB(fields)
+	}
+	return parseALB(fields)
+}
+
+// parseALB parses fields of https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html
+func parseALB(fields []string) (map[string]any, int64, bool) {
+	if len(fields) < minALBFields {
+		return nil, 0, false
+	}
+	timestamp, ok := parseRFC3339Millis(fields[1])
+	if !ok {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(albPrefix, len(fields)+4)
+	setters := []func(v string){
+		func(v string) { f.setString("type", v) },
+		func(v string) { f.setString("time", v) },
+		func(v string) { f.setString("elb", v) },
+		func(v string) { f.setHostPort("client", v) },
+		func(v string) { f.setHostPort("target", v) },
+		func(v string) { f.setFloat("request_processing_time", v) },
+		func(v string) { f.setFloat("target_processing_time", v) },
+		func(v string) { f.setFloat("response_processing_time", v) },
+		func(v string) { f.setInt("elb_status_code", v) },
+		func(v string) { f.setInt("target_status_code", v) },
+		func(v string) { f.setInt("received_bytes", v) },
+		func(v string) { f.setInt("sent_bytes", v) },
+		func(v string) { setALBRequest(f, v) },
+		func(v string) { f.setString("user_agent", v) },
+		func(v string) { f.setString("ssl_cipher", v) },
+		func(v string) { f.setString("ssl_protocol", v) },
+		func(v string) { f.setString("target_group_arn", v) },
+		func(v string) { f.setString("trace_id", v) },
+		func(v string) { f.setString("domain_name", v) },
+		func(v string) { f.setString("chosen_cert_arn", v) },
+		func(v string) { f.setInt("matched_rule_priority", v) },
+		func(v string) { f.setString("request_creation_time", v) },
+		func(v string) { f.setList("actions_executed", v, ",") },
+		func(v string) { f.setString("redirect_url", v) },
+		func(v string) { f.setString("error_reason", v) },
+		func(v string) { f.setList("target_port_list", v, " ") },
+		func(v string) { f.setList("target_status_code_list", v, " ") },
+		func(v string) { f.setString("classification", v) },
+		func(v string) { f.setString("classification_reason", v) },
+		func(v string) { f.setString("conn_trace_id", v) },
+	}
+	// fields added after conn_trace_id are ignored until they are known
+	for i := 0; i < len(setters) && i < len(fields); i++ {
+		setters[i](fields[i])
+	}
+	if f.err != nil {
+		return nil, 0, false
+	}
+	return f.attributes, timestamp, true
+}
+
+// setALBRequest sets the request line, i.e. "GET http://example.com:80/ HTTP/1.1", and its method, URL and protocol.
+// Requests which could not be parsed are logged as "- - -".
+func setALBRequest(f *fieldSetter, v string) {
+	parts := strings.SplitN(v, " ", 3)
+	if len(parts) != 3 || isEmpty(parts[0]) {
+		return
+	}
+	f.setString("request", v)
+	f.setString("request_method", parts[0])
+	f.setString("request_url", parts[1])
+	f.setString("request_protocol", parts[2])
+}
+
+// parseNLB parses fields of https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-access-logs.html
+func parseNLB(fields []string) (map[string]any, int64, bool) {
+	if len(fields) < minNLBFields {
+		return nil, 0, false
+	}
+	timestamp, ok := parseRFC3339Millis(fields[2])
+	if !ok {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(nlbPrefix, len(fields)+2)
+	setters := []func(v string){
+		func(v string) { f.setString("type", v) },
+		func(v string) { f.setString("version", v) },
+		func(v string) { f.setString("time", v) },
+		func(v string) { f.setString("elb", v) },
+		func(v string) { f.setString("listener", v) },
+		func(v string) { f.setHostPort("client", v) },
+		func(v string) { f.setHostPort("destination", v) },
+		func(v string) { f.setInt("connection_time", v) },
+		func(v string) { f.setInt("tls_handshake_time", v) },
+		func(v string) { f.setInt("received_bytes", v) },
+		func(v string) { f.setInt("sent_bytes", v) },
+		func(v string) { f.setString("incoming_tls_alert", v) },
+		func(v string) { f.setString("chosen_cert_arn", v) },
+		func(v string) { f.setString("chosen_cert_serial", v) },
+		func(v string) { f.setString("tls_cipher", v) },
+		func(v string) { f.setString("tls_protocol_version", v) },
+		func(v string) { f.setString("tls_named_group", v) },
+		func(v string) { f.setString("domain_name", v) },
+		func(v string) { f.setString("alpn_fe_protocol", v) },
+		func(v string) { f.setString("alpn_be_protocol", v) },
+		func(v string) { f.setQuotedList("alpn_client_preference_list", v) },
+		func(v string) { f.setString("tls_connection_creation_time", v) },
+	}
+	for i := 0; i < len(setters) && i < len(fields); i++ {
+		setters[i](fields[i])
+	}
+	if f.err != nil {
+		return nil, 0, false
+	}
+	return f.attributes, timestamp, true
+}
+
+func parseRFC3339Millis(v string) (int64, bool) {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return 0, false
+	}
+	return t.UnixMilli(), true
+}
+
+// IsELBS3Key returns true if the object is an access log of an application or network load balancer.
+func IsELBS3Key(key string) bool {
+	return elbS3KeyRegex.MatchString(key)
+}
@@ -0,0 +1,174 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestELBParser(t *testing.T) {
+	tests := []struct {
+		desc          string
+		message       string
+		want          map[string]any
+		wantTimestamp int64
+		wantOK        bool
+	}{
+		{
+			desc:    "ALB HTTPS request",
+			message: `https 2024-01-01T00:00:00.123456Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/path?a=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2024-01-01T00:00:00.000000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "Acceptable" "-" TID_1234abcd5678ef90`,
+			want: map[string]any{
+				"alb.type":                     "https",
+				"alb.time":                     "2024-01-01T00:00:00.123456Z",
+				"alb.elb":                      "app/my-loadbalancer/50dc6c495c0c9188",
+				"alb.client_ip":                "192.168.131.39",
+				"alb.client_port":              int64(2817),
+				"alb.target_ip":                "10.0.0.1",
+				"alb.target_port":              int64(80),
+				"alb.request_processing_time":  0.086,
+				"alb.target_processing_time":   0.048,
+				"alb.response_processing_time": 0.037,
+				"alb.elb_status_code":          int64(200),
+				"alb.target_status_code":       int64(200),
+				"alb.received_bytes":           int64(0),
+				"alb.sent_bytes":               int64(57),
+				"alb.request":                  "GET https://www.example.com:443/path?a=1 HTTP/1.1",
+				"alb.request_method":           "GET",
+				"alb.request_url":              "https://www.example.com:443/path?a=1",
+				"alb.request_protocol":         "HTTP/1.1",
+				"alb.user_agent":               "curl/7.46.0",
+				"alb.ssl_cipher":               "ECDHE-RSA-AES128-GCM-SHA256",
+				"alb.ssl_protocol":             "TLSv1.2",
+				"alb.target_group_arn":         "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067",
+				"alb.trace_id":                 "Root=1-58337281-1d84f3d73c47ec4e58577259",
+				"alb.domain_name":              "www.example.com",
+				"alb.chosen_cert_arn":          "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012",
+				"alb.matched_rule_priority":    int64(1),
+				"alb.request_creation_time":    "2024-01-01T00:00:00.000000Z",
+				"alb.actions_executed":         []string{"authenticate", "forward"},
+				"alb.target_port_list":         []string{"10.0.0.1:80"},
+				"alb.target_status_code_list":  []string{"200"},
+				"alb.classification":           "Acceptable",
+				"alb.conn_trace_id":            "TID_1234abcd5678ef90",
+			},
+			wantTimestamp: 1704067200123,
+			wantOK:        true,
+		},
+		{
+			desc:    "ALB request not dispatched to a target",
+			message: `http 2024-01-01T00:00:00.000000Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 400 - 0 0 "- - - " "-" - - - "-" "-" "-" - 2024-01-01T00:00:00.000000Z "-" "-" "-" "-" "-" "Ambiguous" "UndefinedContentLengthSemantics" "-"`,
+			want: map[string]any{
+				"alb.type":                     "http",
+				"alb.time":                     "2024-01-01T00:00:00.000000Z",
+				"alb.elb":                      "app/my-loadbalancer/50dc6c495c0c9188",
+				"alb.client_ip":                "192.168.131.39",
+				"alb.client_port":              int64(2817),
+				"alb.request_processing_time":  float64(-1),
+				"alb.target_processing_time":   float64(-1),
+				"alb.response_processing_time": float64(-1),
+				"alb.elb_status_code":          int64(400),
+				"alb.received_bytes":           int64(0),
+				"alb.sent_bytes":               int64(0),
+				"alb.request_creation_time":    "2024-01-01T00:00:00.000000Z",
+				"alb.classification":           "Ambiguous",
+				"alb.classification_reason":    "UndefinedContentLengthSemantics",
+			},
+			wantTimestamp: 1704067200000,
+			wantOK:        true,
+		},
+		{
+			desc:    "ALB request with escaped user agent in an older format",
+			message: `h2 2024-01-01T00:00:01.000000Z app/my-loadbalancer/50dc6c495c0c9188 [2001:db8::1]:443 - 0.001 0.002 0.003 200 200 10 20 "GET https://example.com:443/ HTTP/2.0" "agent \"quoted\"" - - -`,
+			want: map[string]any{
+				"alb.type":                     "h2",
+				"alb.time":                     "2024-01-01T00:00:01.000000Z",
+				"alb.elb":                      "app/my-loadbalancer/50dc6c495c0c9188",
+				"alb.client_ip":                "[2001:db8::1]",
+				"alb.client_port":              int64(443),
+				"alb.request_processing_time":  0.001,
+				"alb.target_processing_time":   0.002,
+				"alb.response_processing_time": 0.003,
+				"alb.elb_status_code":          int64(200),
+				"alb.target_status_code":       int64(200),
+				"alb.received_bytes":           int64(10),
+				"alb.sent_bytes":               int64(20),
+				"alb.request":                  "GET https://example.com:443/ HTTP/2.0",
+				"alb.request_method":           "GET",
+				"alb.request_url":              "https://example.com:443/",
+				"alb.request_protocol":         "HTTP/2.0",
+				"alb.user_agent":               `agent "quoted"`,
+			},
+			wantTimestamp: 1704067201000,
+			wantOK:        true,
+		},
+		{
+			desc:    "NLB TLS",
+			message: `tls 2.0 2024-01-01T00:00:00Z net/my-network-loadbalancer/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246 - arn:aws:acm:us-east-2:123456789012:certificate/abc 0f9c1a3d1234 ECDHE-RSA-AES128-SHA tlsv12 - my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com h2 h2 "h2","http/1.1" 2024-01-01T00:00:00Z`,
+			want: map[string]any{
+				"nlb.type":                         "tls",
+				"nlb.version":                      "2.0",
+				"nlb.time":                         "2024-01-01T00:00:00Z",
+				"nlb.elb":                          "net/my-network-loadbalancer/c6e77e28c25b2234",
+				"nlb.listener":                     "g3d4b5e8bb8464cd",
+				"nlb.client_ip":                    "72.21.218.154",
+				"nlb.client_port":                  int64(51341),
+				"nlb.destination_ip":               "172.100.100.185",
+				"nlb.destination_port":             int64(443),
+				"nlb.connection_time":              int64(5),
+				"nlb.tls_handshake_time":           int64(2),
+				"nlb.received_bytes":               int64(98),
+				"nlb.sent_bytes":                   int64(246),
+				"nlb.chosen_cert_arn":              "arn:aws:acm:us-east-2:123456789012:certificate/abc",
+				"nlb.chosen_cert_serial":           "0f9c1a3d1234",
+				"nlb.tls_cipher":                   "ECDHE-RSA-AES128-SHA",
+				"nlb.tls_protocol_version":         "tlsv12",
+				"nlb.domain_name":                  "my-network-loadbalancer-c6e77e28c25b2234.elb.us-east-2.amazonaws.com",
+				"nlb.alpn_fe_protocol":             "h2",
+				"nlb.alpn_be_protocol":             "h2",
+				"nlb.alpn_client_preference_list":  []string{"h2", "http/1.1"},
+				"nlb.tls_connection_creation_time": "2024-01-01T00:00:00Z",
+			},
+			wantTimestamp: 1704067200000,
+			wantOK:        true,
+		},
+		{
+			desc:    "Not enough fields",
+			message: "https 2024-01-01T00:00:00.123456Z app/my-loadbalancer/50dc6c495c0c9188",
+		},
+		{
+			desc:    "Invalid number",
+			message: `http 2024-01-01T00:00:00.000000Z app/lb/1 1.1.1.1:1 - fast -1 -1 400 - 0 0 "- - - " "-" - - -`,
+		},
+		{
+			desc:    "Quote is not closed",
+			message: `http 2024-01-01T00:00:00.000000Z app/lb/1 1.1.1.1:1 - -1 -1 -1 400 - 0 0 "GET / HTTP/1.1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, timestamp, ok := elbParser.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTimestamp, timestamp)
+		})
+	}
+}
+
+func TestIsELBS3Key(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"AWSLogs/123456789012/elasticloadbalancing/us-east-2/2024/01/01/123456789012_elasticloadbalancing_us-east-2_app.my-loadbalancer.50dc6c495c0c9188_20240101T0000Z_192.168.131.39_2hiyxfm2.log.gz", true},
+		{"prefix/AWSLogs/123456789012/elasticloadbalancing/us-east-2/2024/01/01/123456789012_elasticloadbalancing_us-east-2_net.my-nlb.c6e77e28c25b2234_20240101T0000Z_1a2b3c4d.log.gz", true},
+		{"AWSLogs/123456789012/vpcflowlogs/us-east-2/2024/01/01/123456789012_vpcflowlogs_us-east-2_fl-1234abcd_20240101T0000Z_1a2b3c4d.log.gz", false},
+		{"logs/app.log", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsELBS3Key(tt.key))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package logparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// noValue is the value of fields which are not applicable to a record in AWS access logs
+	noValue = "-"
+)
+
+// splitQuoted splits a space separated record whose fields may be double quoted or bracketed, quotes and brackets are
+// removed and backslash escaped characters in quoted fields are unescaped. It returns false if a quote or bracket is not closed.
+// Fields which are not only a quoted or bracketed value, i.e. a list of quoted values ("h2","http/1.1") or an IPv6 address
+// with port ([2001:db8::1]:443), are returned as is.
+func splitQuoted(s string) ([]string, bool) {
+	var fields []string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return fields, true
+		}
+		var field string
+		var i int
+		switch s[0] {
+		case '"':
+			field, i = unquote(s)
+		case '[':
+			i = strings.IndexByte(s, ']')
+			if i > 0 {
+				field = s[1:i]
+			}
+		default:
+			field, s, _ = strings.Cut(s, " ")
+			fields = append(fields, field)
+			continue
+		}
+		if i < 0 {
+			return nil, false
+		}
+		if i+1 < len(s) && s[i+1] != ' ' {
+			field, s, _ = strings.Cut(s, " ")
+			fields = append(fields, field)
+			continue
+		}
+		fields, s = append(fields, field), s[i+1:]
+	}
+}
+
+// unquote returns the unescaped value of the quoted field at the start of s and the index of its closing quote,
+// index is -1 if the quote is not closed.
+func unquote(s string) (string, int) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			b.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		if s[i] == '"' {
+			return b.String(), i
+		}
+		b.WriteByte(s[i])
+	}
+	return "", -1
+}
+
+// fieldSetter sets typed attributes with a prefix, values which are empty or "-" are omitted.
+// Parse errors of numeric values are kept, so records in an unexpected format can be rejected.
+type fieldSetter struct {
+	prefix     string
+	attributes map[string]any
+	err        error
+}
+
+func newFieldSetter(prefix string, size int) *fieldSetter {
+	return &fieldSetter{prefix: prefix, attributes: make(map[string]any, size)}
+}
+
+func isEmpty(v string) bool {
+	return v == "" || v == noValue
+}
+
+func (f *fieldSetter) setString(key, v string) {
+	if !isEmpty(v) {
+		f.attributes[f.prefix+key] = v
+	}
+}
+
+func (f *fieldSetter) setInt(key, v string) {
+	if isEmpty(v) {
+		return
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		f.err = err
+		return
+	}
+	f.attributes[f.prefix+key] = n
+}
+
+func (f *fieldSetter) setFloat(key, v string) {
+	if isEmpty(v) {
+		return
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		f.err = err
+		return
+	}
+	f.attributes[f.prefix+key] = n
+}
+
+// setList sets a separated list, i.e. "forward,redirect".
+func (f *fieldSetter) setList(key, v, sep string) {
+	if !isEmpty(v) {
+		f.attributes[f.prefix+key] = strings.Split(v, sep)
+	}
+}
+
+// setQuotedList sets a comma separated list of quoted values, i.e. "h2","http/1.1".
+func (f *fieldSetter) setQuotedList(key, v string) {
+	if isEmpty(v) {
+		return
+	}
+	values := strings.Split(v, ",")
+	for i, value := range values {
+		values[i] = strings.Trim(value, `"`)
+	}
+	f.attributes[f.prefix+key] = values
+}
+
+// setHostPort sets "<key>_ip" and "<key>_port" of an "ip:port" value, IPv6 addresses are not bracketed in access logs.
+func (f *fieldSetter) setHostPort(key, v string) {
+	if isEmpty(v) {
+		return
+	}
+	i := strings.LastIndexByte(v, ':')
+	if i < 0 {
+		f.setString(key+"_ip", v)
+		return
+	}
+	f.setString(key+"_ip", v[:i])
+	f.setInt(key+"_port", v[i+1:])
+}
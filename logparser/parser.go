@@ -0,0 +1,120 @@
+// Package logparser parses logs of AWS services, which are delivered as plain text, into structured attributes.
+package logparser
+
+import (
+	"log"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+)
+
+// Parser returns attributes and timestamp (epoch milliseconds, 0 if message has no timestamp) of a log message,
+// false if the message is not in the format of the parser.
+type Parser interface {
+	Parse(message string) (map[string]any, int64, bool)
+}
+
+// Splitter is implemented by parsers of logs whose messages may have multiple records, i.e. CloudTrail log files.
+type Splitter interface {
+	// Split returns the records of the message, false if the message is a single record.
+	Split(message string) ([]string, bool)
+}
+
+// Filter is implemented by parsers of logs which have lines that are not records, i.e. headers of CloudFront standard log files.
+type Filter interface {
+	// Skip returns true if the message is not a record and should not be forwarded. It is called after Parse of the message,
+	// so headers are still read by the parser.
+	Skip(message string) bool
+}
+
+// Registry finds the parser of logs by their source.
+type Registry struct {
+	flowLogParsers           map[string]*FlowLogParser
+	cloudFrontRealtimeParser *CloudFrontParser
+}
+
+func NewRegistry(conf *cfg.Config) *Registry {
+	return &Registry{
+		flowLogParsers:           prepareFlowLogParsers(conf.VPCFlowLogFormats),
+		cloudFrontRealtimeParser: NewCloudFrontRealtimeParser(conf.CloudFrontRealtimeLogFields),
+	}
+}
+
+// ForLogGroup returns the parser of the logs of a log group, nil if they are not parsed.
+func (r *Registry) ForLogGroup(logGroup, logStream string) Parser {
+	if IsWAFLogGroup(logGroup) {
+		return wafParser
+	}
+	if IsCloudTrailLogGroup(logGroup, logStream) {
+		return cloudTrailParser
+	}
+	if p, ok := r.flowLogParsers[logGroup]; ok {
+		return p
+	}
+	if IsFlowLogGroup(logGroup, logStream) {
+		return defaultFlowLogParser
+	}
+	if IsRoute53LogGroup(logGroup, logStream) {
+		return route53Parser
+	}
+	return nil
+}
+
+// ForS3Object returns the parser of the logs in an S3 object, nil if they are not parsed.
+// Parser may keep the header of the object, so it should not be shared with other objects.
+func (r *Registry) ForS3Object(key string) Parser {
+	if IsELBS3Key(key) {
+		return elbParser
+	}
+	if IsCloudFrontS3Key(key) {
+		return NewCloudFrontParser(DefaultCloudFrontFields)
+	}
+	if IsS3AccessLogKey(key) {
+		return s3AccessParser
+	}
+	if IsWAFS3Key(key) {
+		return wafParser
+	}
+	if IsCloudTrailS3Key(key) {
+		return cloudTrailParser
+	}
+	if IsRoute53S3Key(key) {
+		return route53Parser
+	}
+	return nil
+}
+
+// ForFirehoseDeliveryStream returns the parser of the records of a delivery stream, nil if they are not parsed.
+func (r *Registry) ForFirehoseDeliveryStream(deliveryStreamARN string) Parser {
+	if IsWAFDeliveryStream(deliveryStreamARN) {
+		return wafParser
+	}
+	return nil
+}
+
+// ForCloudFrontRealtime returns the parser of CloudFront real-time logs delivered through a Kinesis stream.
+func (r *Registry) ForCloudFrontRealtime() Parser {
+	return r.cloudFrontRealtimeParser
+}
+
+// prepareFlowLogParsers parses comma separated "<log_group>=<format>" pairs, invalid pairs are logged and skipped.
+func prepareFlowLogParsers(formats string) map[string]*FlowLogParser {
+	if formats == "" {
+		return nil
+	}
+	parsers := make(map[string]*FlowLogParser)
+	for _, p := range strings.Split(formats, ",") {
+		logGroup, format, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			log.Printf("Invalid VPC flow log format: %s, expected <log_group>=<format>", p)
+			continue
+		}
+		parser, err := NewFlowLogParser(format)
+		if err != nil {
+			log.Printf("Invalid VPC flow log format of log group: %s, err: %v", logGroup, err)
+			continue
+		}
+		parsers[strings.TrimSpace(logGroup)] = parser
+	}
+	return parsers
+}
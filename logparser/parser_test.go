@@ -0,0 +1,70 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryForLogGroup(t *testing.T) {
+	custom := "${version} ${vpc-id} ${srcaddr}"
+	r := NewRegistry(&cfg.Config{VPCFlowLogFormats: "my-vpc-logs=" + custom + ", invalid"})
+
+	tests := []struct {
+		desc      string
+		logGroup  string
+		logStream string
+		want      Parser
+	}{
+		{desc: "WAF log group", logGroup: "aws-waf-logs-my-acl", logStream: "us-east-1_my-acl_0", want: wafParser},
+		{desc: "CloudTrail log group", logGroup: "aws-cloudtrail-logs-111122223333-1a2b3c4d", logStream: "111122223333_CloudTrail_us-east-1", want: cloudTrailParser},
+		{desc: "CloudTrail log stream", logGroup: "/aws/cloudtrail/my-trail", logStream: "111122223333_CloudTrail_us-east-1", want: cloudTrailParser},
+		{desc: "Configured log group", logGroup: "my-vpc-logs", logStream: "stream", want: &FlowLogParser{fields: []string{"version", "vpc-id", "srcaddr"}}},
+		{desc: "VPC log group", logGroup: "/ec2/vpc/vpc-12345678", want: defaultFlowLogParser},
+		{desc: "Flow log in log group name", logGroup: "prod-VPC-Flow-Logs", want: defaultFlowLogParser},
+		{desc: "Network interface log stream", logGroup: "network", logStream: "eni-0123456789abcdef0-all", want: defaultFlowLogParser},
+		{desc: "Route 53 log group", logGroup: "/aws/route53/example.com", logStream: "Z123412341234/FRA6", want: route53Parser},
+		{desc: "Other log group", logGroup: "/aws/lambda/my-function", logStream: "2024/01/01/[$LATEST]abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := r.ForLogGroup(tt.logGroup, tt.logStream)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegistryForS3Object(t *testing.T) {
+	r := NewRegistry(&cfg.Config{})
+
+	tests := []struct {
+		desc string
+		key  string
+		want Parser
+	}{
+		{desc: "ALB access log", key: "AWSLogs/123456789012/elasticloadbalancing/us-east-2/2024/01/01/123456789012_elasticloadbalancing_us-east-2_app.my-loadbalancer.50dc6c495c0c9188_20240101T0000Z_192.168.131.39_2hiyxfm2.log.gz", want: elbParser},
+		{desc: "CloudFront standard log", key: "cloudfront/EMLARXS9EXAMPLE.2019-11-14-20.RT4KCN4SGK9.gz", want: NewCloudFrontParser(DefaultCloudFrontFields)},
+		{desc: "S3 server access log", key: "logs/2019-02-06-00-00-38-ABCDEF0123456789", want: s3AccessParser},
+		{desc: "WAF log", key: "AWSLogs/111122223333/WAFLogs/us-east-1/my-acl/2024/01/01/00/00/111122223333_waflogs_us-east-1_my-acl_20240101T0000Z_abc.log.gz", want: wafParser},
+		{desc: "CloudTrail log", key: "AWSLogs/111122223333/CloudTrail/us-east-1/2024/01/01/111122223333_CloudTrail_us-east-1_20240101T0000Z_abc.json.gz", want: cloudTrailParser},
+		{desc: "Route 53 Resolver query log", key: "AWSLogs/111122223333/vpcdnsquerylogs/vpc-0123456789abcdef0/2024/01/01/111122223333_vpcdnsquerylogs_vpc-0123456789abcdef0_20240101T0000Z_abc.log.gz", want: route53Parser},
+		{desc: "Other object", key: "logs/app.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := r.ForS3Object(tt.key)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package logparser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-forwarder/parser"
+)
+
+const (
+	route53Prefix = "route53."
+	// route53LogGroupPrefix is the prefix of log groups created by the console for public DNS query logs (/aws/route53/{domain}),
+	// resolver query logs are usually logged to /aws/route53resolver/... log groups
+	route53LogGroupPrefix = "/aws/route53"
+	// route53PublicFields is the number of fields of public DNS query logs (version 1.0)
+	route53PublicFields = 10
+)
+
+var (
+	// resolver query log objects are delivered to [prefix/]AWSLogs/{account_id}/vpcdnsquerylogs/{vpc_id}/yyyy/mm/dd/...
+	route53ResolverS3KeyRegex = regexp.MustCompile(`(^|/)AWSLogs/\d{12}/vpcdnsquerylogs/vpc-[0-9a-f]+/`)
+
+	route53Parser = &Route53Parser{}
+)
+
+type route53ResolverLog struct {
+	Version        string `json:"version"`
+	AccountID      string `json:"account_id"`
+	Region         string `json:"region"`
+	VPCID          string `json:"vpc_id"`
+	QueryTimestamp string `json:"query_timestamp"`
+	QueryName      string `json:"query_name"`
+	QueryType      string `json:"query_type"`
+	QueryClass     string `json:"query_class"`
+	Rcode          string `json:"rcode"`
+	Answers        []struct {
+		Rdata string `json:"Rdata"`
+		Type  string `json:"Type"`
+		Class string `json:"Class"`
+	} `json:"answers"`
+	SrcAddr   string      `json:"srcaddr"`
+	SrcPort   json.Number `json:"srcport"`
+	Transport string      `json:"transport"`
+	SrcIDs    struct {
+		Instance         string `json:"instance"`
+		ResolverEndpoint string `json:"resolver_endpoint"`
+	} `json:"srcids"`
+	FirewallRuleAction   string `json:"firewall_rule_action"`
+	FirewallRuleGroupID  string `json:"firewall_rule_group_id"`
+	FirewallDomainListID string `json:"firewall_domain_list_id"`
+}
+
+// Route53Parser parses Route 53 Resolver query logs, which are JSON, and public DNS query logs of hosted zones,
+// which are space separated.
+type Route53Parser struct{}
+
+// Parse returns fields of a DNS query log with "route53." prefix. Answers and source IDs of resolver query logs are
+// flattened and fields without value ("-") of public DNS query logs are omitted. Timestamp is the time of the query.
+func (p *Route53Parser) Parse(message string) (map[string]any, int64, bool) {
+	if strings.HasPrefix(message, "{") {
+		return p.parseResolver(message)
+	}
+	return p.parsePublic(message)
+}
+
+// parseResolver parses https://docs.aws.amazon.com/Route53/latest/DeveloperGuide/resolver-query-logs-format.html.
+func (p *Route53Parser) parseResolver(message string) (map[string]any, int64, bool) {
+	var l route53ResolverLog
+	if err := json.Unmarshal([]byte(message), &l); err != nil || l.QueryName == "" {
+		return nil, 0, false
+	}
+	timestamp, ok := parseRFC3339Millis(l.QueryTimestamp)
+	if !ok {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(route53Prefix, 24)
+	f.setString("version", l.Version)
+	f.setString("account_id", l.AccountID)
+	f.setString("region", l.Region)
+	f.setString("vpc_id", l.VPCID)
+	f.setString("query_timestamp", l.QueryTimestamp)
+	f.setString("query_name", l.QueryName)
+	f.setString("query_type", l.QueryType)
+	f.setString("query_class", l.QueryClass)
+	f.setString("rcode", l.Rcode)
+	f.setString("srcaddr", l.SrcAddr)
+	f.setInt("srcport", l.SrcPort.String())
+	f.setString("transport", l.Transport)
+	f.setString("srcids.instance", l.SrcIDs.Instance)
+	f.setString("srcids.resolver_endpoint", l.SrcIDs.ResolverEndpoint)
+	f.setString("firewall_rule_action", l.FirewallRuleAction)
+	f.setString("firewall_rule_group_id", l.FirewallRuleGroupID)
+	f.setString("firewall_domain_list_id", l.FirewallDomainListID)
+
+	var answers, answerTypes []string
+	for _, a := range l.Answers {
+		answers = append(answers, a.Rdata)
+		answerTypes = append(answerTypes, a.Type)
+	}
+	setStrings(f, "answers", answers)
+	setStrings(f, "answer_types", answerTypes)
+
+	if f.err != nil {
+		return nil, 0, false
+	}
+	return f.attributes, timestamp, true
+}
+
+// parsePublic parses https://docs.aws.amazon.com/Route53/latest/DeveloperGuide/query-logs.html#query-logs-format.
+func (p *Route53Parser) parsePublic(message string) (map[string]any, int64, bool) {
+	fields := strings.Fields(message)
+	if len(fields) != route53PublicFields {
+		return nil, 0, false
+	}
+	timestamp, ok := parseRFC3339Millis(fields[1])
+	if !ok {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(route53Prefix, len(fields))
+	f.setString("version", fields[0])
+	f.setString("query_timestamp", fields[1])
+	f.setString("hosted_zone_id", fields[2])
+	f.setString("query_name", fields[3])
+	f.setString("query_type", fields[4])
+	f.setString("rcode", fields[5])
+	f.setString("protocol", fields[6])
+	f.setString("edge_location", fields[7])
+	f.setString("resolver_ip", fields[8])
+	f.setString("edns_client_subnet", fields[9])
+	return f.attributes, timestamp, true
+}
+
+// IsRoute53LogGroup returns true if the log group has DNS query logs. Query logs have no fixed log group name,
+// so they are recognized by the log group prefix of the console or by log streams of public hosted zones and
+// resolver query logging configurations.
+func IsRoute53LogGroup(logGroup, logStream string) bool {
+	return strings.HasPrefix(logGroup, route53LogGroupPrefix) ||
+		parser.Route53PublicLogStreamRegex.MatchString(logStream) ||
+		parser.Route53ResolverConfigIDRegex.MatchString(logStream)
+}
+
+// IsRoute53S3Key returns true if the object is a resolver query log delivered to S3.
+func IsRoute53S3Key(key string) bool {
+	return route53ResolverS3KeyRegex.MatchString(key)
+}
@@ -0,0 +1,134 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute53Parser(t *testing.T) {
+	tests := []struct {
+		desc          string
+		message       string
+		want          map[string]any
+		wantTimestamp int64
+		wantOK        bool
+	}{
+		{
+			desc:    "Resolver query from instance",
+			message: `{"version":"1.100000","account_id":"111122223333","region":"us-east-1","vpc_id":"vpc-0123456789abcdef0","query_timestamp":"2024-01-01T12:00:00Z","query_name":"example.com.","query_type":"A","query_class":"IN","rcode":"NOERROR","answers":[{"Rdata":"93.184.216.34","Type":"A","Class":"IN"},{"Rdata":"93.184.216.35","Type":"A","Class":"IN"}],"srcaddr":"10.0.0.10","srcport":"45938","transport":"UDP","srcids":{"instance":"i-0123456789abcdef0"}}`,
+			want: map[string]any{
+				"route53.version":         "1.100000",
+				"route53.account_id":      "111122223333",
+				"route53.region":          "us-east-1",
+				"route53.vpc_id":          "vpc-0123456789abcdef0",
+				"route53.query_timestamp": "2024-01-01T12:00:00Z",
+				"route53.query_name":      "example.com.",
+				"route53.query_type":      "A",
+				"route53.query_class":     "IN",
+				"route53.rcode":           "NOERROR",
+				"route53.answers":         []string{"93.184.216.34", "93.184.216.35"},
+				"route53.answer_types":    []string{"A", "A"},
+				"route53.srcaddr":         "10.0.0.10",
+				"route53.srcport":         int64(45938),
+				"route53.transport":       "UDP",
+				"route53.srcids.instance": "i-0123456789abcdef0",
+			},
+			wantTimestamp: 1704110400000,
+			wantOK:        true,
+		},
+		{
+			desc:    "Resolver query blocked by firewall",
+			message: `{"version":"1.100000","account_id":"111122223333","region":"us-east-1","vpc_id":"vpc-0123456789abcdef0","query_timestamp":"2024-01-01T12:00:00.5Z","query_name":"malware.example.","query_type":"AAAA","query_class":"IN","rcode":"NXDOMAIN","answers":[],"srcaddr":"10.0.1.5","srcport":53,"transport":"TCP","srcids":{"resolver_endpoint":"rslvr-in-0123456789abcdef0"},"firewall_rule_action":"BLOCK","firewall_rule_group_id":"rslvr-frg-0123456789abcdef","firewall_domain_list_id":"rslvr-fdl-0123456789abcdef"}`,
+			want: map[string]any{
+				"route53.version":                  "1.100000",
+				"route53.account_id":               "111122223333",
+				"route53.region":                   "us-east-1",
+				"route53.vpc_id":                   "vpc-0123456789abcdef0",
+				"route53.query_timestamp":          "2024-01-01T12:00:00.5Z",
+				"route53.query_name":               "malware.example.",
+				"route53.query_type":               "AAAA",
+				"route53.query_class":              "IN",
+				"route53.rcode":                    "NXDOMAIN",
+				"route53.srcaddr":                  "10.0.1.5",
+				"route53.srcport":                  int64(53),
+				"route53.transport":                "TCP",
+				"route53.srcids.resolver_endpoint": "rslvr-in-0123456789abcdef0",
+				"route53.firewall_rule_action":     "BLOCK",
+				"route53.firewall_rule_group_id":   "rslvr-frg-0123456789abcdef",
+				"route53.firewall_domain_list_id":  "rslvr-fdl-0123456789abcdef",
+			},
+			wantTimestamp: 1704110400500,
+			wantOK:        true,
+		},
+		{
+			desc:    "Public DNS query",
+			message: "1.0 2017-12-13T08:16:02.130Z Z123412341234 example.com A NOERROR UDP FRA6 192.168.1.1 -",
+			want: map[string]any{
+				"route53.version":         "1.0",
+				"route53.query_timestamp": "2017-12-13T08:16:02.130Z",
+				"route53.hosted_zone_id":  "Z123412341234",
+				"route53.query_name":      "example.com",
+				"route53.query_type":      "A",
+				"route53.rcode":           "NOERROR",
+				"route53.protocol":        "UDP",
+				"route53.edge_location":   "FRA6",
+				"route53.resolver_ip":     "192.168.1.1",
+			},
+			wantTimestamp: 1513152962130,
+			wantOK:        true,
+		},
+		{
+			desc:    "Public DNS query with EDNS client subnet",
+			message: "1.0 2017-12-13T08:15:50.235Z Z123412341234 example.com AAAA NOERROR TCP IAD12 2001:db8::1234 2001:db8:abcd::/48",
+			want: map[string]any{
+				"route53.version":            "1.0",
+				"route53.query_timestamp":    "2017-12-13T08:15:50.235Z",
+				"route53.hosted_zone_id":     "Z123412341234",
+				"route53.query_name":         "example.com",
+				"route53.query_type":         "AAAA",
+				"route53.rcode":              "NOERROR",
+				"route53.protocol":           "TCP",
+				"route53.edge_location":      "IAD12",
+				"route53.resolver_ip":        "2001:db8::1234",
+				"route53.edns_client_subnet": "2001:db8:abcd::/48",
+			},
+			wantTimestamp: 1513152950235,
+			wantOK:        true,
+		},
+		{
+			desc:    "Invalid source port",
+			message: `{"query_timestamp":"2024-01-01T12:00:00Z","query_name":"example.com.","srcport":"port"}`,
+		},
+		{
+			desc:    "Not a resolver query log",
+			message: `{"level":"info","msg":"hello"}`,
+		},
+		{
+			desc:    "Invalid timestamp",
+			message: "1.0 yesterday Z123412341234 example.com A NOERROR UDP FRA6 192.168.1.1 -",
+		},
+		{
+			desc:    "Not a query log",
+			message: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, timestamp, ok := route53Parser.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTimestamp, timestamp)
+		})
+	}
+}
+
+func TestIsRoute53Log(t *testing.T) {
+	assert.True(t, IsRoute53LogGroup("/aws/route53/example.com", "Z123412341234/FRA6"))
+	assert.True(t, IsRoute53LogGroup("dns-queries", "Z123412341234/IAD89-C1"))
+	assert.True(t, IsRoute53LogGroup("resolver-queries", "vpc-0123456789abcdef0_rslvr-rqlc-0123456789abcdef"))
+	assert.False(t, IsRoute53LogGroup("/aws/lambda/my-function", "2024/01/01/[$LATEST]abc"))
+	assert.True(t, IsRoute53S3Key("AWSLogs/111122223333/vpcdnsquerylogs/vpc-0123456789abcdef0/2024/01/01/111122223333_vpcdnsquerylogs_vpc-0123456789abcdef0_20240101T0000Z_abc.log.gz"))
+	assert.False(t, IsRoute53S3Key("logs/app.log"))
+}
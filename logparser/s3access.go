@@ -0,0 +1,90 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	s3AccessPrefix = "s3_access."
+	// minS3AccessFields is the number of fields of the oldest server access log format, up to version ID
+	minS3AccessFields  = 18
+	s3AccessTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+var (
+	// server access log objects are named {target_prefix}yyyy-mm-dd-hh-mm-ss-{unique_id} in simple and partitioned key formats,
+	// target prefix does not have to end with "/"
+	s3AccessKeyRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}-[0-9A-F]{16}$`)
+
+	s3AccessParser = &S3AccessParser{}
+)
+
+// S3AccessParser parses S3 server access logs, fields added to the format later are optional.
+type S3AccessParser struct{}
+
+// Parse returns fields of https://docs.aws.amazon.com/AmazonS3/latest/userguide/LogFormat.html with "s3_access." prefix,
+// fields without value ("-") are omitted. Object key is URL decoded. Timestamp is the time the request was received.
+func (p *S3AccessParser) Parse(message string) (map[string]any, int64, bool) {
+	fields, ok := splitQuoted(message)
+	if !ok || len(fields) < minS3AccessFields {
+		return nil, 0, false
+	}
+	t, err := time.Parse(s3AccessTimeLayout, fields[2])
+	if err != nil {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(s3AccessPrefix, len(fields))
+	setters := []func(v string){
+		func(v string) { f.setString("bucket_owner", v) },
+		func(v string) { f.setString("bucket", v) },
+		func(v string) { f.setString("time", v) },
+		func(v string) { f.setString("remote_ip", v) },
+		func(v string) { f.setString("requester", v) },
+		func(v string) { f.setString("request_id", v) },
+		func(v string) { f.setString("operation", v) },
+		func(v string) { f.setString("key", unescapeURL(v)) },
+		func(v string) { f.setString("request_uri", v) },
+		func(v string) { f.setInt("http_status", v) },
+		func(v string) { f.setString("error_code", v) },
+		func(v string) { f.setInt("bytes_sent", v) },
+		func(v string) { f.setInt("object_size", v) },
+		func(v string) { f.setInt("total_time", v) },
+		func(v string) { f.setInt("turn_around_time", v) },
+		func(v string) { f.setString("referer", v) },
+		func(v string) { f.setString("user_agent", v) },
+		func(v string) { f.setString("version_id", v) },
+		func(v string) { f.setString("host_id", v) },
+		func(v string) { f.setString("signature_version", v) },
+		func(v string) { f.setString("cipher_suite", v) },
+		func(v string) { f.setString("authentication_type", v) },
+		func(v string) { f.setString("host_header", v) },
+		func(v string) { f.setString("tls_version", v) },
+		func(v string) { f.setString("access_point_arn", v) },
+		func(v string) { f.setString("acl_required", v) },
+	}
+	// fields added after acl_required are ignored until they are known
+	for i := 0; i < len(setters) && i < len(fields); i++ {
+		setters[i](fields[i])
+	}
+	if f.err != nil {
+		return nil, 0, false
+	}
+	return f.attributes, t.UnixMilli(), true
+}
+
+// IsS3AccessLogKey returns true if the object is a server access log of an S3 bucket.
+func IsS3AccessLogKey(key string) bool {
+	return s3AccessKeyRegex.MatchString(key)
+}
+
+// GetS3AccessLogBucket returns the bucket of a server access log record, which is the bucket the requests are made to.
+func GetS3AccessLogBucket(message string) (string, bool) {
+	fields := strings.SplitN(message, " ", 3)
+	if len(fields) < 3 || isEmpty(fields[1]) {
+		return "", false
+	}
+	return fields[1], true
+}
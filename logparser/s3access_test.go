@@ -0,0 +1,118 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3AccessParser(t *testing.T) {
+	tests := []struct {
+		desc          string
+		message       string
+		want          map[string]any
+		wantTimestamp int64
+		wantOK        bool
+	}{
+		{
+			desc:    "Object request",
+			message: `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be amzn-s3-demo-bucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 arn:aws:iam::123456789012:user/alice 3E57427F3EXAMPLE REST.GET.OBJECT photos/2019/my%20puppy.jpg "GET /amzn-s3-demo-bucket1/photos/2019/my%20puppy.jpg HTTP/1.1" 200 - 2662992 3462992 70 10 "-" "aws-cli/2.15.0 Python/3.11.6" - s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader amzn-s3-demo-bucket1.s3.us-west-1.amazonaws.com TLSv1.2 - Yes`,
+			want: map[string]any{
+				"s3_access.bucket_owner":        "79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be",
+				"s3_access.bucket":              "amzn-s3-demo-bucket1",
+				"s3_access.time":                "06/Feb/2019:00:00:38 +0000",
+				"s3_access.remote_ip":           "192.0.2.3",
+				"s3_access.requester":           "arn:aws:iam::123456789012:user/alice",
+				"s3_access.request_id":          "3E57427F3EXAMPLE",
+				"s3_access.operation":           "REST.GET.OBJECT",
+				"s3_access.key":                 "photos/2019/my puppy.jpg",
+				"s3_access.request_uri":         "GET /amzn-s3-demo-bucket1/photos/2019/my%20puppy.jpg HTTP/1.1",
+				"s3_access.http_status":         int64(200),
+				"s3_access.bytes_sent":          int64(2662992),
+				"s3_access.object_size":         int64(3462992),
+				"s3_access.total_time":          int64(70),
+				"s3_access.turn_around_time":    int64(10),
+				"s3_access.user_agent":          "aws-cli/2.15.0 Python/3.11.6",
+				"s3_access.host_id":             "s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234=",
+				"s3_access.signature_version":   "SigV4",
+				"s3_access.cipher_suite":        "ECDHE-RSA-AES128-GCM-SHA256",
+				"s3_access.authentication_type": "AuthHeader",
+				"s3_access.host_header":         "amzn-s3-demo-bucket1.s3.us-west-1.amazonaws.com",
+				"s3_access.tls_version":         "TLSv1.2",
+				"s3_access.acl_required":        "Yes",
+			},
+			wantTimestamp: 1549411238000,
+			wantOK:        true,
+		},
+		{
+			desc:    "Failed request in the oldest format",
+			message: `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be amzn-s3-demo-bucket1 [06/Feb/2019:02:00:38 +0200] 192.0.2.3 - 891CE47D2EXAMPLE REST.GET.OBJECT secret.txt "GET /amzn-s3-demo-bucket1/secret.txt HTTP/1.1" 403 AccessDenied 243 - 12 - "https://example.com/" "curl/8.0" -`,
+			want: map[string]any{
+				"s3_access.bucket_owner": "79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be",
+				"s3_access.bucket":       "amzn-s3-demo-bucket1",
+				"s3_access.time":         "06/Feb/2019:02:00:38 +0200",
+				"s3_access.remote_ip":    "192.0.2.3",
+				"s3_access.request_id":   "891CE47D2EXAMPLE",
+				"s3_access.operation":    "REST.GET.OBJECT",
+				"s3_access.key":          "secret.txt",
+				"s3_access.request_uri":  "GET /amzn-s3-demo-bucket1/secret.txt HTTP/1.1",
+				"s3_access.http_status":  int64(403),
+				"s3_access.error_code":   "AccessDenied",
+				"s3_access.bytes_sent":   int64(243),
+				"s3_access.total_time":   int64(12),
+				"s3_access.referer":      "https://example.com/",
+				"s3_access.user_agent":   "curl/8.0",
+			},
+			wantTimestamp: 1549411238000,
+			wantOK:        true,
+		},
+		{
+			desc:    "Invalid time",
+			message: `owner amzn-s3-demo-bucket1 [06/Feb/2019] 192.0.2.3 - 891CE47D2EXAMPLE REST.GET.OBJECT secret.txt "GET / HTTP/1.1" 403 AccessDenied 243 - 12 - "-" "curl/8.0" -`,
+		},
+		{
+			desc:    "Bracket is not closed",
+			message: `owner amzn-s3-demo-bucket1 [06/Feb/2019:00:00:38 +0000 192.0.2.3`,
+		},
+		{
+			desc:    "Not enough fields",
+			message: `owner amzn-s3-demo-bucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, timestamp, ok := s3AccessParser.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTimestamp, timestamp)
+		})
+	}
+}
+
+func TestIsS3AccessLogKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"logs/2019-02-06-00-00-38-ABCDEF0123456789", true},
+		{"access-log-2019-02-06-00-00-38-ABCDEF0123456789", true},
+		{"logs/123456789012/us-west-1/amzn-s3-demo-bucket1/2019/02/06/2019-02-06-00-00-38-ABCDEF0123456789", true},
+		{"logs/2019-02-06-00-00-38.log", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsS3AccessLogKey(tt.key))
+		})
+	}
+}
+
+func TestGetS3AccessLogBucket(t *testing.T) {
+	bucket, ok := GetS3AccessLogBucket("79a59df900b949e5 amzn-s3-demo-bucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3")
+	assert.True(t, ok)
+	assert.Equal(t, "amzn-s3-demo-bucket1", bucket)
+
+	_, ok = GetS3AccessLogBucket("owner")
+	assert.False(t, ok)
+}
@@ -0,0 +1,154 @@
+package logparser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	flowLogPrefix = "vpc_flow."
+)
+
+var (
+	// DefaultFlowLogFormat is the format of flow logs created without a custom format
+	DefaultFlowLogFormat = "${version} ${account-id} ${interface-id} ${srcaddr} ${dstaddr} ${srcport} ${dstport} ${protocol} ${packets} ${bytes} ${start} ${end} ${action} ${log-status}"
+
+	defaultFlowLogParser, _ = NewFlowLogParser(DefaultFlowLogFormat)
+
+	// flow log streams are named after the network interface, i.e. eni-0123456789abcdef0-all
+	flowLogStreamRegex = regexp.MustCompile(`^eni-[0-9a-f]+(-(all|accept|reject))?$`)
+
+	flowLogIntegerFields = map[string]bool{
+		"version":      true,
+		"srcport":      true,
+		"dstport":      true,
+		"protocol":     true,
+		"packets":      true,
+		"bytes":        true,
+		"start":        true,
+		"end":          true,
+		"tcp-flags":    true,
+		"traffic-path": true,
+	}
+
+	// protocolNames has IANA protocol numbers commonly seen in flow logs
+	protocolNames = map[int64]string{
+		1:   "ICMP",
+		2:   "IGMP",
+		4:   "IPv4",
+		6:   "TCP",
+		17:  "UDP",
+		41:  "IPv6",
+		47:  "GRE",
+		50:  "ESP",
+		51:  "AH",
+		58:  "IPv6-ICMP",
+		89:  "OSPF",
+		112: "VRRP",
+		132: "SCTP",
+	}
+
+	tcpFlagNames = []struct {
+		flag int64
+		name string
+	}{
+		{1, "FIN"},
+		{2, "SYN"},
+		{4, "RST"},
+		{8, "PSH"},
+		{16, "ACK"},
+		{32, "URG"},
+		{64, "ECE"},
+		{128, "CWR"},
+	}
+)
+
+// FlowLogParser parses VPC flow log records of a format, which is the field list given when the flow log is created.
+type FlowLogParser struct {
+	fields []string
+}
+
+// NewFlowLogParser returns a parser of the given format, i.e. "${version} ${vpc-id} ${srcaddr}".
+// Fields may also be given without "${}", i.e. as in the header of flow log files delivered to S3.
+func NewFlowLogParser(format string) (*FlowLogParser, error) {
+	var fields []string
+	for _, f := range strings.Fields(format) {
+		f = strings.TrimSuffix(strings.TrimPrefix(f, "${"), "}")
+		if f == "" {
+			return nil, errors.New("empty field name")
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("format has no fields")
+	}
+	return &FlowLogParser{fields: fields}, nil
+}
+
+// Parse returns fields of a flow log record with "vpc_flow." prefix, hyphens in field names are replaced by underscores.
+// Numeric fields are integers, protocol name and TCP flag names are added, fields without value ("-") are omitted.
+// Records are sent to CloudWatch Logs with their timestamps, so timestamp is not returned.
+func (p *FlowLogParser) Parse(message string) (map[string]any, int64, bool) {
+	values := strings.Fields(message)
+	if len(values) != len(p.fields) {
+		return nil, 0, false
+	}
+
+	attributes := make(map[string]any, len(values)+2)
+	for i, v := range values {
+		if v == noValue {
+			continue
+		}
+		field := p.fields[i]
+		key := flowLogPrefix + strings.ReplaceAll(field, "-", "_")
+		if !flowLogIntegerFields[field] {
+			attributes[key] = v
+			continue
+		}
+
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			// record is not in the format, i.e. format of the log group is changed
+			return nil, 0, false
+		}
+		attributes[key] = n
+		switch field {
+		case "protocol":
+			if name, ok := protocolNames[n]; ok {
+				attributes[flowLogPrefix+"protocol_name"] = name
+			}
+		case "tcp-flags":
+			attributes[flowLogPrefix+"tcp_flag_names"] = decodeTCPFlags(n)
+		}
+	}
+	return attributes, 0, true
+}
+
+// decodeTCPFlags returns names of the flags set in the bitmask, flags of a flow are ORed during aggregation interval.
+func decodeTCPFlags(flags int64) []string {
+	names := []string{}
+	for _, f := range tcpFlagNames {
+		if flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// IsFlowLogGroup returns true if logs of the log group are VPC flow logs. Flow log groups have no fixed name,
+// so they are recognized by the log group created for a VPC (/ec2/vpc/{vpc_id}), "flow" and "log" in the name,
+// or by log streams named after network interfaces.
+func IsFlowLogGroup(logGroup, logStream string) bool {
+	if strings.HasPrefix(logGroup, "/ec2/vpc/") || flowLogStreamRegex.MatchString(logStream) {
+		return true
+	}
+	lower := strings.ToLower(logGroup)
+	for _, s := range []string{"flow-log", "flowlog", "flow_log"} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowLogParser(t *testing.T) {
+	tests := []struct {
+		desc    string
+		format  string
+		message string
+		want    map[string]any
+		wantOK  bool
+	}{
+		{
+			desc:    "Default format",
+			format:  DefaultFlowLogFormat,
+			message: "2 123456789012 eni-0123456789abcdef0 172.31.16.139 172.31.16.21 20641 22 6 20 4249 1418530010 1418530070 ACCEPT OK",
+			want: map[string]any{
+				"vpc_flow.version":       int64(2),
+				"vpc_flow.account_id":    "123456789012",
+				"vpc_flow.interface_id":  "eni-0123456789abcdef0",
+				"vpc_flow.srcaddr":       "172.31.16.139",
+				"vpc_flow.dstaddr":       "172.31.16.21",
+				"vpc_flow.srcport":       int64(20641),
+				"vpc_flow.dstport":       int64(22),
+				"vpc_flow.protocol":      int64(6),
+				"vpc_flow.protocol_name": "TCP",
+				"vpc_flow.packets":       int64(20),
+				"vpc_flow.bytes":         int64(4249),
+				"vpc_flow.start":         int64(1418530010),
+				"vpc_flow.end":           int64(1418530070),
+				"vpc_flow.action":        "ACCEPT",
+				"vpc_flow.log_status":    "OK",
+			},
+			wantOK: true,
+		},
+		{
+			desc:    "Default format without data",
+			format:  DefaultFlowLogFormat,
+			message: "2 123456789012 eni-0123456789abcdef0 - - - - - - - 1431280876 1431280934 - NODATA",
+			want: map[string]any{
+				"vpc_flow.version":      int64(2),
+				"vpc_flow.account_id":   "123456789012",
+				"vpc_flow.interface_id": "eni-0123456789abcdef0",
+				"vpc_flow.start":        int64(1431280876),
+				"vpc_flow.end":          int64(1431280934),
+				"vpc_flow.log_status":   "NODATA",
+			},
+			wantOK: true,
+		},
+		{
+			desc:    "Custom v3 format with TCP flags",
+			format:  "${version} ${vpc-id} ${subnet-id} ${instance-id} ${srcaddr} ${dstaddr} ${protocol} ${tcp-flags} ${type} ${pkt-srcaddr} ${pkt-dstaddr}",
+			message: "3 vpc-12345678 subnet-012345678 i-0123456789abcdef0 10.0.0.1 10.0.0.2 6 19 IPv4 10.0.0.1 10.0.0.2",
+			want: map[string]any{
+				"vpc_flow.version":        int64(3),
+				"vpc_flow.vpc_id":         "vpc-12345678",
+				"vpc_flow.subnet_id":      "subnet-012345678",
+				"vpc_flow.instance_id":    "i-0123456789abcdef0",
+				"vpc_flow.srcaddr":        "10.0.0.1",
+				"vpc_flow.dstaddr":        "10.0.0.2",
+				"vpc_flow.protocol":       int64(6),
+				"vpc_flow.protocol_name":  "TCP",
+				"vpc_flow.tcp_flags":      int64(19),
+				"vpc_flow.tcp_flag_names": []string{"FIN", "SYN", "ACK"},
+				"vpc_flow.type":           "IPv4",
+				"vpc_flow.pkt_srcaddr":    "10.0.0.1",
+				"vpc_flow.pkt_dstaddr":    "10.0.0.2",
+			},
+			wantOK: true,
+		},
+		{
+			desc:    "Custom v5 format from S3 header",
+			format:  "version region az-id flow-direction traffic-path pkt-src-aws-service protocol",
+			message: "5 us-east-1 use1-az1 egress 8 S3 17",
+			want: map[string]any{
+				"vpc_flow.version":             int64(5),
+				"vpc_flow.region":              "us-east-1",
+				"vpc_flow.az_id":               "use1-az1",
+				"vpc_flow.flow_direction":      "egress",
+				"vpc_flow.traffic_path":        int64(8),
+				"vpc_flow.pkt_src_aws_service": "S3",
+				"vpc_flow.protocol":            int64(17),
+				"vpc_flow.protocol_name":       "UDP",
+			},
+			wantOK: true,
+		},
+		{
+			desc:    "Field count does not match",
+			format:  DefaultFlowLogFormat,
+			message: "3 vpc-12345678 10.0.0.1",
+		},
+		{
+			desc:    "Integer field is not a number",
+			format:  "${version} ${srcport}",
+			message: "2 http",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			p, err := NewFlowLogParser(tt.format)
+			require.NoError(t, err)
+			got, _, ok := p.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package logparser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	wafPrefix = "waf."
+	// WAF requires log groups, delivery streams and S3 buckets of web ACL logs to be named with this prefix
+	wafLogNamePrefix = "aws-waf-logs-"
+)
+
+var (
+	// WAF log objects are delivered to [prefix/]AWSLogs/{account_id}/WAFLogs/{region|cloudfront}/{web_acl_name}/...
+	wafS3KeyRegex = regexp.MustCompile(`(^|/)AWSLogs/\d{12}/WAFLogs/`)
+
+	wafParser = &WAFParser{}
+)
+
+type wafRule struct {
+	RuleID string `json:"ruleId"`
+	Action string `json:"action"`
+}
+
+type wafLog struct {
+	Timestamp                   int64  `json:"timestamp"`
+	WebACLID                    string `json:"webaclId"`
+	TerminatingRuleID           string `json:"terminatingRuleId"`
+	TerminatingRuleType         string `json:"terminatingRuleType"`
+	Action                      string `json:"action"`
+	TerminatingRuleMatchDetails []struct {
+		ConditionType string `json:"conditionType"`
+		Location      string `json:"location"`
+	} `json:"terminatingRuleMatchDetails"`
+	HTTPSourceName string `json:"httpSourceName"`
+	HTTPSourceID   string `json:"httpSourceId"`
+	RuleGroupList  []struct {
+		RuleGroupID                 string    `json:"ruleGroupId"`
+		TerminatingRule             *wafRule  `json:"terminatingRule"`
+		NonTerminatingMatchingRules []wafRule `json:"nonTerminatingMatchingRules"`
+	} `json:"ruleGroupList"`
+	RateBasedRuleList []struct {
+		RateBasedRuleID string `json:"rateBasedRuleId"`
+	} `json:"rateBasedRuleList"`
+	NonTerminatingMatchingRules []wafRule `json:"nonTerminatingMatchingRules"`
+	ResponseCodeSent            *int64    `json:"responseCodeSent"`
+	HTTPRequest                 struct {
+		ClientIP string `json:"clientIp"`
+		Country  string `json:"country"`
+		Headers  []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		URI         string `json:"uri"`
+		Args        string `json:"args"`
+		HTTPVersion string `json:"httpVersion"`
+		HTTPMethod  string `json:"httpMethod"`
+		RequestID   string `json:"requestId"`
+	} `json:"httpRequest"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	JA3Fingerprint string `json:"ja3Fingerprint"`
+	JA4Fingerprint string `json:"ja4Fingerprint"`
+}
+
+// WAFParser parses AWS WAF web ACL logs, which are JSON, and flattens the fields needed to query them.
+type WAFParser struct{}
+
+// Parse returns fields of a WAF log with "waf." prefix. Terminating rule of a rule group, rules with matches which did not
+// terminate the request, labels and the request are flattened, the rest of the log stays in the message.
+// Timestamp is the time of the request.
+func (p *WAFParser) Parse(message string) (map[string]any, int64, bool) {
+	var l wafLog
+	if err := json.Unmarshal([]byte(message), &l); err != nil || l.WebACLID == "" {
+		return nil, 0, false
+	}
+
+	f := newFieldSetter(wafPrefix, 24)
+	f.setString("webacl_id", l.WebACLID)
+	f.setString("action", l.Action)
+	f.setString("terminating_rule_id", l.TerminatingRuleID)
+	f.setString("terminating_rule_type", l.TerminatingRuleType)
+	f.setString("http_source_name", l.HTTPSourceName)
+	f.setString("http_source_id", l.HTTPSourceID)
+	f.setString("client_ip", l.HTTPRequest.ClientIP)
+	f.setString("country", l.HTTPRequest.Country)
+	f.setString("uri", l.HTTPRequest.URI)
+	f.setString("args", l.HTTPRequest.Args)
+	f.setString("http_method", l.HTTPRequest.HTTPMethod)
+	f.setString("http_version", l.HTTPRequest.HTTPVersion)
+	f.setString("request_id", l.HTTPRequest.RequestID)
+	f.setString("ja3_fingerprint", l.JA3Fingerprint)
+	f.setString("ja4_fingerprint", l.JA4Fingerprint)
+	for _, h := range l.HTTPRequest.Headers {
+		switch strings.ToLower(h.Name) {
+		case "host":
+			f.setString("host", h.Value)
+		case "user-agent":
+			f.setString("user_agent", h.Value)
+		}
+	}
+	if l.ResponseCodeSent != nil {
+		f.attributes[wafPrefix+"response_code_sent"] = *l.ResponseCodeSent
+	}
+
+	var conditionTypes []string
+	for _, d := range l.TerminatingRuleMatchDetails {
+		conditionTypes = append(conditionTypes, d.ConditionType)
+	}
+	setStrings(f, "terminating_rule_condition_types", conditionTypes)
+
+	var ruleGroupIDs, nonTerminatingRuleIDs, rateBasedRuleIDs, labels []string
+	for _, r := range l.NonTerminatingMatchingRules {
+		nonTerminatingRuleIDs = append(nonTerminatingRuleIDs, r.RuleID)
+	}
+	for _, g := range l.RuleGroupList {
+		ruleGroupIDs = append(ruleGroupIDs, g.RuleGroupID)
+		if g.TerminatingRule != nil {
+			f.setString("terminating_rule_group_id", g.RuleGroupID)
+			f.setString("terminating_rule_group_rule_id", g.TerminatingRule.RuleID)
+		}
+		for _, r := range g.NonTerminatingMatchingRules {
+			nonTerminatingRuleIDs = append(nonTerminatingRuleIDs, r.RuleID)
+		}
+	}
+	for _, r := range l.RateBasedRuleList {
+		rateBasedRuleIDs = append(rateBasedRuleIDs, r.RateBasedRuleID)
+	}
+	for _, label := range l.Labels {
+		labels = append(labels, label.Name)
+	}
+	setStrings(f, "rule_group_ids", ruleGroupIDs)
+	setStrings(f, "non_terminating_rule_ids", nonTerminatingRuleIDs)
+	setStrings(f, "rate_based_rule_ids", rateBasedRuleIDs)
+	setStrings(f, "labels", labels)
+
+	return f.attributes, l.Timestamp, true
+}
+
+// setStrings sets a list of values, empty lists are omitted.
+func setStrings(f *fieldSetter, key string, values []string) {
+	if len(values) > 0 {
+		f.attributes[f.prefix+key] = values
+	}
+}
+
+// IsWAFLogGroup returns true if the log group has WAF logs, WAF only logs to log groups named "aws-waf-logs-*".
+func IsWAFLogGroup(logGroup string) bool {
+	return strings.HasPrefix(logGroup, wafLogNamePrefix)
+}
+
+// IsWAFDeliveryStream returns true if the Firehose delivery stream has WAF logs, which are named "aws-waf-logs-*".
+func IsWAFDeliveryStream(deliveryStreamARN string) bool {
+	name := deliveryStreamARN[strings.LastIndexByte(deliveryStreamARN, '/')+1:]
+	return strings.HasPrefix(name, wafLogNamePrefix)
+}
+
+// IsWAFS3Key returns true if the object is a WAF log delivered to S3.
+func IsWAFS3Key(key string) bool {
+	return wafS3KeyRegex.MatchString(key)
+}
+
+// GetWAFWebACLARN returns the ARN of the web ACL of a WAF log, false if it is not a WAF log.
+func GetWAFWebACLARN(message string) (string, bool) {
+	var l struct {
+		WebACLID string `json:"webaclId"`
+	}
+	if err := json.Unmarshal([]byte(message), &l); err != nil || !strings.HasPrefix(l.WebACLID, "arn:") {
+		return "", false
+	}
+	return l.WebACLID, true
+}
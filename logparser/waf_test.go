@@ -0,0 +1,109 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	wafLogMessage = `{"timestamp":1576280412771,"formatVersion":1,"webaclId":"arn:aws:wafv2:ap-southeast-2:111122223333:regional/webacl/STMTest/1EXAMPLE-2ARN-3ARN-4ARN-123456EXAMPLE","terminatingRuleId":"AWS-AWSManagedRulesCommonRuleSet","terminatingRuleType":"MANAGED_RULE_GROUP","action":"BLOCK","terminatingRuleMatchDetails":[{"conditionType":"XSS","location":"BODY","matchedData":["<script>"]}],"httpSourceName":"ALB","httpSourceId":"111122223333-app/my-alb/50dc6c495c0c9188","ruleGroupList":[{"ruleGroupId":"AWS#AWSManagedRulesCommonRuleSet","terminatingRule":{"ruleId":"CrossSiteScripting_BODY","action":"BLOCK"},"nonTerminatingMatchingRules":[{"ruleId":"SizeRestrictions_BODY","action":"COUNT"}],"excludedRules":null}],"rateBasedRuleList":[{"rateBasedRuleId":"123","limitKey":"IP","maxRateAllowed":100}],"nonTerminatingMatchingRules":[{"ruleId":"TestRule","action":"COUNT"}],"requestHeadersInserted":null,"responseCodeSent":403,"httpRequest":{"clientIp":"1.1.1.1","country":"AU","headers":[{"name":"Host","value":"example.com"},{"name":"User-Agent","value":"curl/8.0"}],"uri":"/login","args":"a=1","httpVersion":"HTTP/1.1","httpMethod":"POST","requestId":"rid"},"labels":[{"name":"awswaf:managed:aws:core-rule-set:CrossSiteScripting_Body"}],"ja3Fingerprint":"e7d705a3286e19ea42f587b344ee6865"}`
+)
+
+func TestWAFParser(t *testing.T) {
+	tests := []struct {
+		desc          string
+		message       string
+		want          map[string]any
+		wantTimestamp int64
+		wantOK        bool
+	}{
+		{
+			desc:    "Blocked by rule group",
+			message: wafLogMessage,
+			want: map[string]any{
+				"waf.webacl_id":                        "arn:aws:wafv2:ap-southeast-2:111122223333:regional/webacl/STMTest/1EXAMPLE-2ARN-3ARN-4ARN-123456EXAMPLE",
+				"waf.action":                           "BLOCK",
+				"waf.terminating_rule_id":              "AWS-AWSManagedRulesCommonRuleSet",
+				"waf.terminating_rule_type":            "MANAGED_RULE_GROUP",
+				"waf.terminating_rule_condition_types": []string{"XSS"},
+				"waf.terminating_rule_group_id":        "AWS#AWSManagedRulesCommonRuleSet",
+				"waf.terminating_rule_group_rule_id":   "CrossSiteScripting_BODY",
+				"waf.http_source_name":                 "ALB",
+				"waf.http_source_id":                   "111122223333-app/my-alb/50dc6c495c0c9188",
+				"waf.rule_group_ids":                   []string{"AWS#AWSManagedRulesCommonRuleSet"},
+				"waf.non_terminating_rule_ids":         []string{"TestRule", "SizeRestrictions_BODY"},
+				"waf.rate_based_rule_ids":              []string{"123"},
+				"waf.response_code_sent":               int64(403),
+				"waf.client_ip":                        "1.1.1.1",
+				"waf.country":                          "AU",
+				"waf.host":                             "example.com",
+				"waf.user_agent":                       "curl/8.0",
+				"waf.uri":                              "/login",
+				"waf.args":                             "a=1",
+				"waf.http_version":                     "HTTP/1.1",
+				"waf.http_method":                      "POST",
+				"waf.request_id":                       "rid",
+				"waf.labels":                           []string{"awswaf:managed:aws:core-rule-set:CrossSiteScripting_Body"},
+				"waf.ja3_fingerprint":                  "e7d705a3286e19ea42f587b344ee6865",
+			},
+			wantTimestamp: 1576280412771,
+			wantOK:        true,
+		},
+		{
+			desc:    "Allowed by default action",
+			message: `{"timestamp":1576280412771,"webaclId":"arn:aws:wafv2:us-east-1:111122223333:global/webacl/cf/abc","terminatingRuleId":"Default_Action","terminatingRuleType":"REGULAR","action":"ALLOW","terminatingRuleMatchDetails":[],"httpSourceName":"CF","httpSourceId":"-","ruleGroupList":[],"rateBasedRuleList":[],"nonTerminatingMatchingRules":[],"httpRequest":{"clientIp":"2001:db8::1","country":"US","headers":[],"uri":"/","args":"","httpVersion":"HTTP/2.0","httpMethod":"GET","requestId":null}}`,
+			want: map[string]any{
+				"waf.webacl_id":             "arn:aws:wafv2:us-east-1:111122223333:global/webacl/cf/abc",
+				"waf.action":                "ALLOW",
+				"waf.terminating_rule_id":   "Default_Action",
+				"waf.terminating_rule_type": "REGULAR",
+				"waf.http_source_name":      "CF",
+				"waf.client_ip":             "2001:db8::1",
+				"waf.country":               "US",
+				"waf.uri":                   "/",
+				"waf.http_version":          "HTTP/2.0",
+				"waf.http_method":           "GET",
+			},
+			wantTimestamp: 1576280412771,
+			wantOK:        true,
+		},
+		{
+			desc:    "Not a WAF log",
+			message: `{"level":"info","msg":"hello"}`,
+		},
+		{
+			desc:    "Not JSON",
+			message: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, timestamp, ok := wafParser.Parse(tt.message)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTimestamp, timestamp)
+		})
+	}
+}
+
+func TestGetWAFWebACLARN(t *testing.T) {
+	arn, ok := GetWAFWebACLARN(wafLogMessage)
+	assert.True(t, ok)
+	assert.Equal(t, "arn:aws:wafv2:ap-southeast-2:111122223333:regional/webacl/STMTest/1EXAMPLE-2ARN-3ARN-4ARN-123456EXAMPLE", arn)
+
+	_, ok = GetWAFWebACLARN(`{"webaclId":"STMTest"}`)
+	assert.False(t, ok)
+	_, ok = GetWAFWebACLARN("hello")
+	assert.False(t, ok)
+}
+
+func TestIsWAFLog(t *testing.T) {
+	assert.True(t, IsWAFLogGroup("aws-waf-logs-my-acl"))
+	assert.False(t, IsWAFLogGroup("/aws/waf/my-acl"))
+	assert.True(t, IsWAFDeliveryStream("arn:aws:firehose:us-east-1:111122223333:deliverystream/aws-waf-logs-my-acl"))
+	assert.False(t, IsWAFDeliveryStream("arn:aws:firehose:us-east-1:111122223333:deliverystream/my-stream"))
+	assert.True(t, IsWAFS3Key("AWSLogs/111122223333/WAFLogs/us-east-1/my-acl/2024/01/01/00/00/111122223333_waflogs_us-east-1_my-acl_20240101T0000Z_abc.log.gz"))
+	assert.False(t, IsWAFS3Key("logs/app.log"))
+}
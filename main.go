@@ -2,46 +2,89 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/edgedelta/edgedelta-forwarder/cfg"
-	"github.com/edgedelta/edgedelta-forwarder/chunker"
-	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/dispatch"
 	"github.com/edgedelta/edgedelta-forwarder/ecs"
 	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/otlp"
 	"github.com/edgedelta/edgedelta-forwarder/push"
 	"github.com/edgedelta/edgedelta-forwarder/resource"
+	"github.com/edgedelta/edgedelta-forwarder/s3"
+	"github.com/edgedelta/edgedelta-forwarder/webhook"
 
 	lambdaCl "github.com/edgedelta/edgedelta-forwarder/lambda"
 )
 
 var (
-	config     *cfg.Config
-	pusher     *push.Pusher
-	enricher   *enrich.Enricher
-	logChunker *chunker.Chunker
+	config        *cfg.Config
+	enricher      *enrich.Enricher
+	forwarder     *forward.Forwarder
+	s3Client      s3.Client
+	authenticator *webhook.Authenticator
+	otlpServer    *otlp.Server
 )
 
-type HandlerFn func(context.Context, events.CloudwatchLogsEvent) error
+type HandlerFn[T any] func(context.Context, T) error
 
-func withGracefulShutdown(handler HandlerFn, gracePeriod time.Duration) HandlerFn {
-	return func(ctx context.Context, logsEvent events.CloudwatchLogsEvent) error {
-		deadline, ok := ctx.Deadline()
-		if !ok {
-			return handler(ctx, logsEvent)
-		}
-		shorterDeadline := deadline.Add(-gracePeriod)
-		graceCtx, cancel := context.WithDeadline(ctx, shorterDeadline)
+type ResponseHandlerFn[T, R any] func(context.Context, T) (R, error)
+
+func withGracefulShutdown[T any](handler HandlerFn[T], gracePeriod time.Duration) HandlerFn[T] {
+	return func(ctx context.Context, event T) error {
+		graceCtx, cancel := withGraceDeadline(ctx, gracePeriod)
 		defer cancel()
-		return handler(graceCtx, logsEvent)
+		return handler(graceCtx, event)
+	}
+}
+
+func withGracefulShutdownResponse[T, R any](handler ResponseHandlerFn[T, R], gracePeriod time.Duration) ResponseHandlerFn[T, R] {
+	return func(ctx context.Context, event T) (R, error) {
+		graceCtx, cancel := withGraceDeadline(ctx, gracePeriod)
+		defer cancel()
+		return handler(graceCtx, event)
+	}
+}
+
+// withGraceDeadline shortens context deadline by grace period so handler has time to respond before lambda times out.
+func withGraceDeadline(ctx context.Context, gracePeriod time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}
 	}
+	return context.WithDeadline(ctx, deadline.Add(-gracePeriod))
 }
 
 func main() {
-	lambda.Start(withGracefulShutdown(handleRequest, time.Second*5))
+	switch config.HandlerMode {
+	case cfg.HandlerModeS3:
+		lambda.Start(withGracefulShutdown(handleS3Request, time.Second*5))
+	case cfg.HandlerModeSQS:
+		lambda.Start(withGracefulShutdownResponse(handleSQSRequest, time.Second*5))
+	case cfg.HandlerModeKinesis:
+		lambda.Start(withGracefulShutdownResponse(handleKinesisRequest, time.Second*5))
+	case cfg.HandlerModeFirehose:
+		lambda.Start(withGracefulShutdownResponse(handleFirehoseRequest, time.Second*5))
+	case cfg.HandlerModeEventBridge:
+		lambda.Start(withGracefulShutdown(handleEventBridgeRequest, time.Second*5))
+	case cfg.HandlerModeSNS:
+		lambda.Start(withGracefulShutdown(handleSNSRequest, time.Second*5))
+	case cfg.HandlerModeKafka:
+		lambda.Start(withGracefulShutdown(handleKafkaRequest, time.Second*5))
+	case cfg.HandlerModeFunctionURL:
+		lambda.Start(withGracefulShutdownResponse(handleFunctionURLRequest, time.Second*5))
+	case cfg.HandlerModeAuto:
+		lambda.Start(withGracefulShutdownResponse(handleEvent, time.Second*5))
+	default:
+		lambda.Start(withGracefulShutdown(handleRequest, time.Second*5))
+	}
 }
 
 func init() {
@@ -62,11 +105,65 @@ func init() {
 	if err != nil {
 		log.Fatalf("Failed to create AWS ECS client, err: %v", err)
 	}
+	s3Cl, err := s3.NewClient(config.Region)
+	if err != nil {
+		log.Fatalf("Failed to create AWS S3 client, err: %v", err)
+	}
+	s3Client = s3Cl
 
 	enricher = enrich.NewEnricher(config, resCl, lambdaClient, ecsClient)
 	enricher.StartECSContainerCacheCleanup()
 
-	pusher = push.NewPusher(config)
+	forwarder = forward.NewForwarder(config, push.NewPusher(config))
+	authenticator = webhook.NewAuthenticator(config)
+	otlpServer = otlp.NewServer(enricher, forwarder, authenticator)
+}
+
+// handleEvent detects the event type of the invocation and dispatches it to the matching handler.
+func handleEvent(ctx context.Context, payload json.RawMessage) (any, error) {
+	handlerMode, err := dispatch.Detect(payload)
+	if err != nil {
+		log.Printf("Failed to detect event type, err: %v", err)
+		return nil, err
+	}
+
+	switch handlerMode {
+	case cfg.HandlerModeCloudwatchLogs:
+		return dispatchEvent(ctx, payload, handleRequest)
+	case cfg.HandlerModeS3:
+		return dispatchEvent(ctx, payload, handleS3Request)
+	case cfg.HandlerModeSQS:
+		return dispatchResponse(ctx, payload, handleSQSRequest)
+	case cfg.HandlerModeKinesis:
+		return dispatchResponse(ctx, payload, handleKinesisRequest)
+	case cfg.HandlerModeFirehose:
+		return dispatchResponse(ctx, payload, handleFirehoseRequest)
+	case cfg.HandlerModeEventBridge:
+		return dispatchEvent(ctx, payload, handleEventBridgeRequest)
+	case cfg.HandlerModeSNS:
+		return dispatchEvent(ctx, payload, handleSNSRequest)
+	case cfg.HandlerModeKafka:
+		return dispatchEvent(ctx, payload, handleKafkaRequest)
+	case cfg.HandlerModeFunctionURL:
+		return dispatchResponse(ctx, payload, handleFunctionURLRequest)
+	}
+	return nil, fmt.Errorf("no handler for event type: %s", handlerMode)
+}
+
+func dispatchEvent[T any](ctx context.Context, payload json.RawMessage, handler HandlerFn[T]) (any, error) {
+	var event T
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode %T, err: %v", event, err)
+	}
+	return nil, handler(ctx, event)
+}
+
+func dispatchResponse[T, R any](ctx context.Context, payload json.RawMessage, handler ResponseHandlerFn[T, R]) (any, error) {
+	var event T
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode %T, err: %v", event, err)
+	}
+	return handler(ctx, event)
 }
 
 func handleRequest(ctx context.Context, logsEvent events.CloudwatchLogsEvent) error {
@@ -81,32 +178,24 @@ func handleRequest(ctx context.Context, logsEvent events.CloudwatchLogsEvent) er
 		log.Printf("Failed to parse logs event, err: %v", err)
 		return err
 	}
-	common := enricher.GetEDCommon(ctx, data.SubscriptionFilters, data.MessageType, data.LogGroup, data.LogStream, data.Owner)
-
-	edLog := &core.Log{
-		Common: core.Common(*common),
-		Data: core.Data{
-			LogEvents: data.LogEvents,
-		},
-	}
-
-	logChunker, err := chunker.NewChunker(config.BatchSize, edLog)
+	return forwardCloudwatchLogsData(ctx, &data)
+}
 
-	chunks, err := logChunker.ChunkLogs()
-	if err != nil {
-		log.Printf("Failed to chunk logs, err: %v", err)
-		return err
+func forwardCloudwatchLogsData(ctx context.Context, data *events.CloudwatchLogsData) error {
+	if data.MessageType == cwlogs.ControlMessage {
+		return forwardControlMessage(ctx, data)
 	}
-
-	for i, chunk := range chunks {
-		log.Printf("Sending chunk %d of %d, size: %d bytes", i+1, len(chunks), len(chunk))
-		// blocks until context deadline
-		if err := pusher.Push(ctx, chunk); err != nil {
-			log.Printf("Failed to push chunk %d, err: %v", i+1, err)
+	common := enricher.GetEDCommon(ctx, data.SubscriptionFilters, data.MessageType, data.LogGroup, data.LogStream, data.Owner)
+	for _, b := range enricher.GroupByWebACL(ctx, common, data.LogEvents) {
+		if err := forwarder.Forward(ctx, b.Common, b.LogEvents); err != nil {
 			return err
 		}
 	}
-
-	log.Printf("Successfully pushed %d log chunks", len(chunks))
 	return nil
 }
+
+// forwardControlMessage sends the control message CloudWatch Logs sends on subscription creation as a health event.
+func forwardControlMessage(ctx context.Context, data *events.CloudwatchLogsData) error {
+	common := enricher.GetHealthCommon(ctx, data.MessageType, data.LogGroup, data.Owner, data.SubscriptionFilters)
+	return forwarder.ForwardHealth(ctx, common, cwlogs.GetHealthEvents(data))
+}
@@ -0,0 +1,160 @@
+package metricstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+const (
+	metricStreamARNPrefix = "metric-stream/"
+)
+
+// Metric is a metric data point in CloudWatch metric streams JSON output format.
+type Metric struct {
+	MetricStreamName string             `json:"metric_stream_name"`
+	AccountID        string             `json:"account_id"`
+	Region           string             `json:"region"`
+	Namespace        string             `json:"namespace"`
+	MetricName       string             `json:"metric_name"`
+	Dimensions       map[string]string  `json:"dimensions"`
+	Timestamp        int64              `json:"timestamp"`
+	Value            map[string]float64 `json:"value"`
+	Unit             string             `json:"unit"`
+}
+
+// Decode returns metrics of a Firehose record delivered by a CloudWatch metric stream with the given output format.
+func Decode(data []byte, format string) ([]Metric, error) {
+	switch format {
+	case cfg.MetricStreamFormatJSON:
+		return DecodeJSON(data)
+	case cfg.MetricStreamFormatOpenTelemetry:
+		return DecodeOpenTelemetry(data)
+	default:
+		return nil, fmt.Errorf("unknown metric stream format: %s", format)
+	}
+}
+
+// DecodeJSON decodes newline delimited metrics of JSON output format.
+func DecodeJSON(data []byte) ([]Metric, error) {
+	var metrics []Metric
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var m Metric
+		if err := json.Unmarshal(line, &m); err != nil {
+			return nil, fmt.Errorf("failed to decode metric at line %d, err: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// DecodeOpenTelemetry decodes metrics of OpenTelemetry 1.0 output format, which consists of size delimited
+// ExportMetricsServiceRequest messages. Each CloudWatch metric is a summary data point whose attributes contain
+// namespace, metric name and dimensions.
+func DecodeOpenTelemetry(data []byte) ([]Metric, error) {
+	var metrics []Metric
+	r := bytes.NewReader(data)
+	for {
+		// MetricsData has the same wire format with ExportMetricsServiceRequest
+		var md metricspb.MetricsData
+		if err := protodelim.UnmarshalFrom(r, &md); err != nil {
+			if errors.Is(err, io.EOF) {
+				return metrics, nil
+			}
+			return nil, fmt.Errorf("failed to decode OpenTelemetry metrics, err: %v", err)
+		}
+		for _, rm := range md.GetResourceMetrics() {
+			metrics = append(metrics, convertResourceMetrics(rm)...)
+		}
+	}
+}
+
+func convertResourceMetrics(rm *metricspb.ResourceMetrics) []Metric {
+	var accountID, region, streamName string
+	for _, attr := range rm.GetResource().GetAttributes() {
+		switch attr.GetKey() {
+		case "cloud.account.id":
+			accountID = attr.GetValue().GetStringValue()
+		case "cloud.region":
+			region = attr.GetValue().GetStringValue()
+		case "aws.exporter.arn":
+			// arn:aws:cloudwatch:{region}:{account_id}:metric-stream/{name}
+			arn := attr.GetValue().GetStringValue()
+			if i := strings.Index(arn, metricStreamARNPrefix); i >= 0 {
+				streamName = arn[i+len(metricStreamARNPrefix):]
+			}
+		}
+	}
+
+	var metrics []Metric
+	for _, sm := range rm.GetScopeMetrics() {
+		for _, m := range sm.GetMetrics() {
+			for _, dp := range m.GetSummary().GetDataPoints() {
+				metric := Metric{
+					MetricStreamName: streamName,
+					AccountID:        accountID,
+					Region:           region,
+					Timestamp:        int64(dp.GetTimeUnixNano() / 1e6),
+					Value:            getSummaryValue(dp),
+					Unit:             m.GetUnit(),
+				}
+				for _, attr := range dp.GetAttributes() {
+					switch attr.GetKey() {
+					case "Namespace":
+						metric.Namespace = attr.GetValue().GetStringValue()
+					case "MetricName":
+						metric.MetricName = attr.GetValue().GetStringValue()
+					case "Dimensions":
+						metric.Dimensions = getStringMap(attr.GetValue().GetKvlistValue())
+					}
+				}
+				if metric.MetricName == "" {
+					metric.MetricName = m.GetName()
+				}
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+	return metrics
+}
+
+// getSummaryValue returns statistics with the same keys as JSON output format, 0 and 1 quantiles are min and max.
+func getSummaryValue(dp *metricspb.SummaryDataPoint) map[string]float64 {
+	value := map[string]float64{
+		"count": float64(dp.GetCount()),
+		"sum":   dp.GetSum(),
+	}
+	for _, q := range dp.GetQuantileValues() {
+		switch q.GetQuantile() {
+		case 0:
+			value["min"] = q.GetValue()
+		case 1:
+			value["max"] = q.GetValue()
+		default:
+			value[fmt.Sprintf("p%g", q.GetQuantile()*100)] = q.GetValue()
+		}
+	}
+	return value
+}
+
+func getStringMap(kvs *commonpb.KeyValueList) map[string]string {
+	if len(kvs.GetValues()) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(kvs.GetValues()))
+	for _, kv := range kvs.GetValues() {
+		m[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	return m
+}
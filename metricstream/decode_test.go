@@ -0,0 +1,128 @@
+package metricstream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+const jsonRecord = `{"metric_stream_name":"my-stream","account_id":"123456789012","region":"us-east-1","namespace":"AWS/EC2","metric_name":"CPUUtilization","dimensions":{"InstanceId":"i-0123456789abcdef0"},"timestamp":1611929698000,"value":{"max":10.5,"min":1,"sum":20,"count":4},"unit":"Percent"}
+{"metric_stream_name":"my-stream","account_id":"123456789012","region":"us-east-1","namespace":"AWS/SQS","metric_name":"NumberOfMessagesSent","dimensions":{"QueueName":"my-queue"},"timestamp":1611929698000,"value":{"max":1,"min":1,"sum":3,"count":3,"p99":1},"unit":"Count"}
+`
+
+func TestDecodeJSON(t *testing.T) {
+	metrics, err := Decode([]byte(jsonRecord), cfg.MetricStreamFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, []Metric{
+		{
+			MetricStreamName: "my-stream",
+			AccountID:        "123456789012",
+			Region:           "us-east-1",
+			Namespace:        "AWS/EC2",
+			MetricName:       "CPUUtilization",
+			Dimensions:       map[string]string{"InstanceId": "i-0123456789abcdef0"},
+			Timestamp:        1611929698000,
+			Value:            map[string]float64{"max": 10.5, "min": 1, "sum": 20, "count": 4},
+			Unit:             "Percent",
+		},
+		{
+			MetricStreamName: "my-stream",
+			AccountID:        "123456789012",
+			Region:           "us-east-1",
+			Namespace:        "AWS/SQS",
+			MetricName:       "NumberOfMessagesSent",
+			Dimensions:       map[string]string{"QueueName": "my-queue"},
+			Timestamp:        1611929698000,
+			Value:            map[string]float64{"max": 1, "min": 1, "sum": 3, "count": 3, "p99": 1},
+			Unit:             "Count",
+		},
+	}, metrics)
+
+	_, err = Decode([]byte("not json\n"), cfg.MetricStreamFormatJSON)
+	assert.Error(t, err)
+}
+
+func TestDecodeOpenTelemetry(t *testing.T) {
+	stringValue := func(v string) *commonpb.AnyValue {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	}
+	request := &metricspb.MetricsData{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				{Key: "cloud.provider", Value: stringValue("aws")},
+				{Key: "cloud.account.id", Value: stringValue("123456789012")},
+				{Key: "cloud.region", Value: stringValue("us-east-1")},
+				{Key: "aws.exporter.arn", Value: stringValue("arn:aws:cloudwatch:us-east-1:123456789012:metric-stream/my-stream")},
+			}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "amazonaws.com/AWS/EC2/CPUUtilization",
+					Unit: "%",
+					Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: []*metricspb.SummaryDataPoint{{
+						Attributes: []*commonpb.KeyValue{
+							{Key: "Namespace", Value: stringValue("AWS/EC2")},
+							{Key: "MetricName", Value: stringValue("CPUUtilization")},
+							{Key: "Dimensions", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+								Values: []*commonpb.KeyValue{{Key: "InstanceId", Value: stringValue("i-0123456789abcdef0")}},
+							}}}},
+						},
+						TimeUnixNano: 1611929698000000000,
+						Count:        4,
+						Sum:          20,
+						QuantileValues: []*metricspb.SummaryDataPoint_ValueAtQuantile{
+							{Quantile: 0, Value: 1},
+							{Quantile: 0.99, Value: 10},
+							{Quantile: 1, Value: 10.5},
+						},
+					}}}},
+				}},
+			}},
+		}},
+	}
+
+	// a record may contain multiple size delimited messages
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		_, err := protodelim.MarshalTo(&buf, request)
+		require.NoError(t, err)
+	}
+
+	metrics, err := Decode(buf.Bytes(), cfg.MetricStreamFormatOpenTelemetry)
+	require.NoError(t, err)
+	expected := Metric{
+		MetricStreamName: "my-stream",
+		AccountID:        "123456789012",
+		Region:           "us-east-1",
+		Namespace:        "AWS/EC2",
+		MetricName:       "CPUUtilization",
+		Dimensions:       map[string]string{"InstanceId": "i-0123456789abcdef0"},
+		Timestamp:        1611929698000,
+		Value:            map[string]float64{"max": 10.5, "min": 1, "sum": 20, "count": 4, "p99": 10},
+		Unit:             "%",
+	}
+	assert.Equal(t, []Metric{expected, expected}, metrics)
+
+	_, err = Decode([]byte{0x05, 0x01}, cfg.MetricStreamFormatOpenTelemetry)
+	assert.Error(t, err)
+}
+
+func TestGroupByResource(t *testing.T) {
+	metrics, err := DecodeJSON([]byte(jsonRecord + jsonRecord))
+	require.NoError(t, err)
+
+	groups := groupByResource(metrics)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "AWS/EC2", groups[0].key.namespace)
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0", groups[0].sources[0].ARN)
+	assert.Len(t, groups[0].metrics, 2)
+	assert.Equal(t, "AWS/SQS", groups[1].key.namespace)
+	assert.Equal(t, "arn:aws:sqs:us-east-1:123456789012:my-queue", groups[1].sources[0].ARN)
+	assert.Len(t, groups[1].metrics, 2)
+}
@@ -0,0 +1,87 @@
+package metricstream
+
+import (
+	"context"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/parser"
+	"github.com/edgedelta/edgedelta-forwarder/tag"
+)
+
+// Batch contains metrics of the same resources with the common fields of these resources.
+type Batch struct {
+	Common  *enrich.Common
+	Metrics []core.Metric
+}
+
+type groupKey struct {
+	metricStreamName string
+	accountID        string
+	region           string
+	namespace        string
+	arns             string
+}
+
+type group struct {
+	key     groupKey
+	sources []tag.ServiceInfo
+	metrics []core.Metric
+}
+
+// Enrich groups metrics by their resources and gets common fields of each group,
+// so tags of a resource are attached only to its own metrics.
+func Enrich(ctx context.Context, enricher *enrich.Enricher, metrics []Metric) []Batch {
+	groups := groupByResource(metrics)
+	var sources []tag.ServiceInfo
+	for _, g := range groups {
+		sources = append(sources, g.sources...)
+	}
+	enricher.PrepareSourceTags(ctx, sources)
+
+	batches := make([]Batch, 0, len(groups))
+	for _, g := range groups {
+		batches = append(batches, Batch{
+			Common:  enricher.GetMetricStreamCommon(ctx, g.key.metricStreamName, g.key.accountID, g.key.region, g.key.namespace, g.sources),
+			Metrics: g.metrics,
+		})
+	}
+	return batches
+}
+
+// groupByResource returns groups in the order of their first metric.
+func groupByResource(metrics []Metric) []*group {
+	var groups []*group
+	groupsByKey := make(map[groupKey]*group)
+	for _, m := range metrics {
+		sources, _ := parser.GetSourceARNsFromMetric(m.AccountID, m.Region, m.Namespace, m.Dimensions)
+		arns := make([]string, 0, len(sources))
+		for _, s := range sources {
+			arns = append(arns, s.ARN)
+		}
+
+		key := groupKey{
+			metricStreamName: m.MetricStreamName,
+			accountID:        m.AccountID,
+			region:           m.Region,
+			namespace:        m.Namespace,
+			arns:             strings.Join(arns, ","),
+		}
+		g, ok := groupsByKey[key]
+		if !ok {
+			g = &group{key: key, sources: sources}
+			groupsByKey[key] = g
+			groups = append(groups, g)
+		}
+		g.metrics = append(g.metrics, core.Metric{
+			Namespace:  m.Namespace,
+			Name:       m.MetricName,
+			Dimensions: m.Dimensions,
+			Timestamp:  m.Timestamp,
+			Value:      m.Value,
+			Unit:       m.Unit,
+		})
+	}
+	return groups
+}
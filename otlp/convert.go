@@ -0,0 +1,124 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// Batch is the log records of a resource, records of all scopes of the resource are forwarded together.
+type Batch struct {
+	Resource  map[string]any
+	LogEvents []core.LogEvent
+}
+
+// Convert returns a batch for each resource, received time is used for records without timestamps.
+func Convert(ld *logspb.LogsData, received time.Time) []Batch {
+	var batches []Batch
+	for _, rl := range ld.GetResourceLogs() {
+		batch := Batch{Resource: convertAttributes(rl.GetResource().GetAttributes())}
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				batch.LogEvents = append(batch.LogEvents, convertLogRecord(lr, sl.GetScope(), received))
+			}
+		}
+		if len(batch.LogEvents) > 0 {
+			batches = append(batches, batch)
+		}
+	}
+	return batches
+}
+
+// convertLogRecord uses the body as the message, bodies other than strings are sent as JSON.
+// Record attributes are kept as is, other fields of the record are set as attributes with "otel." prefix.
+func convertLogRecord(lr *logspb.LogRecord, scope *commonpb.InstrumentationScope, received time.Time) core.LogEvent {
+	timestamp := received.UnixMilli()
+	if lr.GetTimeUnixNano() > 0 {
+		timestamp = int64(lr.GetTimeUnixNano() / 1e6)
+	} else if lr.GetObservedTimeUnixNano() > 0 {
+		timestamp = int64(lr.GetObservedTimeUnixNano() / 1e6)
+	}
+
+	var message string
+	if body := lr.GetBody(); body != nil {
+		if s, ok := body.GetValue().(*commonpb.AnyValue_StringValue); ok {
+			message = s.StringValue
+		} else if b, err := json.Marshal(convertValue(body)); err == nil {
+			message = string(b)
+		}
+	}
+
+	attributes := convertAttributes(lr.GetAttributes())
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+	setAttribute := func(key, value string) {
+		if value != "" {
+			attributes[key] = value
+		}
+	}
+	setAttribute("otel.severity_text", lr.GetSeverityText())
+	if lr.GetSeverityNumber() != logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		attributes["otel.severity_number"] = int32(lr.GetSeverityNumber())
+	}
+	setAttribute("otel.trace_id", hex.EncodeToString(lr.GetTraceId()))
+	setAttribute("otel.span_id", hex.EncodeToString(lr.GetSpanId()))
+	setAttribute("otel.scope.name", scope.GetName())
+	setAttribute("otel.scope.version", scope.GetVersion())
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+
+	return core.LogEvent{
+		CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{
+			Timestamp: timestamp,
+			Message:   message,
+		},
+		Attributes: attributes,
+	}
+}
+
+func convertAttributes(kvs []*commonpb.KeyValue) map[string]any {
+	if len(kvs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(kvs))
+	for _, kv := range kvs {
+		m[kv.GetKey()] = convertValue(kv.GetValue())
+	}
+	return m
+}
+
+func convertValue(v *commonpb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return v.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, 0, len(v.ArrayValue.GetValues()))
+		for _, value := range v.ArrayValue.GetValues() {
+			values = append(values, convertValue(value))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		m := convertAttributes(v.KvlistValue.GetValues())
+		if m == nil {
+			m = map[string]any{}
+		}
+		return m
+	default:
+		return nil
+	}
+}
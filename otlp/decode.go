@@ -0,0 +1,107 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+var (
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// mediaType returns the media type of a content type header, i.e. "application/json" of "application/json; charset=utf-8".
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mt
+}
+
+// DecodeRequest decodes the body of an ExportLogsServiceRequest in binary protobuf or JSON encoding.
+// Returned errors other than ErrUnsupportedContentType are caused by the body, so they should be reported as bad request.
+func DecodeRequest(body []byte, contentType string) (*logspb.LogsData, error) {
+	// LogsData has the same wire format with ExportLogsServiceRequest
+	var ld logspb.LogsData
+	switch mediaType(contentType) {
+	case ContentTypeProtobuf:
+		if err := proto.Unmarshal(body, &ld); err != nil {
+			return nil, fmt.Errorf("failed to decode protobuf body, err: %v", err)
+		}
+	case ContentTypeJSON:
+		body, err := convertIDs(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode JSON body, err: %v", err)
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, &ld); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON body, err: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return &ld, nil
+}
+
+// convertIDs converts trace and span IDs from hex, which OTLP JSON encoding uses instead of base64 of protobuf JSON mapping.
+// Numbers are decoded as json.Number, so 64-bit integers given as JSON numbers (i.e. timeUnixNano) keep their precision.
+func convertIDs(body []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	if err := walkIDs(v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func walkIDs(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, value := range v {
+			switch k {
+			case "traceId", "trace_id", "spanId", "span_id":
+				s, ok := value.(string)
+				if !ok {
+					continue
+				}
+				id, err := hex.DecodeString(s)
+				if err != nil {
+					return fmt.Errorf("invalid %s %q, err: %v", k, s, err)
+				}
+				v[k] = base64.StdEncoding.EncodeToString(id)
+			default:
+				if err := walkIDs(value); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		for _, value := range v {
+			if err := walkIDs(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRequestJSONNumbers(t *testing.T) {
+	body := `{"resourceLogs":[{"scopeLogs":[{"logRecords":[{
+		"timeUnixNano": 1704067200123456789,
+		"attributes": [{"key": "id", "value": {"intValue": 9007199254740993}}],
+		"traceId": "5b8efff798038103d269b633813fc60c"
+	}]}]}]}`
+
+	ld, err := DecodeRequest([]byte(body), ContentTypeJSON)
+	require.NoError(t, err)
+	record := ld.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, uint64(1704067200123456789), record.TimeUnixNano)
+	assert.Equal(t, int64(9007199254740993), record.Attributes[0].Value.GetIntValue())
+	assert.Equal(t, []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}, record.TraceId)
+
+	_, err = DecodeRequest([]byte(`{"resourceLogs":[]} {}`), ContentTypeJSON)
+	assert.Error(t, err)
+}
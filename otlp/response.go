@@ -0,0 +1,74 @@
+package otlp
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// gRPC status codes used in error responses
+const (
+	codeInvalidArgument = 3
+	codeUnavailable     = 14
+)
+
+type partialSuccess struct {
+	RejectedLogRecords string `json:"rejectedLogRecords,omitempty"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+}
+
+type exportResponse struct {
+	PartialSuccess *partialSuccess `json:"partialSuccess,omitempty"`
+}
+
+type status struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// encodeExportResponse encodes an ExportLogsServiceResponse, partial success is set only if records are rejected.
+// Messages are encoded by hand, since collector service package depends on gRPC.
+func encodeExportResponse(contentType string, rejected int64, errorMessage string) []byte {
+	if contentType == ContentTypeJSON {
+		var resp exportResponse
+		if rejected > 0 {
+			resp.PartialSuccess = &partialSuccess{RejectedLogRecords: strconv.FormatInt(rejected, 10), ErrorMessage: errorMessage}
+		}
+		b, _ := json.Marshal(resp)
+		return b
+	}
+
+	if rejected == 0 {
+		return nil
+	}
+	// ExportLogsPartialSuccess: rejected_log_records = 1, error_message = 2
+	var ps []byte
+	ps = protowire.AppendTag(ps, 1, protowire.VarintType)
+	ps = protowire.AppendVarint(ps, uint64(rejected))
+	if errorMessage != "" {
+		ps = protowire.AppendTag(ps, 2, protowire.BytesType)
+		ps = protowire.AppendString(ps, errorMessage)
+	}
+	// ExportLogsServiceResponse: partial_success = 1
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, ps)
+}
+
+// encodeStatus encodes a google.rpc.Status, which is the body of failed responses.
+func encodeStatus(contentType string, code int32, message string) []byte {
+	if contentType == ContentTypeJSON {
+		b, _ := json.Marshal(status{Code: code, Message: message})
+		return b
+	}
+
+	// Status: code = 1, message = 2
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(code))
+	if message != "" {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, message)
+	}
+	return b
+}
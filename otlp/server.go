@@ -0,0 +1,144 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/webhook"
+)
+
+const (
+	// LogsPath is the default path of OTLP/HTTP logs endpoint
+	LogsPath = "/v1/logs"
+	// maxRequestBodySize limits compressed request bodies, decompressed bodies are limited by webhook.Decompress
+	maxRequestBodySize = 64 * 1024 * 1024
+)
+
+// Response is an OTLP/HTTP response, body is encoded with the encoding of the request.
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Server implements OTLP/HTTP logs endpoint with binary protobuf and JSON encodings.
+// Log records of each resource are enriched by the resource attributes and forwarded in a batch.
+// If some batches can not be forwarded, their records are reported as rejected in a partial success response,
+// so clients do not send the forwarded records again. If none of the batches can be forwarded, clients are told to retry.
+type Server struct {
+	enricher      *enrich.Enricher
+	forwarder     *forward.Forwarder
+	authenticator *webhook.Authenticator
+}
+
+func NewServer(enricher *enrich.Enricher, forwarder *forward.Forwarder, authenticator *webhook.Authenticator) *Server {
+	return &Server{
+		enricher:      enricher,
+		forwarder:     forwarder,
+		authenticator: authenticator,
+	}
+}
+
+// Export forwards the logs of an export request body and returns the response to send.
+func (s *Server) Export(ctx context.Context, body []byte, contentType, contentEncoding string) *Response {
+	respContentType := ContentTypeProtobuf
+	if mediaType(contentType) == ContentTypeJSON {
+		respContentType = ContentTypeJSON
+	}
+	newErrorResponse := func(statusCode int, code int32, err error) *Response {
+		return &Response{StatusCode: statusCode, ContentType: respContentType, Body: encodeStatus(respContentType, code, err.Error())}
+	}
+
+	body, err := webhook.Decompress(body, contentEncoding)
+	if err != nil {
+		return newErrorResponse(http.StatusBadRequest, codeInvalidArgument, err)
+	}
+	ld, err := DecodeRequest(body, contentType)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedContentType) {
+			return newErrorResponse(http.StatusUnsupportedMediaType, codeInvalidArgument, err)
+		}
+		return newErrorResponse(http.StatusBadRequest, codeInvalidArgument, err)
+	}
+
+	batches := Convert(ld, time.Now())
+	resources := make([]map[string]any, 0, len(batches))
+	for _, batch := range batches {
+		resources = append(resources, batch.Resource)
+	}
+	s.enricher.PrepareOTLPTags(ctx, resources)
+
+	var total, rejected int64
+	var firstErr error
+	for _, batch := range batches {
+		total += int64(len(batch.LogEvents))
+		common := s.enricher.GetOTLPCommon(ctx, batch.Resource)
+		if err := s.forwarder.ForwardEvents(ctx, common, batch.LogEvents); err != nil {
+			rejected += int64(len(batch.LogEvents))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if rejected == 0 {
+		return &Response{StatusCode: http.StatusOK, ContentType: respContentType, Body: encodeExportResponse(respContentType, 0, "")}
+	}
+
+	log.Printf("Failed to forward %d of %d OTLP log records, err: %v", rejected, total, firstErr)
+	if rejected == total {
+		// 503 is retryable, none of the records are forwarded so client can send all of them again
+		return newErrorResponse(http.StatusServiceUnavailable, codeUnavailable, errors.New("failed to forward logs"))
+	}
+	errorMessage := fmt.Sprintf("failed to forward %d log records", rejected)
+	return &Response{StatusCode: http.StatusOK, ContentType: respContentType, Body: encodeExportResponse(respContentType, rejected, errorMessage)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != LogsPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// requests are authenticated with the shared secret or HMAC signature as Function URL requests
+	headers := make(map[string]string, len(r.Header))
+	for k := range r.Header {
+		headers[strings.ToLower(k)] = r.Header.Get(k)
+	}
+	if _, err := s.authenticator.Authenticate(headers, body, false); err != nil {
+		log.Printf("Rejected OTLP request from: %s, err: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	resp := s.Export(r.Context(), body, r.Header.Get("Content-Type"), r.Header.Get("Content-Encoding"))
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.WriteHeader(resp.StatusCode)
+	if _, err := w.Write(resp.Body); err != nil {
+		log.Printf("Failed to write OTLP response, err: %v", err)
+	}
+}
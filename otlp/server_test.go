@@ -0,0 +1,259 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/forward/forwardtest"
+	"github.com/edgedelta/edgedelta-forwarder/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	sharedSecret = "my-secret"
+	jsonRequest  = `{
+		"resourceLogs": [{
+			"resource": {"attributes": [
+				{"key": "service.name", "value": {"stringValue": "checkout"}},
+				{"key": "cloud.account.id", "value": {"stringValue": "123456789012"}},
+				{"key": "cloud.region", "value": {"stringValue": "us-east-1"}},
+				{"key": "host.name", "value": {"stringValue": "my-host"}}
+			]},
+			"scopeLogs": [{
+				"scope": {"name": "my-logger", "version": "1.0.0"},
+				"logRecords": [{
+					"timeUnixNano": "1704067200123000000",
+					"severityNumber": 9,
+					"severityText": "INFO",
+					"body": {"stringValue": "hello"},
+					"attributes": [{"key": "http.status_code", "value": {"intValue": "200"}}],
+					"traceId": "5b8efff798038103d269b633813fc60c",
+					"spanId": "eee19b7ec3c1b174"
+				}]
+			}]
+		}]
+	}`
+)
+
+// failUnavailable fails to push logs of the resource whose service name is "unavailable"
+func failUnavailable(l core.Log) error {
+	if l.Resource["service.name"] == "unavailable" {
+		return errors.New("service unavailable")
+	}
+	return nil
+}
+
+func newResourceLogs(serviceName string, bodies ...*commonpb.AnyValue) *logspb.ResourceLogs {
+	var records []*logspb.LogRecord
+	for _, b := range bodies {
+		records = append(records, &logspb.LogRecord{ObservedTimeUnixNano: 1704067200000000000, Body: b})
+	}
+	return &logspb.ResourceLogs{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: serviceName}}},
+		}},
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}},
+	}
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func TestServer(t *testing.T) {
+	kvlistBody := &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: []*commonpb.KeyValue{
+		{Key: "event", Value: stringValue("login")},
+		{Key: "success", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+	}}}}
+	marshal := func(ld *logspb.LogsData) []byte {
+		b, err := proto.Marshal(ld)
+		require.NoError(t, err)
+		return b
+	}
+
+	tests := []struct {
+		desc               string
+		path               string
+		contentType        string
+		body               []byte
+		expectedStatusCode int
+		expectedRejected   int64
+		expectedLogs       []core.Log
+		// unauthenticated sends the request without the shared secret
+		unauthenticated bool
+	}{
+		{
+			desc:               "Protobuf",
+			path:               LogsPath,
+			contentType:        ContentTypeProtobuf,
+			body:               marshal(&logspb.LogsData{ResourceLogs: []*logspb.ResourceLogs{newResourceLogs("checkout", stringValue("hello"), kvlistBody)}}),
+			expectedStatusCode: http.StatusOK,
+			expectedLogs: []core.Log{{
+				Common: core.Common{Resource: map[string]any{"service.name": "checkout"}},
+				Data: core.Data{LogEvents: []core.LogEvent{
+					{CloudwatchLogsLogEvent: newEvent(1704067200000, "hello")},
+					{CloudwatchLogsLogEvent: newEvent(1704067200000, `{"event":"login","success":true}`)},
+				}},
+			}},
+		},
+		{
+			desc:               "JSON",
+			path:               LogsPath,
+			contentType:        "application/json; charset=utf-8",
+			body:               []byte(jsonRequest),
+			expectedStatusCode: http.StatusOK,
+			expectedLogs: []core.Log{{
+				Common: core.Common{HostName: "my-host", Resource: map[string]any{"service.name": "checkout"}},
+				Data: core.Data{LogEvents: []core.LogEvent{{
+					CloudwatchLogsLogEvent: newEvent(1704067200123, "hello"),
+					Attributes: map[string]any{
+						"http.status_code":     float64(200),
+						"otel.severity_text":   "INFO",
+						"otel.severity_number": float64(9),
+						"otel.trace_id":        "5b8efff798038103d269b633813fc60c",
+						"otel.span_id":         "eee19b7ec3c1b174",
+						"otel.scope.name":      "my-logger",
+						"otel.scope.version":   "1.0.0",
+					},
+				}}},
+			}},
+		},
+		{
+			desc:        "Partial success",
+			path:        LogsPath,
+			contentType: ContentTypeProtobuf,
+			body: marshal(&logspb.LogsData{ResourceLogs: []*logspb.ResourceLogs{
+				newResourceLogs("checkout", stringValue("hello")),
+				newResourceLogs("unavailable", stringValue("a"), stringValue("b")),
+			}}),
+			expectedStatusCode: http.StatusOK,
+			expectedRejected:   2,
+			expectedLogs: []core.Log{{
+				Common: core.Common{Resource: map[string]any{"service.name": "checkout"}},
+				Data:   core.Data{LogEvents: []core.LogEvent{{CloudwatchLogsLogEvent: newEvent(1704067200000, "hello")}}},
+			}},
+		},
+		{
+			desc:               "All records are rejected",
+			path:               LogsPath,
+			contentType:        ContentTypeProtobuf,
+			body:               marshal(&logspb.LogsData{ResourceLogs: []*logspb.ResourceLogs{newResourceLogs("unavailable", stringValue("a"))}}),
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:               "Invalid body",
+			path:               LogsPath,
+			contentType:        ContentTypeJSON,
+			body:               []byte(`{"resourceLogs": [`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc:               "Unsupported content type",
+			path:               LogsPath,
+			contentType:        "text/plain",
+			body:               []byte("hello"),
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			desc:               "Missing shared secret",
+			path:               LogsPath,
+			contentType:        ContentTypeProtobuf,
+			body:               marshal(&logspb.LogsData{ResourceLogs: []*logspb.ResourceLogs{newResourceLogs("checkout", stringValue("hello"))}}),
+			expectedStatusCode: http.StatusUnauthorized,
+			unauthenticated:    true,
+		},
+		{
+			desc:               "Unknown path",
+			path:               "/v1/traces",
+			contentType:        ContentTypeProtobuf,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			pusher := &forwardtest.Endpoint{Fail: failUnavailable}
+			conf := &cfg.Config{
+				Region:           "us-west-2",
+				BatchSize:        cfg.MaxChunkSize,
+				HTTPSharedSecret: sharedSecret,
+			}
+			enricher, forwarder := forwardtest.NewForwarder(conf, pusher)
+			server := httptest.NewServer(NewServer(enricher, forwarder, webhook.NewAuthenticator(conf)))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+tt.path, bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+			if !tt.unauthenticated {
+				req.Header.Set("Authorization", "Bearer "+sharedSecret)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatusCode, resp.StatusCode, string(respBody))
+
+			if tt.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, tt.expectedRejected, decodeRejected(t, resp.Header.Get("Content-Type"), respBody))
+			}
+
+			logs := pusher.Logs()
+			require.Len(t, logs, len(tt.expectedLogs))
+			for i, l := range logs {
+				assert.Equal(t, tt.expectedLogs[i].LogEvents, l.LogEvents)
+				assert.Equal(t, tt.expectedLogs[i].Resource, l.Resource)
+				assert.Equal(t, tt.expectedLogs[i].HostName, l.HostName)
+			}
+			if tt.desc == "JSON" {
+				assert.Equal(t, "123456789012", logs[0].Cloud.AccountID)
+				assert.Equal(t, "us-east-1", logs[0].Cloud.Region)
+			}
+		})
+	}
+}
+
+// decodeRejected returns rejected log records of an export response.
+func decodeRejected(t *testing.T, contentType string, body []byte) int64 {
+	if contentType == ContentTypeJSON {
+		var resp struct {
+			PartialSuccess struct {
+				RejectedLogRecords int64 `json:"rejectedLogRecords,string"`
+			} `json:"partialSuccess"`
+		}
+		require.NoError(t, json.Unmarshal(body, &resp))
+		return resp.PartialSuccess.RejectedLogRecords
+	}
+	if len(body) == 0 {
+		return 0
+	}
+
+	_, _, n := protowire.ConsumeTag(body)
+	partialSuccess, _ := protowire.ConsumeBytes(body[n:])
+	_, _, n = protowire.ConsumeTag(partialSuccess)
+	rejected, m := protowire.ConsumeVarint(partialSuccess[n:])
+	require.Positive(t, m)
+	_, _, n2 := protowire.ConsumeTag(partialSuccess[n+m:])
+	message, _ := protowire.ConsumeString(partialSuccess[n+m+n2:])
+	assert.NotEmpty(t, message)
+	return int64(rejected)
+}
+
+func newEvent(timestamp int64, message string) events.CloudwatchLogsLogEvent {
+	return events.CloudwatchLogsLogEvent{Timestamp: timestamp, Message: message}
+}
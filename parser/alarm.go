@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ParseCloudWatchAlarm parses the message CloudWatch alarms publish to SNS topics on state changes,
+// false is returned if message is not an alarm notification.
+func ParseCloudWatchAlarm(message string) (*events.CloudWatchAlarmSNSPayload, bool) {
+	if len(message) == 0 || message[0] != '{' {
+		return nil, false
+	}
+	var alarm events.CloudWatchAlarmSNSPayload
+	if err := json.Unmarshal([]byte(message), &alarm); err != nil {
+		return nil, false
+	}
+	if alarm.AlarmName == "" || alarm.NewStateValue == "" {
+		return nil, false
+	}
+	return &alarm, true
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCloudWatchAlarm(t *testing.T) {
+	tests := []struct {
+		desc              string
+		message           string
+		expectedFound     bool
+		expectedName      string
+		expectedState     string
+		expectedNamespace string
+	}{
+		{
+			desc:              "Metric alarm",
+			message:           `{"AlarmName":"high-cpu","AlarmDescription":"CPU is high","AWSAccountId":"123456789012","NewStateValue":"ALARM","NewStateReason":"Threshold Crossed","StateChangeTime":"2024-01-01T00:00:00.000+0000","Region":"US West (Oregon)","AlarmArn":"arn:aws:cloudwatch:us-west-2:123456789012:alarm:high-cpu","OldStateValue":"OK","Trigger":{"MetricName":"CPUUtilization","Namespace":"AWS/EC2","Statistic":"AVERAGE","Dimensions":[{"name":"InstanceId","value":"i-1234567890"}],"Period":300,"EvaluationPeriods":1,"ComparisonOperator":"GreaterThanThreshold","Threshold":80.0}}`,
+			expectedFound:     true,
+			expectedName:      "high-cpu",
+			expectedState:     "ALARM",
+			expectedNamespace: "AWS/EC2",
+		},
+		{
+			desc:          "JSON message which is not an alarm",
+			message:       `{"order_id":"1234","status":"shipped"}`,
+			expectedFound: false,
+		},
+		{
+			desc:          "Plain text message",
+			message:       "deployment finished",
+			expectedFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			alarm, found := ParseCloudWatchAlarm(tt.message)
+			assert.Equal(t, tt.expectedFound, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tt.expectedName, alarm.AlarmName)
+			assert.Equal(t, tt.expectedState, alarm.NewStateValue)
+			assert.Equal(t, tt.expectedNamespace, alarm.Trigger.Namespace)
+		})
+	}
+}
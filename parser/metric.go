@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/edgedelta/edgedelta-forwarder/tag"
+)
+
+// metricResourceDimension maps a dimension of a CloudWatch namespace to the ARN of the resource it identifies.
+type metricResourceDimension struct {
+	dimension string
+	buildARN  func(value, accountID, region string) string
+}
+
+func resourceARNBuilder(service, format string) func(value, accountID, region string) string {
+	return func(value, accountID, region string) string {
+		return BuildResourceARN(service, accountID, region, fmt.Sprintf(format, value))
+	}
+}
+
+var (
+	metricNamespaceResources = map[string][]metricResourceDimension{
+		"AWS/EC2":            {{"InstanceId", resourceARNBuilder("ec2", "instance/%s")}},
+		"AWS/EBS":            {{"VolumeId", resourceARNBuilder("ec2", "volume/%s")}},
+		"AWS/Lambda":         {{"FunctionName", resourceARNBuilder("lambda", "function:%s")}},
+		"AWS/SQS":            {{"QueueName", resourceARNBuilder("sqs", "%s")}},
+		"AWS/SNS":            {{"TopicName", resourceARNBuilder("sns", "%s")}},
+		"AWS/DynamoDB":       {{"TableName", resourceARNBuilder("dynamodb", "table/%s")}},
+		"AWS/Kinesis":        {{"StreamName", resourceARNBuilder("kinesis", "stream/%s")}},
+		"AWS/Firehose":       {{"DeliveryStreamName", resourceARNBuilder("firehose", "deliverystream/%s")}},
+		"AWS/Logs":           {{"LogGroupName", resourceARNBuilder("logs", "log-group:%s")}},
+		"AWS/Events":         {{"RuleName", resourceARNBuilder("events", "rule/%s")}},
+		"AWS/ElastiCache":    {{"CacheClusterId", resourceARNBuilder("elasticache", "cluster:%s")}},
+		"ContainerInsights":  {{"ClusterName", resourceARNBuilder("eks", "cluster/%s")}},
+		"AWS/S3":             {{"BucketName", func(value, _, _ string) string { return BuildS3BucketARN(value) }}},
+		"AWS/States":         {{"StateMachineArn", func(value, _, _ string) string { return value }}},
+		"AWS/ApplicationELB": loadBalancerResources,
+		"AWS/NetworkELB":     loadBalancerResources,
+		"AWS/RDS": {
+			{"DBInstanceIdentifier", resourceARNBuilder("rds", "db:%s")},
+			{"DBClusterIdentifier", resourceARNBuilder("rds", "cluster:%s")},
+		},
+		"AWS/ECS": {
+			{"ClusterName", resourceARNBuilder("ecs", "cluster/%s")},
+		},
+	}
+
+	// load balancer and target group dimensions are in app/{name}/{id} and targetgroup/{name}/{id} format
+	loadBalancerResources = []metricResourceDimension{
+		{"LoadBalancer", resourceARNBuilder("elasticloadbalancing", "loadbalancer/%s")},
+		{"TargetGroup", resourceARNBuilder("elasticloadbalancing", "%s")},
+	}
+)
+
+// GetSourceARNsFromMetric returns the resources a CloudWatch metric belongs to by using its namespace and dimensions,
+// so metrics get the same source tags as the logs of the resource.
+func GetSourceARNsFromMetric(accountID, region, namespace string, dimensions map[string]string) ([]tag.ServiceInfo, bool) {
+	var services []tag.ServiceInfo
+	for _, r := range metricNamespaceResources[namespace] {
+		value := dimensions[r.dimension]
+		if value == "" {
+			continue
+		}
+		if s, ok := GetServiceInfoFromARN(r.buildARN(value, accountID, region)); ok {
+			services = append(services, s)
+		}
+	}
+
+	// service metrics are reported both per cluster and per service
+	if namespace == "AWS/ECS" && dimensions["ClusterName"] != "" && dimensions["ServiceName"] != "" {
+		services = append(services, tag.ServiceInfo{
+			Name: tag.SourceECSService,
+			ARN:  BuildResourceARN("ecs", accountID, region, fmt.Sprintf("service/%s/%s", dimensions["ClusterName"], dimensions["ServiceName"])),
+		})
+	}
+
+	return services, len(services) > 0
+}
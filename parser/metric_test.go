@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/edgedelta/edgedelta-forwarder/tag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSourceARNsFromMetric(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespace     string
+		dimensions    map[string]string
+		expected      []tag.ServiceInfo
+		expectedFound bool
+	}{
+		{
+			name:          "ec2 instance",
+			namespace:     "AWS/EC2",
+			dimensions:    map[string]string{"InstanceId": "i-0123456789abcdef0"},
+			expected:      []tag.ServiceInfo{{Name: tag.SourceEC2, ARN: "arn:aws:ec2:us-west-2:123456789012:instance/i-0123456789abcdef0"}},
+			expectedFound: true,
+		},
+		{
+			name:          "lambda function",
+			namespace:     "AWS/Lambda",
+			dimensions:    map[string]string{"FunctionName": "my-function", "Resource": "my-function"},
+			expected:      []tag.ServiceInfo{{Name: tag.SourceLambda, ARN: "arn:aws:lambda:us-west-2:123456789012:function:my-function"}},
+			expectedFound: true,
+		},
+		{
+			name:          "s3 bucket",
+			namespace:     "AWS/S3",
+			dimensions:    map[string]string{"BucketName": "my-bucket", "StorageType": "StandardStorage"},
+			expected:      []tag.ServiceInfo{{Name: tag.SourceS3, ARN: "arn:aws:s3:::my-bucket"}},
+			expectedFound: true,
+		},
+		{
+			name:       "application load balancer and target group",
+			namespace:  "AWS/ApplicationELB",
+			dimensions: map[string]string{"LoadBalancer": "app/my-alb/50dc6c495c0c9188", "TargetGroup": "targetgroup/my-tg/73e2d6bc24d8a067"},
+			expected: []tag.ServiceInfo{
+				{Name: "elasticloadbalancing", ARN: "arn:aws:elasticloadbalancing:us-west-2:123456789012:loadbalancer/app/my-alb/50dc6c495c0c9188"},
+				{Name: "elasticloadbalancing", ARN: "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/my-tg/73e2d6bc24d8a067"},
+			},
+			expectedFound: true,
+		},
+		{
+			name:       "ecs service",
+			namespace:  "AWS/ECS",
+			dimensions: map[string]string{"ClusterName": "my-cluster", "ServiceName": "my-service"},
+			expected: []tag.ServiceInfo{
+				{Name: tag.SourceECSCluster, ARN: "arn:aws:ecs:us-west-2:123456789012:cluster/my-cluster"},
+				{Name: tag.SourceECSService, ARN: "arn:aws:ecs:us-west-2:123456789012:service/my-cluster/my-service"},
+			},
+			expectedFound: true,
+		},
+		{
+			name:       "missing dimension",
+			namespace:  "AWS/EC2",
+			dimensions: map[string]string{"AutoScalingGroupName": "my-asg"},
+		},
+		{
+			name:       "unknown namespace",
+			namespace:  "MyApp",
+			dimensions: map[string]string{"InstanceId": "i-0123456789abcdef0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, found := GetSourceARNsFromMetric("123456789012", "us-west-2", tt.namespace, tt.dimensions)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expected, services)
+		})
+	}
+}
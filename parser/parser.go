@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 
 	"github.com/edgedelta/edgedelta-forwarder/tag"
@@ -25,9 +26,7 @@ var (
 		"kinesis":          {"stream/"},
 		"docdb":            {"cluster/"},
 		"network-firewall": {"firewall"},
-		"route53":          {"hostedzone", "change"},
 		"vpc":              {NoSuffix},
-		"cloudtrail":       {"trail"},
 		"msk":              {"cluster"},
 		"elasticsearch":    {"es"},
 		"transitgateway":   {"tgw"},
@@ -37,6 +36,11 @@ var (
 	ResourceSuffixToDeleteFromLogGroup = map[string]string{
 		"eks": "/cluster",
 	}
+
+	// Route53PublicLogStreamRegex matches public DNS query log streams, which are named {hosted_zone_id}/{edge_location}
+	Route53PublicLogStreamRegex = regexp.MustCompile(`^Z[A-Z0-9]+/[A-Z0-9-]+$`)
+	// Route53ResolverConfigIDRegex matches ID of a resolver query logging configuration, i.e. rslvr-rqlc-0123456789abcdef
+	Route53ResolverConfigIDRegex = regexp.MustCompile(`rslvr-rqlc-[0-9a-f]+`)
 )
 
 func buildSagemakerARN(trimmedGroup, logStream, accountID, region string) tag.ServiceInfo {
@@ -138,6 +142,37 @@ func buildSNSARN(trimmedGroup, accountID, region string) tag.ServiceInfo {
 	return service
 }
 
+// buildCloudTrailARN returns the ARN of the trail whose log group is /aws/cloudtrail/{trail_name}[/...].
+// Trails log to a log group of any name, so only log groups named after the trail are mapped to it,
+// trails of other log groups are not looked up.
+func buildCloudTrailARN(trimmedGroup, accountID, region string) tag.ServiceInfo {
+	trailName, _, _ := strings.Cut(trimmedGroup, "/")
+	return tag.ServiceInfo{
+		Name: tag.SourceCloudTrail,
+		ARN:  BuildResourceARN("cloudtrail", accountID, region, fmt.Sprintf("trail/%s", trailName)),
+	}
+}
+
+// buildRoute53ARNs returns the ARN of the hosted zone of public DNS query logs, whose log streams are named
+// {hosted_zone_id}/{edge_location}, or of the resolver query logging configuration whose ID is in the log stream.
+// Hosted zone ARNs do not contain region and account.
+func buildRoute53ARNs(logStream, accountID, region string) []tag.ServiceInfo {
+	if Route53PublicLogStreamRegex.MatchString(logStream) {
+		hostedZoneID, _, _ := strings.Cut(logStream, "/")
+		return []tag.ServiceInfo{{
+			Name: tag.SourceRoute53,
+			ARN:  fmt.Sprintf("arn:aws:route53:::hostedzone/%s", hostedZoneID),
+		}}
+	}
+	if configID := Route53ResolverConfigIDRegex.FindString(logStream); configID != "" {
+		return []tag.ServiceInfo{{
+			Name: tag.SourceRoute53Resolver,
+			ARN:  BuildResourceARN("route53resolver", accountID, region, fmt.Sprintf("resolver-query-log-config/%s", configID)),
+		}}
+	}
+	return nil
+}
+
 func findSourceFromLogGroup(logGroup string) (string, string, bool) {
 	trimPrefixFunc := func(prefix string) string {
 		return strings.TrimPrefix(logGroup, prefix)
@@ -219,6 +254,17 @@ func GetSourceARNsFromLogGroup(accountID, region, logGroup, logStream string) ([
 	if hasPrefixFunc("/ec2/") {
 		return []tag.ServiceInfo{buildEC2ARN(trimPrefixFunc("/ec2/"), accountID, region)}, true
 	}
+	if hasPrefixFunc("/aws/cloudtrail/") {
+		return []tag.ServiceInfo{buildCloudTrailARN(trimPrefixFunc("/aws/cloudtrail/"), accountID, region)}, true
+	}
+	// query log groups are named after the domain, not the hosted zone or the resolver query logging configuration
+	if hasPrefixFunc("/aws/route53") {
+		return buildRoute53ARNs(logStream, accountID, region), true
+	}
+	// WAF log groups are not named after the web ACL, its ARN is read from the logs
+	if hasPrefixFunc("aws-waf-logs-") {
+		return nil, true
+	}
 
 	return buildGenericARN(logGroup, accountID, region)
 }
@@ -227,6 +273,81 @@ func BuildResourceARN(service, accountID, region, resource string) string {
 	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, region, accountID, resource)
 }
 
+// BuildS3BucketARN returns bucket ARN, S3 bucket ARNs do not contain region and account.
+func BuildS3BucketARN(bucket string) string {
+	return fmt.Sprintf("arn:aws:s3:::%s", bucket)
+}
+
+// GetLoadBalancerARNFromS3Key returns the ARN of the load balancer whose access log is the given S3 object,
+// object name is {account_id}_elasticloadbalancing_{region}_{app|net}.{name}.{id}_{end_time}_{ip}_{random}.log.gz
+func GetLoadBalancerARNFromS3Key(key string) (string, bool) {
+	name := key[strings.LastIndexByte(key, '/')+1:]
+	parts := strings.SplitN(name, "_", 5)
+	if len(parts) < 5 || parts[1] != "elasticloadbalancing" {
+		return "", false
+	}
+	lb := strings.Split(parts[3], ".")
+	if len(lb) != 3 || (lb[0] != "app" && lb[0] != "net") {
+		return "", false
+	}
+	return BuildResourceARN("elasticloadbalancing", parts[0], parts[2], fmt.Sprintf("loadbalancer/%s/%s/%s", lb[0], lb[1], lb[2])), true
+}
+
+// GetCloudFrontDistributionIDFromS3Key returns the ID of the distribution whose standard log is the given S3 object,
+// object name is {distribution_id}.{yyyy}-{mm}-{dd}-{hh}.{unique_id}.gz
+func GetCloudFrontDistributionIDFromS3Key(key string) (string, bool) {
+	name := key[strings.LastIndexByte(key, '/')+1:]
+	parts := strings.Split(name, ".")
+	if len(parts) != 4 || parts[3] != "gz" || len(parts[1]) != len("yyyy-mm-dd-hh") || parts[0] == "" {
+		return "", false
+	}
+	if strings.ToUpper(parts[0]) != parts[0] {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// BuildCloudFrontDistributionARN returns distribution ARN, CloudFront ARNs do not contain region.
+func BuildCloudFrontDistributionARN(accountID, distributionID string) string {
+	return fmt.Sprintf("arn:aws:cloudfront::%s:distribution/%s", accountID, distributionID)
+}
+
+// GetAccountIDFromARN returns account ID part of the given ARN, empty string if it is not found.
+func GetAccountIDFromARN(arn string) string {
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+// GetServiceInfoFromARN returns the source of the given ARN to get its tags, i.e. resources of an EventBridge event.
+func GetServiceInfoFromARN(arn string) (tag.ServiceInfo, bool) {
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" || parts[2] == "" {
+		return tag.ServiceInfo{}, false
+	}
+
+	service, resource := parts[2], parts[5]
+	name := tag.Source(service)
+	switch service {
+	case "ecs":
+		if strings.HasPrefix(resource, "task/") {
+			name = tag.SourceECSTask
+		} else if strings.HasPrefix(resource, "cluster/") {
+			name = tag.SourceECSCluster
+		} else if strings.HasPrefix(resource, "service/") {
+			name = tag.SourceECSService
+		}
+	case "logs":
+		name = tag.SourceLogGroup
+	}
+
+	return tag.ServiceInfo{Name: name, ARN: arn}, true
+}
+
 func GetFunctionARNAndNameIfSourceIsLambda(logGroup, accountID, region string) (string, string, bool) {
 	if service, resourceName, ok := findSourceFromLogGroup(logGroup); ok && service == "lambda" {
 		return BuildResourceARN(service, accountID, region, resourceName), resourceName, true
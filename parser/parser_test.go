@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/edgedelta/edgedelta-forwarder/tag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,3 +37,122 @@ func TestFindSourceFromLogGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestGetServiceInfoFromARN(t *testing.T) {
+	tests := []struct {
+		arn           string
+		expectedName  tag.Source
+		expectedFound bool
+	}{
+		{"arn:aws:ecs:us-west-2:123456789012:task/my-cluster/1234567890", tag.SourceECSTask, true},
+		{"arn:aws:ecs:us-west-2:123456789012:cluster/my-cluster", tag.SourceECSCluster, true},
+		{"arn:aws:ecs:us-west-2:123456789012:service/my-cluster/my-service", tag.SourceECSService, true},
+		{"arn:aws:ec2:us-west-2:123456789012:instance/i-1234567890", tag.SourceEC2, true},
+		{"arn:aws:logs:us-west-2:123456789012:log-group:my-log-group", tag.SourceLogGroup, true},
+		{"arn:aws:s3:::my-bucket", tag.SourceS3, true},
+		{"arn:aws:guardduty:us-west-2:123456789012:detector/abc", "guardduty", true},
+		{"i-1234567890", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arn, func(t *testing.T) {
+			info, found := GetServiceInfoFromARN(tt.arn)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expectedName, info.Name)
+			if found {
+				assert.Equal(t, tt.arn, info.ARN)
+			}
+		})
+	}
+}
+
+func TestGetSourceARNsFromLogGroup(t *testing.T) {
+	tests := []struct {
+		logGroup      string
+		logStream     string
+		expected      []tag.ServiceInfo
+		expectedFound bool
+	}{
+		{
+			"/aws/cloudtrail/my-trail", "123456789012_CloudTrail_us-west-2",
+			[]tag.ServiceInfo{{Name: tag.SourceCloudTrail, ARN: "arn:aws:cloudtrail:us-west-2:123456789012:trail/my-trail"}},
+			true,
+		},
+		{
+			"/aws/lambda/my-function", "2024/01/01/[$LATEST]abc",
+			[]tag.ServiceInfo{{Name: "lambda", ARN: "arn:aws:lambda:us-west-2:123456789012:function:my-function"}},
+			true,
+		},
+		{
+			"/aws/route53/example.com", "Z123412341234/FRA6",
+			[]tag.ServiceInfo{{Name: tag.SourceRoute53, ARN: "arn:aws:route53:::hostedzone/Z123412341234"}},
+			true,
+		},
+		{
+			"/aws/route53resolver/queries", "vpc-0123456789abcdef0_rslvr-rqlc-0123456789abcdef",
+			[]tag.ServiceInfo{{Name: tag.SourceRoute53Resolver, ARN: "arn:aws:route53resolver:us-west-2:123456789012:resolver-query-log-config/rslvr-rqlc-0123456789abcdef"}},
+			true,
+		},
+		{"/aws/route53/example.com", "stream", nil, true},
+		{"aws-waf-logs-my-acl", "us-west-2_my-acl_0", nil, true},
+		{"aws-cloudtrail-logs-123456789012-1a2b3c4d", "123456789012_CloudTrail_us-west-2", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.logGroup, func(t *testing.T) {
+			services, found := GetSourceARNsFromLogGroup("123456789012", "us-west-2", tt.logGroup, tt.logStream)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expected, services)
+		})
+	}
+}
+
+func TestGetLoadBalancerARNFromS3Key(t *testing.T) {
+	tests := []struct {
+		key           string
+		expectedARN   string
+		expectedFound bool
+	}{
+		{
+			"AWSLogs/123456789012/elasticloadbalancing/us-east-2/2024/01/01/123456789012_elasticloadbalancing_us-east-2_app.my-loadbalancer.50dc6c495c0c9188_20240101T0000Z_192.168.131.39_2hiyxfm2.log.gz",
+			"arn:aws:elasticloadbalancing:us-east-2:123456789012:loadbalancer/app/my-loadbalancer/50dc6c495c0c9188",
+			true,
+		},
+		{
+			"prefix/AWSLogs/123456789012/elasticloadbalancing/eu-west-1/2024/01/01/123456789012_elasticloadbalancing_eu-west-1_net.my-nlb.c6e77e28c25b2234_20240101T0000Z_1a2b3c4d.log.gz",
+			"arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/net/my-nlb/c6e77e28c25b2234",
+			true,
+		},
+		{"AWSLogs/123456789012/vpcflowlogs/us-east-2/2024/01/01/123456789012_vpcflowlogs_us-east-2_fl-1234abcd_20240101T0000Z_1a2b3c4d.log.gz", "", false},
+		{"logs/app.log", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			arn, found := GetLoadBalancerARNFromS3Key(tt.key)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expectedARN, arn)
+		})
+	}
+}
+
+func TestGetCloudFrontDistributionIDFromS3Key(t *testing.T) {
+	tests := []struct {
+		key                    string
+		expectedDistributionID string
+		expectedFound          bool
+	}{
+		{"EMLARXS9EXAMPLE.2019-11-14-20.RT4KCN4SGK9.gz", "EMLARXS9EXAMPLE", true},
+		{"cloudfront/EMLARXS9EXAMPLE.2019-11-14-20.RT4KCN4SGK9.gz", "EMLARXS9EXAMPLE", true},
+		{"cloudfront/app.2019-11-14-20.log.gz", "", false},
+		{"logs/app.log", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			id, found := GetCloudFrontDistributionIDFromS3Key(tt.key)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expectedDistributionID, id)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package replay
+
+import (
+	"github.com/edgedelta/edgedelta-forwarder/utils"
+)
+
+// Checkpoint keeps the last replayed line of each file, so an interrupted replay resumes where it stopped.
+type Checkpoint struct {
+	files *utils.Checkpoint[fileProgress]
+}
+
+type fileProgress struct {
+	Line int  `json:"line"`
+	Done bool `json:"done,omitempty"`
+}
+
+// LoadCheckpoint reads the checkpoint file at path, a missing file is an empty checkpoint.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	files, err := utils.LoadCheckpoint[fileProgress](path, "files")
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{files: files}, nil
+}
+
+// Progress returns the last replayed line of the file and whether the file is completely replayed.
+func (c *Checkpoint) Progress(file string) (int, bool) {
+	p := c.files.Get(file)
+	return p.Line, p.Done
+}
+
+// Save records the last replayed line of the file and writes the checkpoint file.
+func (c *Checkpoint) Save(file string, line int, done bool) error {
+	return c.files.Save(file, fileProgress{Line: line, Done: done})
+}
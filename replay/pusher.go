@@ -0,0 +1,56 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+)
+
+// WriterPusher writes each chunk as a line instead of pushing it, it is used to print envelopes in dry run.
+type WriterPusher struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterPusher(w io.Writer) *WriterPusher {
+	return &WriterPusher{w: w}
+}
+
+func (p *WriterPusher) Push(ctx context.Context, payload []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, err := p.w.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("failed to write chunk, err: %v", err)
+	}
+	return nil
+}
+
+// RateLimitedPusher limits the number of chunks pushed per second, so replaying a backlog does not overload the endpoint.
+type RateLimitedPusher struct {
+	pusher forward.Pusher
+	ticker *time.Ticker
+}
+
+func NewRateLimitedPusher(pusher forward.Pusher, chunksPerSecond float64) *RateLimitedPusher {
+	return &RateLimitedPusher{
+		pusher: pusher,
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / chunksPerSecond)),
+	}
+}
+
+func (p *RateLimitedPusher) Push(ctx context.Context, payload []byte) error {
+	select {
+	case <-p.ticker.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.pusher.Push(ctx, payload)
+}
+
+func (p *RateLimitedPusher) Stop() {
+	p.ticker.Stop()
+}
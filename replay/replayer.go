@@ -0,0 +1,351 @@
+package replay
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cwlogs"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/s3"
+)
+
+const (
+	// FormatAuto detects the format of each file from its first line
+	FormatAuto = "auto"
+	// FormatCloudwatch is captured CloudWatch Logs subscription payloads, one per line
+	FormatCloudwatch = "cloudwatch"
+	// FormatExport is log files exported by CloudWatch Logs to S3, lines are prefixed with RFC3339 timestamp
+	FormatExport = "export"
+	// FormatNDJSON is newline delimited JSON, one log event per line
+	FormatNDJSON = "ndjson"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown input format")
+)
+
+// Options of a replay, log group, log stream and account ID are used to enrich exported and NDJSON logs
+// since CloudWatch payloads carry their own.
+type Options struct {
+	Format    string
+	LogGroup  string
+	LogStream string
+	AccountID string
+	// Checkpoint is optional, replay starts from the beginning of files without it
+	Checkpoint *Checkpoint
+	// DryRun does not update the checkpoint
+	DryRun bool
+}
+
+// Replayer re-sends captured or exported logs through the same enrichment and chunking as the forwarder.
+type Replayer struct {
+	enricher  *enrich.Enricher
+	forwarder *forward.Forwarder
+	opts      Options
+}
+
+func NewReplayer(enricher *enrich.Enricher, forwarder *forward.Forwarder, opts Options) *Replayer {
+	if opts.Format == "" {
+		opts.Format = FormatAuto
+	}
+	return &Replayer{
+		enricher:  enricher,
+		forwarder: forwarder,
+		opts:      opts,
+	}
+}
+
+// Replay replays files in the given order, directories are walked recursively in lexical order.
+func (r *Replayer) Replay(ctx context.Context, paths []string) error {
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return r.ReplayFile(ctx, file)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplayFile replays a file line by line, gzip compressed files are decompressed on the fly.
+// Lines before the checkpoint of the file are skipped and checkpoint is updated after every push.
+func (r *Replayer) ReplayFile(ctx context.Context, path string) error {
+	file, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of %s, err: %v", path, err)
+	}
+	var lastLine int
+	if r.opts.Checkpoint != nil {
+		var done bool
+		lastLine, done = r.opts.Checkpoint.Progress(file)
+		if done {
+			log.Printf("Skipping %s, it is already replayed", file)
+			return nil
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s, err: %v", file, err)
+	}
+	defer f.Close()
+	lr, err := s3.NewLineReader(f)
+	if err != nil {
+		return err
+	}
+	defer lr.Close()
+
+	fr := &fileReplay{Replayer: r, file: file, format: r.opts.Format, now: time.Now().UnixMilli()}
+	lineNumber := 0
+	for {
+		line, err := lr.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read line %d of %s, err: %v", lineNumber+1, file, err)
+		}
+		lineNumber++
+		if lineNumber <= lastLine || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := fr.replayLine(ctx, line, lineNumber); err != nil {
+			return fmt.Errorf("failed to replay line %d of %s, err: %v", lineNumber, file, err)
+		}
+	}
+
+	if err := fr.flush(ctx, lineNumber); err != nil {
+		return fmt.Errorf("failed to replay %s, err: %v", file, err)
+	}
+	log.Printf("Replayed %s", file)
+	return r.saveCheckpoint(file, lineNumber, true)
+}
+
+func (r *Replayer) saveCheckpoint(file string, line int, done bool) error {
+	if r.opts.Checkpoint == nil || r.opts.DryRun {
+		return nil
+	}
+	return r.opts.Checkpoint.Save(file, line, done)
+}
+
+// fileReplay keeps the state of a file being replayed, log events of exported and NDJSON files are batched.
+type fileReplay struct {
+	*Replayer
+	file          string
+	format        string
+	now           int64
+	common        *enrich.Common
+	batch         []events.CloudwatchLogsLogEvent
+	batchSize     int
+	lastTimestamp int64
+}
+
+func (fr *fileReplay) replayLine(ctx context.Context, line string, lineNumber int) error {
+	if fr.format == FormatAuto {
+		format, err := detectFormat(line)
+		if err != nil {
+			return err
+		}
+		log.Printf("Detected %s format for %s", format, fr.file)
+		fr.format = format
+	}
+
+	var event events.CloudwatchLogsLogEvent
+	switch fr.format {
+	case FormatCloudwatch:
+		return fr.replayPayload(ctx, line, lineNumber)
+	case FormatExport:
+		event = fr.parseExportLine(line, lineNumber)
+	case FormatNDJSON:
+		event = fr.parseNDJSONLine(line, lineNumber)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, fr.format)
+	}
+
+	fr.batch = append(fr.batch, event)
+	fr.batchSize += len(event.Message)
+	if fr.batchSize < fr.forwarder.BatchSize() {
+		return nil
+	}
+	return fr.flush(ctx, lineNumber)
+}
+
+// replayPayload forwards a subscription payload with its own log group, control messages are skipped.
+func (fr *fileReplay) replayPayload(ctx context.Context, line string, lineNumber int) error {
+	data, err := decodePayload(line)
+	if err != nil {
+		log.Printf("Skipping line %d of %s, err: %v", lineNumber, fr.file, err)
+		return nil
+	}
+	if data.MessageType == cwlogs.ControlMessage {
+		return nil
+	}
+
+	common := fr.enricher.GetEDCommon(ctx, data.SubscriptionFilters, data.MessageType, data.LogGroup, data.LogStream, data.Owner)
+	if err := fr.forward(ctx, common, data.LogEvents); err != nil {
+		return err
+	}
+	return fr.saveCheckpoint(fr.file, lineNumber, false)
+}
+
+func (fr *fileReplay) flush(ctx context.Context, lineNumber int) error {
+	if len(fr.batch) == 0 {
+		return nil
+	}
+	if fr.common == nil {
+		logStream := fr.opts.LogStream
+		// CloudWatch Logs exports each log stream into its own directory
+		if logStream == "" && fr.format == FormatExport {
+			logStream = filepath.Base(filepath.Dir(fr.file))
+		}
+		fr.common = fr.enricher.GetEDCommon(ctx, nil, cwlogs.DataMessage, fr.opts.LogGroup, logStream, fr.opts.AccountID)
+	}
+
+	if err := fr.forward(ctx, fr.common, fr.batch); err != nil {
+		return err
+	}
+	// chunks are already marshalled, batch can be reused
+	fr.batch, fr.batchSize = fr.batch[:0], 0
+	return fr.saveCheckpoint(fr.file, lineNumber, false)
+}
+
+// forward forwards log events of each web ACL with its own common fields, other logs are forwarded together.
+func (fr *fileReplay) forward(ctx context.Context, common *enrich.Common, logEvents []events.CloudwatchLogsLogEvent) error {
+	for _, b := range fr.enricher.GroupByWebACL(ctx, common, logEvents) {
+		if err := fr.forwarder.Forward(ctx, b.Common, b.LogEvents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseExportLine parses "<timestamp> <message>" lines, lines without timestamp are continuation of multi line messages
+// and get the timestamp of the previous line.
+func (fr *fileReplay) parseExportLine(line string, lineNumber int) events.CloudwatchLogsLogEvent {
+	event := events.CloudwatchLogsLogEvent{ID: strconv.Itoa(lineNumber), Message: line}
+	if timestamp, message, ok := cutExportTimestamp(line); ok {
+		fr.lastTimestamp = timestamp
+		event.Message = message
+	}
+	if fr.lastTimestamp == 0 {
+		fr.lastTimestamp = fr.now
+	}
+	event.Timestamp = fr.lastTimestamp
+	return event
+}
+
+// parseNDJSONLine uses "message", "timestamp" and "id" fields if the line is a log event,
+// otherwise the line itself is the message.
+func (fr *fileReplay) parseNDJSONLine(line string, lineNumber int) events.CloudwatchLogsLogEvent {
+	event := events.CloudwatchLogsLogEvent{ID: strconv.Itoa(lineNumber), Timestamp: fr.now, Message: line}
+
+	var fields struct {
+		ID        string          `json:"id"`
+		Timestamp json.RawMessage `json:"timestamp"`
+		Message   *string         `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil || fields.Message == nil {
+		return event
+	}
+	event.Message = *fields.Message
+	if fields.ID != "" {
+		event.ID = fields.ID
+	}
+	if timestamp, ok := parseTimestamp(fields.Timestamp); ok {
+		event.Timestamp = timestamp
+	}
+	return event
+}
+
+// parseTimestamp parses epoch milliseconds or RFC3339 timestamp.
+func parseTimestamp(raw json.RawMessage) (int64, bool) {
+	var millis int64
+	if err := json.Unmarshal(raw, &millis); err == nil {
+		return millis, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, false
+	}
+	return t.UnixMilli(), true
+}
+
+func cutExportTimestamp(line string) (int64, string, bool) {
+	ts, message, ok := strings.Cut(line, " ")
+	if !ok {
+		return 0, "", false
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return 0, "", false
+	}
+	return t.UnixMilli(), message, true
+}
+
+// decodePayload decodes a captured subscription payload, which is either the lambda event,
+// the decoded subscription data or base64 encoded subscription data of a Kinesis or Firehose record.
+func decodePayload(line string) (*events.CloudwatchLogsData, error) {
+	var data *events.CloudwatchLogsData
+	if strings.HasPrefix(line, "{") {
+		var logsEvent events.CloudwatchLogsEvent
+		if err := json.Unmarshal([]byte(line), &logsEvent); err == nil && logsEvent.AWSLogs.Data != "" {
+			d, err := logsEvent.AWSLogs.Parse()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse logs event, err: %v", err)
+			}
+			data = &d
+		} else if data, err = cwlogs.Decode([]byte(line)); err != nil {
+			return nil, err
+		}
+	} else {
+		b, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 payload, err: %v", err)
+		}
+		if data, err = cwlogs.Decode(b); err != nil {
+			return nil, err
+		}
+	}
+
+	if data.MessageType == "" {
+		return nil, errors.New("line is not a CloudWatch Logs subscription payload")
+	}
+	return data, nil
+}
+
+func detectFormat(line string) (string, error) {
+	if _, err := decodePayload(line); err == nil {
+		return FormatCloudwatch, nil
+	}
+	if json.Valid([]byte(line)) {
+		return FormatNDJSON, nil
+	}
+	if _, _, ok := cutExportTimestamp(line); ok {
+		return FormatExport, nil
+	}
+	return "", fmt.Errorf("%w, first line is neither a CloudWatch Logs payload, JSON nor an exported log line", ErrUnknownFormat)
+}
@@ -0,0 +1,193 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/forward/forwardtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	dataMessage    = `{"messageType":"DATA_MESSAGE","owner":"123456789012","logGroup":"/aws/lambda/my-function","logStream":"stream","subscriptionFilters":["filter"],"logEvents":[{"id":"1","timestamp":1704067200000,"message":"hello"}]}`
+	controlMessage = `{"messageType":"CONTROL_MESSAGE","owner":"CloudwatchLogs","logGroup":"","logStream":"","subscriptionFilters":[],"logEvents":[{"id":"","timestamp":1704067200000,"message":"CWL CONTROL MESSAGE: Checking health of destination"}]}`
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+type replayedEvent struct {
+	logGroup  string
+	logStream string
+	timestamp int64
+	message   string
+}
+
+func decodeReplayed(t *testing.T, out *bytes.Buffer) []replayedEvent {
+	var got []replayedEvent
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(nil, cfg.MaxChunkSize)
+	for scanner.Scan() {
+		var l core.Log
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &l))
+		for _, e := range l.LogEvents {
+			got = append(got, replayedEvent{
+				logGroup:  l.AwsCommon.LogGroup,
+				logStream: l.AwsCommon.LogStream,
+				timestamp: e.Timestamp,
+				message:   e.Message,
+			})
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return got
+}
+
+func TestReplay(t *testing.T) {
+	lambdaEvent, err := json.Marshal(map[string]any{
+		"awslogs": map[string]string{"data": base64.StdEncoding.EncodeToString(gzipBytes(t, []byte(dataMessage)))},
+	})
+	require.NoError(t, err)
+	kinesisRecord := base64.StdEncoding.EncodeToString(gzipBytes(t, []byte(dataMessage)))
+
+	tests := []struct {
+		desc           string
+		dir            string
+		content        []byte
+		opts           Options
+		checkpointLine int
+		want           []replayedEvent
+		wantErr        bool
+	}{
+		{
+			desc:    "Captured CloudWatch payloads",
+			content: []byte(string(lambdaEvent) + "\n" + dataMessage + "\n" + controlMessage + "\n" + kinesisRecord + "\n"),
+			want: []replayedEvent{
+				{logGroup: "/aws/lambda/my-function", logStream: "stream", timestamp: 1704067200000, message: "hello"},
+				{logGroup: "/aws/lambda/my-function", logStream: "stream", timestamp: 1704067200000, message: "hello"},
+				{logGroup: "/aws/lambda/my-function", logStream: "stream", timestamp: 1704067200000, message: "hello"},
+			},
+		},
+		{
+			desc:    "Gzip compressed export with log stream from directory",
+			dir:     "2024/01/01/my-stream",
+			content: gzipBytes(t, []byte("2024-01-01T00:00:00.000Z first\n2024-01-01T00:00:01.000Z second\n  continued\n")),
+			opts:    Options{LogGroup: "/my/app"},
+			want: []replayedEvent{
+				{logGroup: "/my/app", logStream: "my-stream", timestamp: 1704067200000, message: "first"},
+				{logGroup: "/my/app", logStream: "my-stream", timestamp: 1704067201000, message: "second"},
+				{logGroup: "/my/app", logStream: "my-stream", timestamp: 1704067201000, message: "  continued"},
+			},
+		},
+		{
+			desc:    "NDJSON log events and objects",
+			content: []byte(`{"timestamp":1704067200000,"message":"hello"}` + "\n" + `{"timestamp":"2024-01-01T00:00:01Z","message":"world"}` + "\n\n" + `{"level":"info"}` + "\n"),
+			opts:    Options{Format: FormatNDJSON, LogGroup: "/my/app", LogStream: "stream"},
+			want: []replayedEvent{
+				{logGroup: "/my/app", logStream: "stream", timestamp: 1704067200000, message: "hello"},
+				{logGroup: "/my/app", logStream: "stream", timestamp: 1704067201000, message: "world"},
+				{logGroup: "/my/app", logStream: "stream", message: `{"level":"info"}`},
+			},
+		},
+		{
+			desc:           "Resume from checkpoint",
+			content:        []byte(dataMessage + "\n" + dataMessage + "\n" + kinesisRecord + "\n"),
+			checkpointLine: 2,
+			want: []replayedEvent{
+				{logGroup: "/aws/lambda/my-function", logStream: "stream", timestamp: 1704067200000, message: "hello"},
+			},
+		},
+		{
+			desc:    "Unknown format",
+			content: []byte("plain text\n"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.dir, "000000.gz")
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, tt.content, 0o644))
+
+			checkpoint, err := LoadCheckpoint(filepath.Join(t.TempDir(), "checkpoint.json"))
+			require.NoError(t, err)
+			if tt.checkpointLine > 0 {
+				require.NoError(t, checkpoint.Save(path, tt.checkpointLine, false))
+			}
+			tt.opts.Checkpoint = checkpoint
+
+			conf := &cfg.Config{Region: "us-west-2", BatchSize: cfg.MaxChunkSize}
+			var out bytes.Buffer
+			enricher, forwarder := forwardtest.NewForwarder(conf, NewWriterPusher(&out))
+			replayer := NewReplayer(enricher, forwarder, tt.opts)
+
+			err = replayer.Replay(context.Background(), []string{dir})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			got := decodeReplayed(t, &out)
+			now := time.Now().UnixMilli()
+			for i := range got {
+				// events without timestamp get the replay time
+				if got[i].timestamp > now-time.Minute.Milliseconds() {
+					got[i].timestamp = 0
+				}
+			}
+			assert.Equal(t, tt.want, got)
+
+			// file is skipped once it is completely replayed
+			line, done := checkpoint.Progress(path)
+			assert.True(t, done)
+			assert.Positive(t, line)
+			out.Reset()
+			require.NoError(t, replayer.Replay(context.Background(), []string{dir}))
+			assert.Empty(t, out.String())
+		})
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpoint, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	require.NoError(t, checkpoint.Save("/logs/a.log", 10, false))
+	require.NoError(t, checkpoint.Save("/logs/b.log", 20, true))
+
+	loaded, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	line, done := loaded.Progress("/logs/a.log")
+	assert.Equal(t, 10, line)
+	assert.False(t, done)
+	line, done = loaded.Progress("/logs/b.log")
+	assert.Equal(t, 20, line)
+	assert.True(t, done)
+	line, done = loaded.Progress("/logs/c.log")
+	assert.Equal(t, 0, line)
+	assert.False(t, done)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	_, err = LoadCheckpoint(path)
+	assert.Error(t, err)
+}
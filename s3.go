@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/logparser"
+	"github.com/edgedelta/edgedelta-forwarder/s3"
+)
+
+const (
+	s3ObjectCreatedEventPrefix = "ObjectCreated:"
+)
+
+func handleS3Request(ctx context.Context, s3Event events.S3Event) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in handleS3Request, err: %v", r)
+		}
+	}()
+
+	return forwardS3Event(ctx, s3Event)
+}
+
+// forwardS3Event forwards objects of the event one by one. Objects which can not be read however many times they
+// are retried are skipped, so only push failures fail the event. Failed events are retried as a whole, so lines
+// pushed before the failure are pushed again (at-least-once delivery).
+func forwardS3Event(ctx context.Context, s3Event events.S3Event) error {
+	for _, record := range s3Event.Records {
+		bucket, key := record.S3.Bucket.Name, record.S3.Object.URLDecodedKey
+		if !strings.HasPrefix(record.EventName, s3ObjectCreatedEventPrefix) {
+			log.Printf("Skipping S3 event: %s for object s3://%s/%s", record.EventName, bucket, key)
+			continue
+		}
+		if err := forwardS3Object(ctx, bucket, key, record.S3.Object.Size, record.EventTime); err != nil {
+			if s3.IsUnreadable(err) {
+				log.Printf("Skipping unreadable S3 object s3://%s/%s, err: %v", bucket, key, err)
+				continue
+			}
+			log.Printf("Failed to forward S3 object s3://%s/%s, err: %v", bucket, key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// forwardS3Object streams the object line by line and pushes lines in batches,
+// so memory usage is bounded by batch size instead of object size.
+func forwardS3Object(ctx context.Context, bucket, key string, size int64, eventTime time.Time) error {
+	body, err := s3Client.GetObject(ctx, bucket, key)
+	if err != nil {
+		return fmt.Errorf("failed to get object, err: %w", err)
+	}
+	defer body.Close()
+
+	lr, err := s3.NewLineReader(body)
+	if err != nil {
+		return err
+	}
+	defer lr.Close()
+
+	// common fields are created with the first line, which has the source bucket of server access logs
+	var common *enrich.Common
+	// parser is shared by batches, so fields in the header of the object are used for all lines
+	var parser logparser.Parser
+	timestamp := eventTime.UnixMilli()
+
+	var batch []events.CloudwatchLogsLogEvent
+	batchSize, lineNumber := 0, 0
+	for {
+		line, err := lr.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read line %d, err: %w", lineNumber+1, err)
+		}
+		lineNumber++
+		if line == "" {
+			continue
+		}
+		if common == nil {
+			common = getS3Common(ctx, bucket, key, size, line)
+			parser = forwarder.Parser(common)
+		}
+
+		batch = append(batch, events.CloudwatchLogsLogEvent{
+			ID:        strconv.Itoa(lineNumber),
+			Timestamp: timestamp,
+			Message:   line,
+		})
+		batchSize += len(line)
+		if batchSize < forwarder.BatchSize() {
+			continue
+		}
+
+		if err := forwardS3Batch(ctx, common, batch, parser); err != nil {
+			return err
+		}
+		// chunks are already marshalled, batch can be reused
+		batch, batchSize = batch[:0], 0
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return forwardS3Batch(ctx, common, batch, parser)
+}
+
+// forwardS3Batch forwards lines of each web ACL of a WAF log object with its own common fields,
+// lines of other objects are forwarded together.
+func forwardS3Batch(ctx context.Context, common *enrich.Common, batch []events.CloudwatchLogsLogEvent, parser logparser.Parser) error {
+	for _, b := range enricher.GroupByWebACL(ctx, common, batch) {
+		if err := forwarder.ForwardWithParser(ctx, b.Common, b.LogEvents, parser); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getS3Common returns common fields of an S3 object, source bucket of a server access log object is read from its first line.
+func getS3Common(ctx context.Context, bucket, key string, size int64, firstLine string) *enrich.Common {
+	if logparser.IsS3AccessLogKey(key) {
+		if sourceBucket, ok := logparser.GetS3AccessLogBucket(firstLine); ok {
+			return enricher.GetS3AccessLogCommon(ctx, bucket, key, size, sourceBucket)
+		}
+	}
+	return enricher.GetS3Common(ctx, bucket, key, size)
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	errCodeAccessDenied = "AccessDenied"
+)
+
+type Client interface {
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+type DefaultClient struct {
+	svc *s3.S3
+}
+
+func NewClient(region string) (*DefaultClient, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS S3 client, err: %v", err)
+	}
+	return &DefaultClient{svc: s3.New(sess, &aws.Config{Region: aws.String(region)})}, nil
+}
+
+// GetObject returns the body of the object, caller is responsible for closing it.
+// Body is streamed from S3 so the object is never fully loaded into memory.
+func (c *DefaultClient) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	result, err := c.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Body, nil
+}
+
+// IsUnreadable returns true if the error means the object can not be read however many times it is retried,
+// i.e. it is deleted, forwarder is not allowed to read it or its content is not valid gzip.
+func IsUnreadable(err error) bool {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket, s3.ErrCodeInvalidObjectState, errCodeAccessDenied:
+			return true
+		}
+		return false
+	}
+	var corruptErr flate.CorruptInputError
+	return errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) || errors.As(err, &corruptErr)
+}
@@ -0,0 +1,59 @@
+package s3
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestIsUnreadable(t *testing.T) {
+	tests := []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{
+			desc: "Deleted object",
+			err:  awserr.NewRequestFailure(awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil), http.StatusNotFound, "request-id"),
+			want: true,
+		},
+		{
+			desc: "Access denied",
+			err:  fmt.Errorf("failed to get object, err: %w", awserr.NewRequestFailure(awserr.New("AccessDenied", "Access Denied", nil), http.StatusForbidden, "request-id")),
+			want: true,
+		},
+		{
+			desc: "Invalid gzip header",
+			err:  fmt.Errorf("failed to create gzip reader, err: %w", gzip.ErrHeader),
+			want: true,
+		},
+		{
+			desc: "Invalid gzip checksum",
+			err:  fmt.Errorf("failed to read line 3, err: %w", gzip.ErrChecksum),
+			want: true,
+		},
+		{
+			desc: "Throttled request",
+			err:  awserr.NewRequestFailure(awserr.New("SlowDown", "Please reduce your request rate.", nil), http.StatusServiceUnavailable, "request-id"),
+			want: false,
+		},
+		{
+			desc: "Interrupted read",
+			err:  fmt.Errorf("failed to read line 3, err: %w", io.ErrUnexpectedEOF),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := IsUnreadable(tt.err); got != tt.want {
+				t.Errorf("IsUnreadable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	snsNotificationType = "Notification"
+	s3TestEvent         = "s3:TestEvent"
+)
+
+type notification struct {
+	// set when message is an S3 event notification
+	Records []events.S3EventRecord `json:"Records"`
+	// set when message is an SNS notification which wraps S3 event notification
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+	// set when message is the test event sent while configuring bucket notifications
+	Event string `json:"Event"`
+}
+
+// ParseNotification parses S3 event notification from a message body, notifications wrapped in an SNS message
+// are unwrapped. Test events sent by S3 while configuring notifications are returned as an event without records.
+func ParseNotification(body []byte) (*events.S3Event, error) {
+	var n notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification, err: %v", err)
+	}
+
+	if n.Type == snsNotificationType {
+		return ParseNotification([]byte(n.Message))
+	}
+
+	if n.Event == s3TestEvent {
+		return &events.S3Event{}, nil
+	}
+
+	if n.Records == nil {
+		return nil, fmt.Errorf("message is not an S3 event notification")
+	}
+
+	return &events.S3Event{Records: n.Records}, nil
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const (
+	s3EventBody = `{"Records":[{"eventVersion":"2.1","eventSource":"aws:s3","awsRegion":"us-west-2","eventTime":"2024-01-01T00:00:00.000Z","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"my-bucket","arn":"arn:aws:s3:::my-bucket"},"object":{"key":"AWSLogs/my+file.log.gz","size":1024}}}]}`
+)
+
+func TestParseNotification(t *testing.T) {
+	snsBody, err := json.Marshal(map[string]string{
+		"Type":     "Notification",
+		"TopicArn": "arn:aws:sns:us-west-2:123456789012:my-topic",
+		"Message":  s3EventBody,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal SNS notification: %v", err)
+	}
+
+	tests := []struct {
+		desc        string
+		body        string
+		wantRecords int
+		wantKey     string
+		wantErr     bool
+	}{
+		{
+			desc:        "S3 event notification",
+			body:        s3EventBody,
+			wantRecords: 1,
+			wantKey:     "AWSLogs/my file.log.gz",
+		},
+		{
+			desc:        "S3 event notification wrapped in SNS notification",
+			body:        string(snsBody),
+			wantRecords: 1,
+			wantKey:     "AWSLogs/my file.log.gz",
+		},
+		{
+			desc:        "S3 test event",
+			body:        `{"Service":"Amazon S3","Event":"s3:TestEvent","Time":"2024-01-01T00:00:00.000Z","Bucket":"my-bucket"}`,
+			wantRecords: 0,
+		},
+		{
+			desc:    "Unknown message",
+			body:    `{"foo":"bar"}`,
+			wantErr: true,
+		},
+		{
+			desc:    "Invalid JSON",
+			body:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			event, err := ParseNotification([]byte(tc.body))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse notification: %v", err)
+			}
+			if len(event.Records) != tc.wantRecords {
+				t.Fatalf("Expected %d records, got %d", tc.wantRecords, len(event.Records))
+			}
+			if tc.wantRecords > 0 && event.Records[0].S3.Object.URLDecodedKey != tc.wantKey {
+				t.Errorf("Expected key %q, got %q", tc.wantKey, event.Records[0].S3.Object.URLDecodedKey)
+			}
+		})
+	}
+}
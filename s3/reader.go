@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	readBufferSize = 64 * 1024 // 64KB
+)
+
+// LineReader reads an object line by line, gzip compressed objects are decompressed on the fly.
+type LineReader struct {
+	r      *bufio.Reader
+	closer io.Closer
+}
+
+func NewLineReader(r io.Reader) (*LineReader, error) {
+	br := bufio.NewReaderSize(r, readBufferSize)
+	// Objects shorter than gzip magic number can not be compressed, peek error is ignored for them
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return &LineReader{r: br}, nil
+	}
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader, err: %w", err)
+	}
+	return &LineReader{r: bufio.NewReaderSize(zr, readBufferSize), closer: zr}, nil
+}
+
+// ReadLine returns the next line without line terminator, io.EOF is returned after the last line.
+func (l *LineReader) ReadLine() (string, error) {
+	line, err := l.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		// last line without trailing new line
+		return strings.TrimSuffix(line, "\r"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func (l *LineReader) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
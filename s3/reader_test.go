@@ -0,0 +1,85 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func gzipString(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatalf("Failed to write gzip content: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		desc    string
+		content func(t *testing.T) io.Reader
+		want    []string
+	}{
+		{
+			desc:    "Plain text with trailing new line",
+			content: func(t *testing.T) io.Reader { return strings.NewReader("line 1\nline 2\n") },
+			want:    []string{"line 1", "line 2"},
+		},
+		{
+			desc:    "Plain text without trailing new line and with CRLF",
+			content: func(t *testing.T) io.Reader { return strings.NewReader("line 1\r\n\nline 3") },
+			want:    []string{"line 1", "", "line 3"},
+		},
+		{
+			desc:    "Single byte object",
+			content: func(t *testing.T) io.Reader { return strings.NewReader("a") },
+			want:    []string{"a"},
+		},
+		{
+			desc:    "Empty object",
+			content: func(t *testing.T) io.Reader { return strings.NewReader("") },
+			want:    nil,
+		},
+		{
+			desc: "Gzip compressed",
+			content: func(t *testing.T) io.Reader {
+				return bytes.NewReader(gzipString(t, "line 1\nline 2\nline 3"))
+			},
+			want: []string{"line 1", "line 2", "line 3"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			lr, err := NewLineReader(tc.content(t))
+			if err != nil {
+				t.Fatalf("Failed to create line reader: %v", err)
+			}
+			defer lr.Close()
+
+			var got []string
+			for {
+				line, err := lr.ReadLine()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Failed to read line: %v", err)
+				}
+				got = append(got, line)
+			}
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Lines mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
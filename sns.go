@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// handleSNSRequest forwards each SNS notification as a log event whose message is the notification message.
+func handleSNSRequest(ctx context.Context, snsEvent events.SNSEvent) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in handleSNSRequest, err: %v", r)
+		}
+	}()
+
+	for _, record := range snsEvent.Records {
+		notification := record.SNS
+		common := enricher.GetSNSCommon(ctx, notification.TopicArn, notification.MessageID, notification.Subject, notification.Message, notification.MessageAttributes)
+		err := forwarder.Forward(ctx, common, []events.CloudwatchLogsLogEvent{
+			{
+				ID:        notification.MessageID,
+				Timestamp: notification.Timestamp.UnixMilli(),
+				Message:   notification.Message,
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to forward SNS notification: %s, err: %v", notification.MessageID, err)
+			return err
+		}
+	}
+	return nil
+}
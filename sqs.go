@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/s3"
+)
+
+// handleSQSRequest processes each message independently and reports failed ones,
+// so only failed messages are redelivered instead of the whole batch.
+// Requires ReportBatchItemFailures to be enabled on the event source mapping.
+func handleSQSRequest(ctx context.Context, sqsEvent events.SQSEvent) (events.SQSEventResponse, error) {
+	var response events.SQSEventResponse
+	for _, message := range sqsEvent.Records {
+		if err := processSQSMessage(ctx, message); err != nil {
+			log.Printf("Failed to process SQS message: %s, err: %v", message.MessageId, err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+		}
+	}
+
+	if len(response.BatchItemFailures) > 0 {
+		log.Printf("Failed to process %d of %d SQS messages", len(response.BatchItemFailures), len(sqsEvent.Records))
+	}
+	return response, nil
+}
+
+func processSQSMessage(ctx context.Context, message events.SQSMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovering from panic in processSQSMessage, err: %v", r)
+			err = fmt.Errorf("panic while processing message: %v", r)
+		}
+	}()
+
+	s3Event, err := s3.ParseNotification([]byte(message.Body))
+	if err != nil {
+		return err
+	}
+
+	return forwardS3Event(ctx, *s3Event)
+}
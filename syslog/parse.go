@@ -0,0 +1,220 @@
+package syslog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	nilValue = "-"
+	// defaultPriority is user.notice, RFC 3164 assigns it to messages without priority
+	defaultPriority = 13
+	maxPriority     = 191
+	rfc3164Layout   = "Jan _2 15:04:05"
+	utf8BOM         = "\ufeff"
+)
+
+// Message is a parsed syslog message, fields missing in the message are left empty.
+type Message struct {
+	Facility       int
+	Severity       int
+	Version        int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Message        string
+}
+
+// Parse parses RFC 5424 and RFC 3164 messages. Messages in neither format are kept as is with the default priority,
+// received time is used if the message has no timestamp.
+func Parse(b []byte, received time.Time) *Message {
+	s := string(bytes.TrimRight(b, "\r\n\x00"))
+	m := &Message{Timestamp: received}
+
+	priority, rest, ok := cutPriority(s)
+	if !ok {
+		m.Facility, m.Severity = defaultPriority/8, defaultPriority%8
+		m.Message = s
+		return m
+	}
+	m.Facility, m.Severity = priority/8, priority%8
+
+	if version, after, ok := cutVersion(rest); ok {
+		m.Version = version
+		if parseRFC5424(m, after) {
+			return m
+		}
+		m.Version = 0
+	}
+	parseRFC3164(m, rest, received)
+	return m
+}
+
+func cutPriority(s string) (int, string, bool) {
+	if !strings.HasPrefix(s, "<") {
+		return 0, s, false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return 0, s, false
+	}
+	priority, err := strconv.Atoi(s[1:end])
+	if err != nil || priority < 0 || priority > maxPriority {
+		return 0, s, false
+	}
+	return priority, s[end+1:], true
+}
+
+func cutVersion(s string) (int, string, bool) {
+	v, rest, ok := strings.Cut(s, " ")
+	if !ok || len(v) == 0 || len(v) > 2 {
+		return 0, s, false
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version == 0 {
+		return 0, s, false
+	}
+	return version, rest, true
+}
+
+// parseRFC5424 parses "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]" following the version.
+func parseRFC5424(m *Message, s string) bool {
+	fields := make([]string, 5)
+	for i := range fields {
+		var ok bool
+		fields[i], s, ok = strings.Cut(s, " ")
+		if !ok {
+			return false
+		}
+	}
+	if fields[0] != nilValue {
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return false
+		}
+		m.Timestamp = t
+	}
+	m.Hostname, m.AppName, m.ProcID, m.MsgID = nilToEmpty(fields[1]), nilToEmpty(fields[2]), nilToEmpty(fields[3]), nilToEmpty(fields[4])
+
+	if strings.HasPrefix(s, nilValue) {
+		s = s[len(nilValue):]
+	} else {
+		sd, rest, ok := parseStructuredData(s)
+		if !ok {
+			return false
+		}
+		m.StructuredData, s = sd, rest
+	}
+	if s != "" && s[0] != ' ' {
+		return false
+	}
+	m.Message = strings.TrimPrefix(strings.TrimPrefix(s, " "), utf8BOM)
+	return true
+}
+
+// parseStructuredData parses "[SD-ID PARAM-NAME="PARAM-VALUE" ...]..." elements, values can contain escaped '"', '\' and ']'.
+func parseStructuredData(s string) (map[string]map[string]string, string, bool) {
+	sd := map[string]map[string]string{}
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		idEnd := strings.IndexAny(s, " ]")
+		if idEnd <= 0 {
+			return nil, s, false
+		}
+		id, params := s[:idEnd], map[string]string{}
+		s = s[idEnd:]
+		for strings.HasPrefix(s, " ") {
+			s = s[1:]
+			nameEnd := strings.Index(s, `="`)
+			if nameEnd <= 0 {
+				return nil, s, false
+			}
+			name := s[:nameEnd]
+			s = s[nameEnd+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(s); i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					value.WriteByte(s[i+1])
+					i++
+					continue
+				}
+				if s[i] == '"' {
+					s, closed = s[i+1:], true
+					break
+				}
+				value.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, s, false
+			}
+			params[name] = value.String()
+		}
+		if !strings.HasPrefix(s, "]") {
+			return nil, s, false
+		}
+		s = s[1:]
+		sd[id] = params
+	}
+	return sd, s, true
+}
+
+// parseRFC3164 parses "TIMESTAMP HOSTNAME TAG[PID]: MSG", devices commonly omit hostname or send RFC 3339 timestamp,
+// so each part is parsed only if it is present.
+func parseRFC3164(m *Message, s string, received time.Time) {
+	if t, rest, ok := cutRFC3164Timestamp(s, received); ok {
+		m.Timestamp, s = t, rest
+	} else {
+		m.Message = s
+		return
+	}
+
+	if host, rest, ok := strings.Cut(s, " "); ok && !isTag(host) {
+		m.Hostname, s = host, rest
+	}
+	if tag, rest, ok := strings.Cut(s, " "); ok && isTag(tag) {
+		tag = strings.TrimSuffix(tag, ":")
+		if name, pid, ok := strings.Cut(tag, "["); ok {
+			tag, m.ProcID = name, strings.TrimSuffix(pid, "]")
+		}
+		m.AppName, s = tag, rest
+	}
+	m.Message = s
+}
+
+func cutRFC3164Timestamp(s string, received time.Time) (time.Time, string, bool) {
+	if len(s) > len(rfc3164Layout) && s[len(rfc3164Layout)] == ' ' {
+		if t, err := time.ParseInLocation(rfc3164Layout, s[:len(rfc3164Layout)], received.Location()); err == nil {
+			t = t.AddDate(received.Year(), 0, 0)
+			// timestamp has no year, message of the last day of a year may be received in the next year
+			if t.After(received.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, s[len(rfc3164Layout)+1:], true
+		}
+	}
+	if ts, rest, ok := strings.Cut(s, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t, rest, true
+		}
+	}
+	return time.Time{}, s, false
+}
+
+// isTag returns true for "app:", "app[123]:" and "app[123]".
+func isTag(s string) bool {
+	return strings.HasSuffix(s, ":") || (strings.Contains(s, "[") && strings.HasSuffix(s, "]"))
+}
+
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
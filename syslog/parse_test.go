@@ -0,0 +1,144 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	received := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		desc     string
+		message  string
+		received time.Time
+		want     *Message
+	}{
+		{
+			desc:    "RFC 5424 with structured data",
+			message: `<165>1 2024-03-10T11:59:58.123Z host1 app 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][origin ip="10.0.0.1"] An application event`,
+			want: &Message{
+				Facility:  20,
+				Severity:  5,
+				Version:   1,
+				Timestamp: time.Date(2024, 3, 10, 11, 59, 58, 123000000, time.UTC),
+				Hostname:  "host1",
+				AppName:   "app",
+				ProcID:    "1234",
+				MsgID:     "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473": {"iut": "3", "eventSource": "Application"},
+					"origin":            {"ip": "10.0.0.1"},
+				},
+				Message: "An application event",
+			},
+		},
+		{
+			desc:    "RFC 5424 with escaped structured data values",
+			message: `<14>1 2024-03-10T11:59:58Z host1 app - - [meta value="a \"quoted\" \] value\\"]`,
+			want: &Message{
+				Facility:       1,
+				Severity:       6,
+				Version:        1,
+				Timestamp:      time.Date(2024, 3, 10, 11, 59, 58, 0, time.UTC),
+				Hostname:       "host1",
+				AppName:        "app",
+				StructuredData: map[string]map[string]string{"meta": {"value": `a "quoted" ] value\`}},
+			},
+		},
+		{
+			desc:    "RFC 5424 with nil values and BOM",
+			message: "<34>1 - - - - - - \ufeffhello\n",
+			want: &Message{
+				Facility:  4,
+				Severity:  2,
+				Version:   1,
+				Timestamp: received,
+				Message:   "hello",
+			},
+		},
+		{
+			desc:    "RFC 3164",
+			message: "<34>Mar  9 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8",
+			want: &Message{
+				Facility:  4,
+				Severity:  2,
+				Timestamp: time.Date(2024, 3, 9, 22, 14, 15, 0, time.UTC),
+				Hostname:  "mymachine",
+				AppName:   "su",
+				ProcID:    "123",
+				Message:   "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			desc:    "RFC 3164 without hostname",
+			message: "<13>Mar 10 11:00:00 sshd: Accepted publickey",
+			want: &Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2024, 3, 10, 11, 0, 0, 0, time.UTC),
+				AppName:   "sshd",
+				Message:   "Accepted publickey",
+			},
+		},
+		{
+			desc:    "RFC 3164 with RFC 3339 timestamp",
+			message: "<13>2024-03-10T11:00:00+02:00 router kernel: link up",
+			want: &Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC),
+				Hostname:  "router",
+				AppName:   "kernel",
+				Message:   "link up",
+			},
+		},
+		{
+			desc:     "RFC 3164 received in the next year",
+			message:  "<13>Dec 31 23:59:59 host app: last message",
+			received: time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC),
+			want: &Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+				Hostname:  "host",
+				AppName:   "app",
+				Message:   "last message",
+			},
+		},
+		{
+			desc:    "Priority without timestamp",
+			message: "<13>just a message",
+			want: &Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: received,
+				Message:   "just a message",
+			},
+		},
+		{
+			desc:    "No priority",
+			message: "plain text",
+			want: &Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: received,
+				Message:   "plain text",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			r := received
+			if !tt.received.IsZero() {
+				r = tt.received
+			}
+			got := Parse([]byte(tt.message), r)
+			assert.True(t, tt.want.Timestamp.Equal(got.Timestamp), "expected timestamp %v, got %v", tt.want.Timestamp, got.Timestamp)
+			got.Timestamp = tt.want.Timestamp
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
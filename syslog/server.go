@@ -0,0 +1,382 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/enrich"
+	"github.com/edgedelta/edgedelta-forwarder/forward"
+	"github.com/edgedelta/edgedelta-forwarder/imds"
+)
+
+const (
+	readBufferSize = 64 * 1024 // 64KB
+	// maxMessageSize limits TCP frames, UDP messages are limited by datagram size
+	maxMessageSize = 1024 * 1024 // 1MB
+	// maxFrameLengthDigits limits the length prefix of octet counted frames, longer lengths exceed max message size anyway
+	maxFrameLengthDigits = 8
+	maxDatagramSize      = 64 * 1024
+	flushInterval        = time.Second
+	eventsChannelSize    = 1024
+)
+
+var (
+	ErrServerClosed = errors.New("syslog: server closed")
+)
+
+// Server receives syslog messages over UDP, TCP and TLS. Messages are parsed into attributes,
+// batched until batch size or flush interval is reached and forwarded with the account and region of the host.
+// Syslog has no acknowledgement, so batches that can not be pushed are dropped after retries.
+type Server struct {
+	udpAddr     string
+	tcpAddr     string
+	tlsAddr     string
+	tlsCertFile string
+	tlsKeyFile  string
+	batchSize   int
+	enricher    *enrich.Enricher
+	forwarder   *forward.Forwarder
+	identity    *imds.InstanceIdentity
+	events      chan core.LogEvent
+	batcherDone chan struct{}
+
+	// ctx is cancelled when shutdown times out, so pending pushes are stopped
+	ctx       context.Context
+	cancel    context.CancelFunc
+	lock      sync.Mutex
+	listeners []io.Closer
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+func NewServer(conf *cfg.Config, enricher *enrich.Enricher, forwarder *forward.Forwarder, identity *imds.InstanceIdentity) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		udpAddr:     conf.SyslogUDPListenAddress,
+		tcpAddr:     conf.SyslogTCPListenAddress,
+		tlsAddr:     conf.SyslogTLSListenAddress,
+		tlsCertFile: conf.SyslogTLSCertFile,
+		tlsKeyFile:  conf.SyslogTLSKeyFile,
+		batchSize:   conf.BatchSize,
+		enricher:    enricher,
+		forwarder:   forwarder,
+		identity:    identity,
+		events:      make(chan core.LogEvent, eventsChannelSize),
+		batcherDone: make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+		conns:       map[net.Conn]struct{}{},
+	}
+	go s.runBatcher()
+	return s
+}
+
+// ListenAndServe listens on configured UDP, TCP and TLS addresses, it returns when a listener fails or the server is shut down.
+func (s *Server) ListenAndServe() error {
+	errs := make(chan error, 3)
+	if s.udpAddr != "" {
+		pc, err := net.ListenPacket("udp", s.udpAddr)
+		if err != nil {
+			return err
+		}
+		go func() { errs <- s.ServeUDP(pc) }()
+	}
+	if s.tcpAddr != "" {
+		l, err := net.Listen("tcp", s.tcpAddr)
+		if err != nil {
+			return err
+		}
+		go func() { errs <- s.ServeTCP(l) }()
+	}
+	if s.tlsAddr != "" {
+		cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate, err: %v", err)
+		}
+		l, err := tls.Listen("tcp", s.tlsAddr, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return err
+		}
+		go func() { errs <- s.ServeTCP(l) }()
+	}
+	return <-errs
+}
+
+// ServeUDP reads a message from each datagram until the server is shut down, it always returns a non-nil error.
+func (s *Server) ServeUDP(pc net.PacketConn) error {
+	if !s.track(pc) {
+		return ErrServerClosed
+	}
+	defer s.wg.Done()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		s.events <- newLogEvent(buf[:n], addr)
+	}
+}
+
+// ServeTCP accepts connections until the server is shut down, it always returns a non-nil error.
+// Messages of a connection are either octet counted or newline delimited.
+func (s *Server) ServeTCP(l net.Listener) error {
+	if !s.track(l) {
+		return ErrServerClosed
+	}
+	defer s.wg.Done()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.lock.Unlock()
+		go s.handleConn(conn)
+	}
+}
+
+// Shutdown stops listeners and reading from open connections, messages that are already read are pushed before it returns.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	if !s.closed {
+		s.closed = true
+		for _, l := range s.listeners {
+			l.Close()
+		}
+		for conn := range s.conns {
+			// unblocks the pending read, so connection handler returns
+			conn.SetReadDeadline(time.Now())
+		}
+		go func() {
+			s.wg.Wait()
+			close(s.events)
+		}()
+	}
+	s.lock.Unlock()
+
+	select {
+	case <-s.batcherDone:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+// track registers a listener to be closed on shutdown, it returns false if the server is already shut down.
+func (s *Server) track(l io.Closer) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		l.Close()
+		return false
+	}
+	s.listeners = append(s.listeners, l)
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReaderSize(conn, readBufferSize)
+	for {
+		frame, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) && !isTimeout(err) {
+				log.Printf("Failed to read syslog message from %s, err: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		s.events <- newLogEvent(frame, conn.RemoteAddr())
+	}
+}
+
+// readFrame reads an octet counted frame ("LEN SP MSG") as described in RFC 6587 if it starts with a digit,
+// otherwise a newline delimited frame.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] >= '1' && b[0] <= '9' {
+		n, err := readFrameLength(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxMessageSize {
+			return nil, fmt.Errorf("frame length %d exceeds max message size", n)
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	var frame []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		frame = append(frame, line...)
+		if len(frame) > maxMessageSize {
+			return nil, errors.New("message exceeds max message size")
+		}
+		if err == nil {
+			return bytes.TrimRight(frame, "\r\n"), nil
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) && len(frame) > 0 {
+			// last message without trailing new line
+			return frame, nil
+		}
+		return nil, err
+	}
+}
+
+// readFrameLength reads the length prefix of an octet counted frame and the space after it, at most
+// maxFrameLengthDigits digits are read, so a prefix without space does not grow the buffer.
+func readFrameLength(r *bufio.Reader) (int, error) {
+	var length []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == ' ' {
+			break
+		}
+		if c < '0' || c > '9' || len(length) == maxFrameLengthDigits {
+			return 0, fmt.Errorf("invalid frame length: %q", append(length, c))
+		}
+		length = append(length, c)
+	}
+	return strconv.Atoi(string(length))
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func newLogEvent(b []byte, addr net.Addr) core.LogEvent {
+	m := Parse(b, time.Now())
+	attributes := map[string]any{
+		"syslog.facility": m.Facility,
+		"syslog.severity": m.Severity,
+	}
+	setAttribute := func(key, value string) {
+		if value != "" {
+			attributes[key] = value
+		}
+	}
+	if m.Version > 0 {
+		attributes["syslog.version"] = m.Version
+	}
+	setAttribute("syslog.hostname", m.Hostname)
+	setAttribute("syslog.app_name", m.AppName)
+	setAttribute("syslog.proc_id", m.ProcID)
+	setAttribute("syslog.msg_id", m.MsgID)
+	if len(m.StructuredData) > 0 {
+		attributes["syslog.structured_data"] = m.StructuredData
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		setAttribute("syslog.source_ip", host)
+	}
+
+	return core.LogEvent{
+		CloudwatchLogsLogEvent: events.CloudwatchLogsLogEvent{
+			Timestamp: m.Timestamp.UnixMilli(),
+			Message:   m.Message,
+		},
+		Attributes: attributes,
+	}
+}
+
+// runBatcher forwards messages of all listeners in batches, a batch is pushed when it reaches batch size or flush interval passes.
+func (s *Server) runBatcher() {
+	defer close(s.batcherDone)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []core.LogEvent
+	size := 0
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		common := s.enricher.GetHostCommon(s.ctx, s.identity.AccountID, s.identity.Region, "", "")
+		if err := s.forwarder.ForwardEvents(s.ctx, common, batch); err != nil {
+			log.Printf("Failed to forward %d syslog messages, err: %v", len(batch), err)
+		}
+		// chunks are already marshalled, batch can be reused
+		batch, size = batch[:0], 0
+	}
+
+	for {
+		select {
+		case e, ok := <-s.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, e)
+			size += len(e.Message)
+			if size >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/edgedelta/edgedelta-forwarder/core"
+	"github.com/edgedelta/edgedelta-forwarder/forward/forwardtest"
+	"github.com/edgedelta/edgedelta-forwarder/imds"
+	"github.com/edgedelta/edgedelta-forwarder/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	messages := []string{
+		`<165>1 2024-03-10T11:59:58.123Z host1 app 1234 ID47 [origin ip="10.0.0.1"] first`,
+		"<34>Mar  9 22:14:15 mymachine su[123]: second",
+	}
+	wantEvents := []core.LogEvent{
+		{Attributes: map[string]any{
+			"syslog.facility":        float64(20),
+			"syslog.severity":        float64(5),
+			"syslog.version":         float64(1),
+			"syslog.hostname":        "host1",
+			"syslog.app_name":        "app",
+			"syslog.proc_id":         "1234",
+			"syslog.msg_id":          "ID47",
+			"syslog.structured_data": map[string]any{"origin": map[string]any{"ip": "10.0.0.1"}},
+			"syslog.source_ip":       "127.0.0.1",
+		}},
+		{Attributes: map[string]any{
+			"syslog.facility":  float64(4),
+			"syslog.severity":  float64(2),
+			"syslog.hostname":  "mymachine",
+			"syslog.app_name":  "su",
+			"syslog.proc_id":   "123",
+			"syslog.source_ip": "127.0.0.1",
+		}},
+	}
+	wantEvents[0].Message, wantEvents[1].Message = "first", "second"
+	wantEvents[0].Timestamp = time.Date(2024, 3, 10, 11, 59, 58, 123000000, time.UTC).UnixMilli()
+
+	tlsConfig := newTLSConfig(t)
+	tests := []struct {
+		desc  string
+		serve func(t *testing.T, s *Server) (net.Addr, <-chan error)
+		send  func(t *testing.T, addr net.Addr)
+	}{
+		{
+			desc:  "UDP",
+			serve: serveUDP,
+			send: func(t *testing.T, addr net.Addr) {
+				conn, err := net.Dial("udp", addr.String())
+				require.NoError(t, err)
+				defer conn.Close()
+				for _, m := range messages {
+					_, err := conn.Write([]byte(m))
+					require.NoError(t, err)
+				}
+			},
+		},
+		{
+			desc:  "TCP with octet counting",
+			serve: serveTCP(nil),
+			send: func(t *testing.T, addr net.Addr) {
+				conn, err := net.Dial("tcp", addr.String())
+				require.NoError(t, err)
+				defer conn.Close()
+				for _, m := range messages {
+					_, err := io.WriteString(conn, octetCounted(m))
+					require.NoError(t, err)
+				}
+			},
+		},
+		{
+			desc:  "TCP with newline delimiter",
+			serve: serveTCP(nil),
+			send: func(t *testing.T, addr net.Addr) {
+				conn, err := net.Dial("tcp", addr.String())
+				require.NoError(t, err)
+				defer conn.Close()
+				// last message has no trailing new line, it is read when connection is closed
+				_, err = io.WriteString(conn, messages[0]+"\r\n"+messages[1])
+				require.NoError(t, err)
+			},
+		},
+		{
+			desc:  "TLS",
+			serve: serveTCP(tlsConfig),
+			send: func(t *testing.T, addr net.Addr) {
+				conn, err := tls.Dial("tcp", addr.String(), &tls.Config{InsecureSkipVerify: true})
+				require.NoError(t, err)
+				defer conn.Close()
+				for _, m := range messages {
+					_, err := io.WriteString(conn, octetCounted(m))
+					require.NoError(t, err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ed := &forwardtest.Endpoint{}
+			edServer := httptest.NewServer(ed)
+			defer edServer.Close()
+
+			conf := &cfg.Config{
+				Region:        "us-west-2",
+				EDEndpoint:    edServer.URL,
+				BatchSize:     cfg.MaxChunkSize,
+				PushTimeout:   time.Second,
+				RetryInterval: 10 * time.Millisecond,
+			}
+			enricher, forwarder := forwardtest.NewForwarder(conf, push.NewPusher(conf))
+			identity := &imds.InstanceIdentity{AccountID: "123456789012", Region: "us-east-1"}
+			server := NewServer(conf, enricher, forwarder, identity)
+
+			addr, serveErr := tt.serve(t, server)
+			tt.send(t, addr)
+			require.Eventually(t, func() bool { return len(ed.LogEvents()) == len(messages) }, 5*time.Second, 10*time.Millisecond)
+
+			got := ed.LogEvents()
+			// RFC 3164 timestamp depends on the received year
+			assert.NotZero(t, got[1].Timestamp)
+			got[1].Timestamp = 0
+			assert.Equal(t, wantEvents, got)
+
+			for _, l := range ed.Logs() {
+				assert.Equal(t, "123456789012", l.Cloud.AccountID)
+				assert.Equal(t, "us-east-1", l.Cloud.Region)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.NoError(t, server.Shutdown(ctx))
+			assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+		})
+	}
+}
+
+func TestServerBatchSize(t *testing.T) {
+	ed := &forwardtest.Endpoint{}
+	edServer := httptest.NewServer(ed)
+	defer edServer.Close()
+
+	conf := &cfg.Config{
+		Region:        "us-west-2",
+		EDEndpoint:    edServer.URL,
+		BatchSize:     1000,
+		PushTimeout:   time.Second,
+		RetryInterval: 10 * time.Millisecond,
+	}
+	enricher, forwarder := forwardtest.NewForwarder(conf, push.NewPusher(conf))
+	server := NewServer(conf, enricher, forwarder, &imds.InstanceIdentity{})
+
+	addr, serveErr := serveTCP(nil)(t, server)
+	conn, err := net.Dial("tcp", addr.String())
+	require.NoError(t, err)
+	message := "<13>" + strings.Repeat("a", 400) + "\n"
+	for i := 0; i < 3; i++ {
+		_, err := io.WriteString(conn, message)
+		require.NoError(t, err)
+	}
+	// messages fill a batch, so they are pushed before flush interval
+	require.Eventually(t, func() bool { return len(ed.LogEvents()) == 3 }, flushInterval/2, 10*time.Millisecond)
+	conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, server.Shutdown(ctx))
+	assert.ErrorIs(t, <-serveErr, ErrServerClosed)
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		desc          string
+		input         string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			desc:     "Octet counted and newline delimited frames",
+			input:    "5 hello<13>world\n11 hello world",
+			expected: []string{"hello", "<13>world", "hello world"},
+		},
+		{
+			desc:          "Length prefix is too long",
+			input:         "123456789 hello",
+			expectedError: true,
+		},
+		{
+			desc:          "Length prefix without space",
+			input:         "1" + strings.Repeat("2", readBufferSize),
+			expectedError: true,
+		},
+		{
+			desc:          "Invalid length prefix",
+			input:         "12a hello",
+			expectedError: true,
+		},
+		{
+			desc:          "Frame length exceeds max message size",
+			input:         "99999999 hello",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(tt.input), readBufferSize)
+			var frames []string
+			for {
+				frame, err := readFrame(r)
+				if err == io.EOF {
+					break
+				}
+				if tt.expectedError {
+					require.Error(t, err)
+					assert.NotErrorIs(t, err, io.EOF)
+					return
+				}
+				require.NoError(t, err)
+				frames = append(frames, string(frame))
+			}
+			require.False(t, tt.expectedError, "expected an error")
+			assert.Equal(t, tt.expected, frames)
+		})
+	}
+}
+
+func serveUDP(t *testing.T, s *Server) (net.Addr, <-chan error) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.ServeUDP(pc) }()
+	return pc.LocalAddr(), serveErr
+}
+
+func serveTCP(tlsConfig *tls.Config) func(t *testing.T, s *Server) (net.Addr, <-chan error) {
+	return func(t *testing.T, s *Server) (net.Addr, <-chan error) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		serveErr := make(chan error, 1)
+		go func() { serveErr <- s.ServeTCP(l) }()
+		return l.Addr(), serveErr
+	}
+}
+
+func octetCounted(m string) string {
+	return fmt.Sprintf("%d %s", len(m), m)
+}
+
+func newTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
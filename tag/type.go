@@ -8,12 +8,23 @@ type ServiceInfo struct {
 type Source string
 
 const (
-	SourceForwarder  Source = "ed_forwarder"
-	SourceLogGroup   Source = "log_group"
-	SourceSagemaker  Source = "sagemaker"
-	SourceECSTask    Source = "ecs_task"
-	SourceECSCluster Source = "ecs_cluster"
-	SourceECSService Source = "ecs_service"
-	SourceEC2        Source = "ec2"
-	SourceSNS        Source = "sns"
+	SourceForwarder       Source = "ed_forwarder"
+	SourceLogGroup        Source = "log_group"
+	SourceLambda          Source = "lambda"
+	SourceSagemaker       Source = "sagemaker"
+	SourceECSTask         Source = "ecs_task"
+	SourceECSCluster      Source = "ecs_cluster"
+	SourceECSService      Source = "ecs_service"
+	SourceEC2             Source = "ec2"
+	SourceSNS             Source = "sns"
+	SourceS3              Source = "s3"
+	SourceFirehose        Source = "firehose"
+	SourceCloudWatchAlarm Source = "cloudwatch_alarm"
+	SourceMSK             Source = "msk"
+	SourceELB             Source = "elasticloadbalancing"
+	SourceCloudFront      Source = "cloudfront"
+	SourceWAF             Source = "wafv2"
+	SourceCloudTrail      Source = "cloudtrail"
+	SourceRoute53         Source = "route53"
+	SourceRoute53Resolver Source = "route53resolver"
 )
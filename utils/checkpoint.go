@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Checkpoint keeps the progress of each item of a long running job in a JSON file, so an interrupted job resumes
+// where it stopped. Progress of the items is kept under the given field of the file.
+type Checkpoint[T any] struct {
+	path  string
+	field string
+	lock  sync.Mutex
+	items map[string]T
+}
+
+// LoadCheckpoint reads the checkpoint file at path, a missing file is an empty checkpoint.
+func LoadCheckpoint[T any](path, field string) (*Checkpoint[T], error) {
+	c := &Checkpoint[T]{path: path, field: field, items: map[string]T{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file, err: %v", err)
+	}
+
+	var f map[string]map[string]T
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint file: %s, err: %v", path, err)
+	}
+	if items := f[field]; items != nil {
+		c.items = items
+	}
+	return c, nil
+}
+
+// Get returns the progress of the item, zero value is returned for an item without progress.
+func (c *Checkpoint[T]) Get(key string) T {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.items[key]
+}
+
+// Save records the progress of the item and writes the checkpoint file.
+func (c *Checkpoint[T]) Save(key string, progress T) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items[key] = progress
+
+	b, err := json.Marshal(map[string]map[string]T{c.field: c.items})
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint, err: %v", err)
+	}
+	if err := WriteFileAtomic(c.path, b); err != nil {
+		return fmt.Errorf("failed to write checkpoint file, err: %v", err)
+	}
+	return nil
+}
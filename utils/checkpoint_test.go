@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testProgress struct {
+	Line int  `json:"line"`
+	Done bool `json:"done,omitempty"`
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	checkpoint, err := LoadCheckpoint[testProgress](path, "files")
+	if err != nil {
+		t.Fatalf("Failed to load missing checkpoint: %v", err)
+	}
+	if err := checkpoint.Save("a", testProgress{Line: 10}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	if err := checkpoint.Save("b", testProgress{Line: 20, Done: true}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint file: %v", err)
+	}
+	if want := `{"files":{"a":{"line":10},"b":{"line":20,"done":true}}}`; string(b) != want {
+		t.Errorf("expected checkpoint file: %s, got: %s", want, b)
+	}
+
+	loaded, err := LoadCheckpoint[testProgress](path, "files")
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if got := loaded.Get("a"); got != (testProgress{Line: 10}) {
+		t.Errorf("unexpected progress of a: %+v", got)
+	}
+	if got := loaded.Get("b"); got != (testProgress{Line: 20, Done: true}) {
+		t.Errorf("unexpected progress of b: %+v", got)
+	}
+	if got := loaded.Get("c"); got != (testProgress{}) {
+		t.Errorf("unexpected progress of c: %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatalf("Failed to write checkpoint file: %v", err)
+	}
+	if _, err := LoadCheckpoint[testProgress](path, "files"); err == nil {
+		t.Error("expected error for invalid checkpoint file")
+	}
+}
@@ -2,7 +2,11 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"golang.org/x/sys/unix"
 )
@@ -33,3 +37,46 @@ func SetKeyWithPrefix(m map[string]string, prefix, k, v string) {
 	sb.WriteString(k)
 	m[sb.String()] = v
 }
+
+// ProcessInParallel calls process for each index in [0, count) using at most given number of workers,
+// it blocks until all indexes are processed.
+func ProcessInParallel(count, workers int, process func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				process(i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// WriteFileAtomic writes to a temporary file and renames it to path,
+// so the file is never left partially written if the process is killed while writing.
+func WriteFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file, err: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file, err: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file, err: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary file, err: %v", err)
+	}
+	return nil
+}
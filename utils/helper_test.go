@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestProcessInParallel(t *testing.T) {
+	tests := []struct {
+		desc    string
+		count   int
+		workers int
+	}{
+		{desc: "More items than workers", count: 100, workers: 4},
+		{desc: "Less items than workers", count: 2, workers: 8},
+		{desc: "No items", count: 0, workers: 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			processed := make([]int32, tc.count)
+			var running, maxRunning int32
+			ProcessInParallel(tc.count, tc.workers, func(i int) {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if current <= m || atomic.CompareAndSwapInt32(&maxRunning, m, current) {
+						break
+					}
+				}
+				atomic.AddInt32(&processed[i], 1)
+				atomic.AddInt32(&running, -1)
+			})
+
+			for i, p := range processed {
+				if p != 1 {
+					t.Errorf("Expected index %d to be processed once, got %d", i, p)
+				}
+			}
+			if maxRunning > int32(tc.workers) {
+				t.Errorf("Expected at most %d concurrent workers, got %d", tc.workers, maxRunning)
+			}
+		})
+	}
+}
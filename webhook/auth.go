@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+)
+
+const (
+	AuthMethodIAM          = "iam"
+	AuthMethodSharedSecret = "shared_secret"
+	AuthMethodHMAC         = "hmac"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Authenticator verifies callers of the HTTP endpoint either by a shared secret sent as bearer token
+// or by HMAC-SHA256 signature of the request body.
+type Authenticator struct {
+	sharedSecret    string
+	hmacSecret      string
+	signatureHeader string
+}
+
+func NewAuthenticator(conf *cfg.Config) *Authenticator {
+	return &Authenticator{
+		sharedSecret:    conf.HTTPSharedSecret,
+		hmacSecret:      conf.HTTPHMACSecret,
+		signatureHeader: strings.ToLower(conf.HTTPSignatureHeader),
+	}
+}
+
+// Authenticate returns the method caller is authenticated with. Headers are expected to have lower case keys as in lambda events.
+// Requests already authenticated by IAM (i.e. Function URL with AWS_IAM auth type) are accepted without a secret,
+// other requests are rejected unless a configured secret matches, so the endpoint is never left open.
+func (a *Authenticator) Authenticate(headers map[string]string, body []byte, iamAuthenticated bool) (string, error) {
+	if iamAuthenticated {
+		return AuthMethodIAM, nil
+	}
+
+	if a.sharedSecret != "" {
+		token, ok := strings.CutPrefix(headers["authorization"], "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(a.sharedSecret)) == 1 {
+			return AuthMethodSharedSecret, nil
+		}
+	}
+
+	if a.hmacSecret != "" {
+		// signature may be prefixed with the algorithm, i.e. sha256=<hex_signature>
+		signature, err := hex.DecodeString(strings.TrimPrefix(headers[a.signatureHeader], "sha256="))
+		if err == nil && hmac.Equal(signature, computeHMAC(a.hmacSecret, body)) {
+			return AuthMethodHMAC, nil
+		}
+	}
+
+	return "", ErrUnauthorized
+}
+
+func computeHMAC(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
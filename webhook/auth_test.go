@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/edgedelta/edgedelta-forwarder/cfg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticate(t *testing.T) {
+	body := []byte(`{"message":"hello"}`)
+	signature := hex.EncodeToString(computeHMAC("hmac-secret", body))
+
+	tests := []struct {
+		desc             string
+		conf             *cfg.Config
+		headers          map[string]string
+		iamAuthenticated bool
+		expectedMethod   string
+		expectedErr      error
+	}{
+		{
+			desc:             "IAM authenticated",
+			conf:             &cfg.Config{},
+			iamAuthenticated: true,
+			expectedMethod:   AuthMethodIAM,
+		},
+		{
+			desc:        "No secret is configured",
+			conf:        &cfg.Config{},
+			expectedErr: ErrUnauthorized,
+		},
+		{
+			desc:           "Shared secret",
+			conf:           &cfg.Config{HTTPSharedSecret: "secret"},
+			headers:        map[string]string{"authorization": "Bearer secret"},
+			expectedMethod: AuthMethodSharedSecret,
+		},
+		{
+			desc:        "Wrong shared secret",
+			conf:        &cfg.Config{HTTPSharedSecret: "secret"},
+			headers:     map[string]string{"authorization": "Bearer wrong"},
+			expectedErr: ErrUnauthorized,
+		},
+		{
+			desc:           "HMAC signature",
+			conf:           &cfg.Config{HTTPHMACSecret: "hmac-secret", HTTPSignatureHeader: "X-Hub-Signature-256"},
+			headers:        map[string]string{"x-hub-signature-256": "sha256=" + signature},
+			expectedMethod: AuthMethodHMAC,
+		},
+		{
+			desc:           "HMAC signature without prefix",
+			conf:           &cfg.Config{HTTPHMACSecret: "hmac-secret", HTTPSignatureHeader: "X-Signature"},
+			headers:        map[string]string{"x-signature": signature},
+			expectedMethod: AuthMethodHMAC,
+		},
+		{
+			desc:        "Wrong HMAC signature",
+			conf:        &cfg.Config{HTTPHMACSecret: "other-secret", HTTPSignatureHeader: "X-Hub-Signature-256"},
+			headers:     map[string]string{"x-hub-signature-256": "sha256=" + signature},
+			expectedErr: ErrUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			method, err := NewAuthenticator(tc.conf).Authenticate(tc.headers, body, tc.iamAuthenticated)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedMethod, method)
+		})
+	}
+}
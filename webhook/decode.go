@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	// maxDecompressedBodySize protects against gzip bombs, lambda request payloads are limited to 6MB
+	maxDecompressedBodySize = 64 * 1024 * 1024
+)
+
+// DecodeBody returns log events of a request body. JSON bodies (an object, an array of objects or newline delimited JSON)
+// become one event per value, other bodies become one event per line. Gzip compressed bodies are decompressed.
+// Returned errors are caused by the body, so they should be reported to the caller as bad request.
+func DecodeBody(body []byte, contentType, contentEncoding string, timestamp int64) ([]events.CloudwatchLogsLogEvent, error) {
+	body, err := Decompress(body, contentEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	if isJSON(contentType, body) {
+		messages, err = decodeJSON(body)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		messages = decodeLines(body)
+	}
+
+	logEvents := make([]events.CloudwatchLogsLogEvent, 0, len(messages))
+	for i, m := range messages {
+		logEvents = append(logEvents, events.CloudwatchLogsLogEvent{
+			ID:        strconv.Itoa(i),
+			Timestamp: timestamp,
+			Message:   m,
+		})
+	}
+	return logEvents, nil
+}
+
+// Decompress returns gzip compressed bodies decompressed, which are detected by content encoding or gzip header,
+// and other bodies as is.
+func Decompress(body []byte, contentEncoding string) ([]byte, error) {
+	isGzip := strings.EqualFold(contentEncoding, "gzip") || (len(body) > 1 && body[0] == 0x1f && body[1] == 0x8b)
+	if !isGzip {
+		return body, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader, err: %v", err)
+	}
+	defer zr.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(zr, maxDecompressedBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress body, err: %v", err)
+	}
+	if len(decompressed) > maxDecompressedBodySize {
+		return nil, fmt.Errorf("decompressed body exceeds %d bytes", maxDecompressedBodySize)
+	}
+	return decompressed, nil
+}
+
+// isJSON uses content type if it is given, i.e. application/json or application/x-ndjson, otherwise sniffs the body.
+func isJSON(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return strings.Contains(mediaType, "json")
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(bytes.SplitN(trimmed, []byte("\n"), 2)[0])
+}
+
+func decodeJSON(body []byte) ([]string, error) {
+	var messages []string
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for {
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			if errors.Is(err, io.EOF) {
+				return messages, nil
+			}
+			return nil, fmt.Errorf("failed to decode JSON body, err: %v", err)
+		}
+
+		var values []json.RawMessage
+		if bytes.HasPrefix(value, []byte("[")) {
+			if err := json.Unmarshal(value, &values); err != nil {
+				return nil, fmt.Errorf("failed to decode JSON array, err: %v", err)
+			}
+		} else {
+			values = []json.RawMessage{value}
+		}
+
+		for _, v := range values {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, v); err != nil {
+				return nil, fmt.Errorf("failed to compact JSON value, err: %v", err)
+			}
+			messages = append(messages, buf.String())
+		}
+	}
+}
+
+func decodeLines(body []byte) []string {
+	var messages []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		messages = append(messages, line)
+	}
+	return messages
+}
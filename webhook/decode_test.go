@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBody(t *testing.T) {
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, err := zw.Write([]byte("line 1\nline 2\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		desc             string
+		body             []byte
+		contentType      string
+		contentEncoding  string
+		expectedMessages []string
+		expectErr        bool
+	}{
+		{
+			desc:             "JSON object",
+			body:             []byte(`{"level": "info", "msg": "hello"}`),
+			contentType:      "application/json",
+			expectedMessages: []string{`{"level":"info","msg":"hello"}`},
+		},
+		{
+			desc:             "JSON array",
+			body:             []byte(`[{"msg": "1"}, {"msg": "2"}]`),
+			contentType:      "application/json; charset=utf-8",
+			expectedMessages: []string{`{"msg":"1"}`, `{"msg":"2"}`},
+		},
+		{
+			desc:             "NDJSON",
+			body:             []byte("{\"msg\": \"1\"}\n{\"msg\": \"2\"}\n"),
+			contentType:      "application/x-ndjson",
+			expectedMessages: []string{`{"msg":"1"}`, `{"msg":"2"}`},
+		},
+		{
+			desc:             "NDJSON without content type",
+			body:             []byte("{\"msg\": \"1\"}\n{\"msg\": \"2\"}\n"),
+			expectedMessages: []string{`{"msg":"1"}`, `{"msg":"2"}`},
+		},
+		{
+			desc:             "Plain text",
+			body:             []byte("line 1\r\n\nline 2"),
+			contentType:      "text/plain",
+			expectedMessages: []string{"line 1", "line 2"},
+		},
+		{
+			desc:             "Text starting with a bracket",
+			body:             []byte("[INFO] started\n[INFO] done"),
+			expectedMessages: []string{"[INFO] started", "[INFO] done"},
+		},
+		{
+			desc:             "Gzip compressed",
+			body:             gzipped.Bytes(),
+			contentType:      "text/plain",
+			contentEncoding:  "gzip",
+			expectedMessages: []string{"line 1", "line 2"},
+		},
+		{
+			desc:        "Invalid JSON",
+			body:        []byte(`{"msg": `),
+			contentType: "application/json",
+			expectErr:   true,
+		},
+		{
+			desc:            "Invalid gzip",
+			body:            []byte("not gzip"),
+			contentEncoding: "gzip",
+			expectErr:       true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			logEvents, err := DecodeBody(tc.body, tc.contentType, tc.contentEncoding, 1704067200000)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var messages []string
+			for _, e := range logEvents {
+				assert.Equal(t, int64(1704067200000), e.Timestamp)
+				messages = append(messages, e.Message)
+			}
+			assert.Equal(t, tc.expectedMessages, messages)
+		})
+	}
+}